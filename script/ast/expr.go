@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"time"
+
 	"github.com/bjatkin/nook/script/token"
 )
 
@@ -36,6 +38,14 @@ type String struct {
 	Value string
 }
 
+// Interpolation is a string literal with embedded expressions (e.g. "hello ${name}"),
+// the parts are the strings and expressions in the order they appear in the literal
+type Interpolation struct {
+	Expr
+	Tok   token.Token
+	Parts []Expr
+}
+
 // Atom is an atom literal (e.g. 'ok)
 type Atom struct {
 	Expr
@@ -64,6 +74,75 @@ type Flag struct {
 	Value string
 }
 
+// Duration is a duration literal (e.g. 1h30m)
+type Duration struct {
+	Expr
+	Tok   token.Token
+	Value time.Duration
+}
+
+// Size is a size literal (e.g. 1.5GiB), the value is the number of bytes
+type Size struct {
+	Expr
+	Tok   token.Token
+	Value int64
+}
+
+// Regex is a regex literal (e.g. #/v\d+/), the value is the pattern without the delimiters
+type Regex struct {
+	Expr
+	Tok   token.Token
+	Value string
+}
+
+// Property is the name of a dict field (e.g. .title)
+type Property struct {
+	Expr
+	Tok  token.Token
+	Name string
+}
+
+// Tuple is a tuple literal (e.g. {5 10})
+type Tuple struct {
+	Expr
+	Tok   token.Token
+	Items []Expr
+}
+
+// Slice is a slice literal (e.g. {[int] 5 10 15})
+type Slice struct {
+	Expr
+	Tok   token.Token
+	Type  *SliceType
+	Items []Expr
+
+	// Value is set by the checker when a typed slice is built from a single any value
+	// (e.g. {[int] (json.parse text)}), the value is converted into the slice at runtime
+	Value *Convert
+}
+
+// Index accesses a single item of a slice, tuple or dict (e.g. [ints 0] or [tv_show .title])
+type Index struct {
+	Expr
+	Tok   token.Token
+	Value Expr
+	Index Expr
+}
+
+// Field is a single field in a dict literal (e.g. .title "dune").
+// It is not an expression as it can only appear inside a Dict
+type Field struct {
+	Property *Property
+	Value    Expr
+}
+
+// Dict is a dict literal (e.g. {.title "dune" .year 1965})
+type Dict struct {
+	Expr
+	Tok    token.Token
+	Fields []Field
+}
+
 // Func is a function literal (e.g. (fn (a int, b int) int (+ a b))
 type Func struct {
 	Expr
@@ -93,7 +172,24 @@ type Let struct {
 // it can be called in a Call expression.
 type Builtin struct {
 	Expr
-	Fn func(args ...any) (any, error)
+	Type *FuncType
+	Fn   func(args ...any) (any, error)
+}
+
+// Overload is a single implementation of an overloaded function.
+// Func is either a Builtin or a Func literal
+type Overload struct {
+	Type *FuncType
+	Func Expr
+}
+
+// Dispatch is an overloaded function that could not be resolved by the checker because
+// the types of the arguments are not known until runtime. It is not representable in the
+// language but it can be called in a Call expression.
+type Dispatch struct {
+	Expr
+	Name      string
+	Overloads []Overload
 }
 
 // Impl is a full imple expression in the language (e.g. (impl add (fn [a b] (+ a b))))
@@ -114,12 +210,124 @@ type Command struct {
 	Tok  token.Token
 	Name string
 	Args []Expr
-	// TODO: redirects?
+	// Stdin is written to the command's stdin, it is nil if nothing was redirected with '<'
+	Stdin Expr
+}
+
+// If is a full if expression in the language (e.g. (if ok "yes" "no")).
+// Else is nil if the expression does not include an else branch.
+type If struct {
+	Expr
+	Tok  token.Token
+	Cond Expr
+	Then Expr
+	Else Expr
+}
+
+// Case is a single case in a match expression (e.g. ('en "English")).
+// It is not an expression as it can only appear inside a Match
+type Case struct {
+	Pattern Expr
+	Body    Expr
+}
+
+// Match is a full match expression in the language (e.g. (match lang ('en "English") (else "Unknown"))).
+// Else is nil if the expression does not include an else case.
+type Match struct {
+	Expr
+	Tok   token.Token
+	Value Expr
+	Cases []Case
+	Else  Expr
+}
+
+// Do is a full do expression in the language (e.g. (do (let a 1) (+ a 1))).
+// The expressions are evaluated in order inside a new scope and the value of
+// the final expression is the value of the do expression.
+type Do struct {
+	Expr
+	Tok   token.Token
+	Exprs []Expr
+}
+
+// Namespace is a full namespace expression in the language (e.g. (ns vec (let add (fn [a b] (+ a b))))).
+// Names declared inside the namespace can be accessed outside of it using a qualified
+// identifier (e.g. vec.add) unless the name starts with an '_' which makes it private.
+type Namespace struct {
+	Expr
+	Tok        token.Token
+	Identifier *Identifier
+	Exprs      []Expr
+}
+
+// Import binds the names declared in a nook script file under a namespace named
+// after the file (e.g. (import ./lib/git.nk) binds git.status)
+type Import struct {
+	Expr
+	Tok        token.Token
+	Path       *Path
+	Identifier *Identifier
+
+	// Module is the loaded file, it is set by the module loader before the import is checked
+	Module *Module
+}
+
+// Module is a nook script file that has been parsed and normalized.
+// It is not an expression, it only holds the contents of an Import.
+type Module struct {
+	Path  string
+	Hash  string
+	Exprs []Expr
+	Lines []int
+}
+
+// Macro is a macro definition (e.g. (macro unless [c body] (quasiquote (if (unquote c) nil (unquote body))))).
+// Macros are expanded by the normalizer so the definition only declares the name.
+type Macro struct {
+	Expr
+	Tok        token.Token
+	Identifier *Identifier
+}
+
+// Try catches any error raised while evaluating an expression (e.g. (try (cd ./missing) (fn [e error] nil))).
+// Without a handler the try evaluates to either the value of the expression or the error.
+type Try struct {
+	Expr
+	Tok     token.Token
+	Body    Expr
+	Handler Expr
+}
+
+// Convert changes the type of a value (e.g. {float 1}). The checker also inserts conversions
+// when an int argument is promoted to a float to match a function overload.
+type Convert struct {
+	Expr
+	Tok   token.Token
+	Type  TypeExpr
+	Value Expr
+}
+
+// And is a full and expression in the language (e.g. (and ready (> count 0))).
+// Operands are evaluated in order and evaluation stops at the first false operand.
+type And struct {
+	Expr
+	Tok   token.Token
+	Exprs []Expr
+}
+
+// Or is a full or expression in the language (e.g. (or cached (exists path))).
+// Operands are evaluated in order and evaluation stops at the first true operand.
+type Or struct {
+	Expr
+	Tok   token.Token
+	Exprs []Expr
 }
 
 // Call is a function call (e.g. (print "hello there"))
 type Call struct {
 	Expr
+	// Tok is the token of the function being called, it is used to report where errors were raised
+	Tok  token.Token
 	Func Expr
 	Args []Expr
 }
@@ -56,3 +56,113 @@ type SCurly struct {
 	Expr
 	Tok token.Token
 }
+
+// SIf is the 'if' keyword at the beginning of an SExpr that evaluates one of two
+// branches based on a condition.
+// It differs from an If expression in that it only refers to the leading
+// element of the containing SExpr and not the full if expression
+type SIf struct {
+	Expr
+	Tok token.Token
+}
+
+// SElse is the 'else' keyword that marks the default case of a match expression.
+type SElse struct {
+	Expr
+	Tok token.Token
+}
+
+// SMatch is the 'match' keyword at the beginning of an SExpr that compares a value
+// against a list of cases.
+// It differs from a Match expression in that it only refers to the leading
+// element of the containing SExpr and not the full match expression
+type SMatch struct {
+	Expr
+	Tok token.Token
+}
+
+// SDo is the 'do' keyword at the beginning of an SExpr that evaluates a list of
+// expressions in a new scope.
+// It differs from a Do expression in that it only refers to the leading
+// element of the containing SExpr and not the full do expression
+type SDo struct {
+	Expr
+	Tok token.Token
+}
+
+// SImport is the 'import' keyword at the beginning of an SExpr that imports a file.
+// It differs from an Import expression in that it only refers to the leading
+// element of the containing SExpr and not the full import expression
+type SImport struct {
+	Expr
+	Tok token.Token
+}
+
+// SMacro is the 'macro' keyword at the beginning of an SExpr that defines a macro.
+// It differs from a Macro expression in that it only refers to the leading
+// element of the containing SExpr and not the full macro definition
+type SMacro struct {
+	Expr
+	Tok token.Token
+}
+
+// SQuote is the 'quote' keyword at the beginning of an SExpr.
+// The quoted expression is inserted into a macro expansion exactly as it was written.
+type SQuote struct {
+	Expr
+	Tok token.Token
+}
+
+// SQuasiquote is the 'quasiquote' keyword at the beginning of an SExpr.
+// The quasiquoted expression is a macro template where unquote and splice are replaced by
+// the arguments passed to the macro.
+type SQuasiquote struct {
+	Expr
+	Tok token.Token
+}
+
+// SUnquote is the 'unquote' keyword at the beginning of an SExpr.
+// It is replaced by the argument passed to a macro param when the macro is expanded.
+type SUnquote struct {
+	Expr
+	Tok token.Token
+}
+
+// SSplice is the 'splice' keyword at the beginning of an SExpr.
+// It is replaced by each of the arguments collected by a rest param when the macro is expanded.
+type SSplice struct {
+	Expr
+	Tok token.Token
+}
+
+// STry is the 'try' keyword at the beginning of an SExpr that catches errors.
+// It differs from a Try expression in that it only refers to the leading
+// element of the containing SExpr and not the full try expression
+type STry struct {
+	Expr
+	Tok token.Token
+}
+
+// SAnd is the 'and' keyword at the beginning of an SExpr.
+// It differs from an And expression in that it only refers to the leading
+// element of the containing SExpr and not the full and expression
+type SAnd struct {
+	Expr
+	Tok token.Token
+}
+
+// SOr is the 'or' keyword at the beginning of an SExpr.
+// It differs from an Or expression in that it only refers to the leading
+// element of the containing SExpr and not the full or expression
+type SOr struct {
+	Expr
+	Tok token.Token
+}
+
+// SNamespace is the 'ns' keyword at the beginning of an SExpr that declares a namespace.
+// It differs from a Namespace expression in that it only refers to the leading
+// element of the containing SExpr and not the full namespace expression
+type SNamespace struct {
+	Expr
+	Tok token.Token
+}
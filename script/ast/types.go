@@ -15,6 +15,21 @@ type IntType struct {
 	Tok token.Token
 }
 
+// BigIntType represents the `bigint` keyword in a type expression in NookScript.
+// Types can always be omitted and then infered in NookScript, in which case
+// this node will not be added until the normalizer or checker phases
+type BigIntType struct {
+	TypeExpr
+	Tok token.Token
+}
+
+// NumberType represents the `number` trait in a type expression in NookScript.
+// Ints, bigints and floats are all numbers.
+type NumberType struct {
+	TypeExpr
+	Tok token.Token
+}
+
 // FloatType represents the `float` keyword in a type expression in NookScript.
 // Types can always be omitted and then infered in NookScript, in which case
 // this node will not be added until the normalizer or checker phases
@@ -63,6 +78,38 @@ type FlagType struct {
 	Tok token.Token
 }
 
+// RegexType represents the `regex` keyword in a type expression in NookScript.
+// Types can always be omitted and then infered in NookScript, in which case
+// this node will not be added until the normalizer or checker phases
+type RegexType struct {
+	TypeExpr
+	Tok token.Token
+}
+
+// DurationType represents the `duration` keyword in a type expression in NookScript.
+// Types can always be omitted and then infered in NookScript, in which case
+// this node will not be added until the normalizer or checker phases
+type DurationType struct {
+	TypeExpr
+	Tok token.Token
+}
+
+// TimeType represents the `time` keyword in a type expression in NookScript.
+// Types can always be omitted and then infered in NookScript, in which case
+// this node will not be added until the normalizer or checker phases
+type TimeType struct {
+	TypeExpr
+	Tok token.Token
+}
+
+// SizeType represents the `size` keyword in a type expression in NookScript.
+// Types can always be omitted and then infered in NookScript, in which case
+// this node will not be added until the normalizer or checker phases
+type SizeType struct {
+	TypeExpr
+	Tok token.Token
+}
+
 // NoneType represents the `none` keyword in a type expression in NookScript.
 // Types can always be omitted and then infered in NookScript, in which case
 // this node will not be added until the normalizer or checker phases
@@ -71,6 +118,14 @@ type NoneType struct {
 	Tok token.Token
 }
 
+// ErrorType represents the `error` keyword in a type expression in NookScript.
+// Types can always be omitted and then infered in NookScript, in which case
+// this node will not be added until the normalizer or checker phases
+type ErrorType struct {
+	TypeExpr
+	Tok token.Token
+}
+
 // CommandType represents the `cmd` keyword in a type expression in NookScript.
 // Types can always be omitted and then infered in NookScript, in which case
 // this node will not be added until the normalizer or checker phases
@@ -84,7 +139,14 @@ type CommandType struct {
 // this node will not be added until the normalizer or checker phases
 type DictType struct {
 	TypeExpr
-	// TODO: I want something similar to the ParamList here.
+	Fields []FieldType
+}
+
+// FieldType is the name and type of a single field in a DictType.
+// It is not a type expression as it can only appear inside a DictType
+type FieldType struct {
+	Name string
+	Type TypeExpr
 }
 
 // TupleType represents a tuple type in NookScript.
@@ -95,6 +157,21 @@ type TupleType struct {
 	Types []TypeExpr
 }
 
+// SliceType represents a slice type in NookScript (e.g. [int]).
+// Elem is nil if the type was written as [_] and the element type must be infered
+type SliceType struct {
+	TypeExpr
+	Tok  token.Token
+	Elem TypeExpr
+}
+
+// TypeVar is a placeholder for a type in the signature of a generic builtin (e.g. T in <fn [T] U>).
+// It is not representable in the language, the checker replaces it with the type of the argument it is bound to.
+type TypeVar struct {
+	TypeExpr
+	Name string
+}
+
 // VariadicType represents a variadic type in NookScript fuction paramater list.
 // It is only allowed in the final position of the paramater list.
 // Types can always be omitted and then infered in NookScript, in which case
@@ -11,15 +11,88 @@ import (
 
 // Builtin is a builtin function for the nook environment
 type Builtin struct {
-	Name string
-	Type *ast.FuncType
-	Fn   func(args ...any) (any, error)
+	Namespace string
+	Name      string
+	Type      *ast.FuncType
+	Fn        func(args ...any) (any, error)
+}
+
+// namespaced creates a builtin in a namespace that takes params and returns ret
+func namespaced(namespace, name string, params []ast.TypeExpr, ret ast.TypeExpr, fn func(args ...any) (any, error)) Builtin {
+	paramList := &ast.ParamList{}
+	for _, param := range params {
+		paramList.Params = append(paramList.Params, ast.Param{Type: param})
+	}
+
+	return Builtin{
+		Namespace: namespace,
+		Name:      name,
+		Type:      &ast.FuncType{Params: paramList, Return: ret},
+		Fn:        fn,
+	}
+}
+
+// Func is a nook function that was passed to a builtin as an argument.
+// Calling it runs the function in the vm and returns the result as a go value.
+type Func struct {
+	Call func(args ...any) (any, error)
+
+	// Value is the vm value of the function, it is used if the builtin returns the function
+	Value any
+}
+
+// Tuple is returned by builtins to create a tuple when the length of the tuple is only known at runtime.
+// Otherwise a []any is a tuple if the return type of the builtin is a tuple and a slice if it is not.
+type Tuple []any
+
+// Dict is a dict that keeps the order of its fields (e.g. the columns of a csv header).
+// Builtins that return a map[string]any get dicts whose fields are in sorted order.
+type Dict struct {
+	Names  []string
+	Fields map[string]any
+}
+
+// Aliases maps short names that are available outside of any namespace to
+// the qualified names of the builtins they refer to.
+var Aliases = map[string]string{
+	"+":         "math.add",
+	"-":         "math.sub",
+	"*":         "math.mul",
+	"/":         "math.div",
+	"==":        "cmp.eq",
+	"!=":        "cmp.ne",
+	"<":         "cmp.lt",
+	"<=":        "cmp.le",
+	">":         "cmp.gt",
+	">=":        "cmp.ge",
+	"not":       "logic.not",
+	"mod":       "math.mod",
+	"rem":       "math.rem",
+	"pow":       "math.pow",
+	"map":       "slice.map",
+	"filter":    "slice.filter",
+	"reduce":    "slice.reduce",
+	"each":      "slice.each",
+	"sort_by":   "slice.sort_by",
+	"group_by":  "slice.group_by",
+	"zip":       "slice.zip",
+	"enumerate": "slice.enumerate",
+	"take":      "slice.take",
+	"drop":      "slice.drop",
+	"uniq":      "slice.uniq",
+	"flatten":   "slice.flatten",
+	"cd":        "fs.cd",
+	"exists":    "path.exists",
+	"ls":        "fs.ls",
+	"fail":      "err.fail",
+	"raise":     "err.raise",
 }
 
 // Builtins is a slice of all the nook builtin functions.
 var Builtins = []Builtin{
 	{
-		Name: "+",
+		Namespace: "math",
+		Name:      "add",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.IntType{}}}},
@@ -29,7 +102,8 @@ var Builtins = []Builtin{
 		Fn: AddInt64,
 	},
 	{
-		Name: "+",
+		Namespace: "math",
+		Name:      "add",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.FloatType{}}}},
@@ -39,7 +113,8 @@ var Builtins = []Builtin{
 		Fn: AddFloat64,
 	},
 	{
-		Name: "-",
+		Namespace: "math",
+		Name:      "sub",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.IntType{}}}},
@@ -49,7 +124,8 @@ var Builtins = []Builtin{
 		Fn: MinusInt64,
 	},
 	{
-		Name: "-",
+		Namespace: "math",
+		Name:      "sub",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.FloatType{}}}},
@@ -59,7 +135,8 @@ var Builtins = []Builtin{
 		Fn: MinusFloat64,
 	},
 	{
-		Name: "*",
+		Namespace: "math",
+		Name:      "mul",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.IntType{}}}},
@@ -69,7 +146,8 @@ var Builtins = []Builtin{
 		Fn: MultiplyInt64,
 	},
 	{
-		Name: "*",
+		Namespace: "math",
+		Name:      "mul",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.FloatType{}}}},
@@ -79,7 +157,8 @@ var Builtins = []Builtin{
 		Fn: MultiplyFloat64,
 	},
 	{
-		Name: "/",
+		Namespace: "math",
+		Name:      "div",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.IntType{}}}},
@@ -89,7 +168,8 @@ var Builtins = []Builtin{
 		Fn: DivideInt64,
 	},
 	{
-		Name: "/",
+		Namespace: "math",
+		Name:      "div",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.VariadicType{Type: &ast.FloatType{}}}},
@@ -99,7 +179,8 @@ var Builtins = []Builtin{
 		Fn: DivideFloat64,
 	},
 	{
-		Name: "cd",
+		Namespace: "fs",
+		Name:      "cd",
 		Type: &ast.FuncType{
 			Params: &ast.ParamList{
 				Params: []ast.Param{{Type: &ast.PathType{}}},
@@ -110,13 +191,72 @@ var Builtins = []Builtin{
 		Fn: ChangeDir,
 	},
 	{
-		Name: "ls",
+		Namespace: "err",
+		Name:      "fail",
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.StringType{}}},
+			},
+			Return: &ast.ErrorType{},
+		},
+		Fn: Fail,
+	},
+	{
+		Namespace: "err",
+		Name:      "fail",
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.StringType{}}, {Type: &ast.ErrorType{}}},
+			},
+			Return: &ast.ErrorType{},
+		},
+		Fn: Fail,
+	},
+	{
+		Namespace: "err",
+		Name:      "raise",
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.ErrorType{}}},
+			},
+			// raise never returns so it can be used in place of any value
+			Return: &ast.TraitType{},
+		},
+		Fn: Raise,
+	},
+	{
+		Namespace: "err",
+		Name:      "raise",
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.StringType{}}},
+			},
+			Return: &ast.TraitType{},
+		},
+		Fn: Raise,
+	},
+	{
+		Namespace: "err",
+		Name:      "message",
 		Type: &ast.FuncType{
-			Params: &ast.ParamList{},
-			// TODO: sould be a slice of data structures
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.ErrorType{}}},
+			},
 			Return: &ast.StringType{},
 		},
-		Fn: ListFiles,
+		Fn: ErrorMessage,
+	},
+	{
+		Namespace: "err",
+		Name:      "cause",
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.ErrorType{}}},
+			},
+			// the cause is either an error or none if the error has no cause
+			Return: &ast.TraitType{},
+		},
+		Fn: ErrorCause,
 	},
 }
 
@@ -128,14 +268,18 @@ var AddInt64 = func(args ...any) (any, error) {
 
 	sum := args[0].(int64)
 	for _, arg := range args[1:] {
-		sum += arg.(int64)
+		var err error
+		sum, err = addInt(sum, arg.(int64))
+		if err != nil {
+			return nil, err
+		}
 	}
 	return sum, nil
 }
 
 var AddFloat64 = func(args ...any) (any, error) {
 	if len(args) == 0 {
-		return 0, nil
+		return float64(0), nil
 	}
 
 	sum := args[0].(float64)
@@ -152,7 +296,11 @@ var MinusInt64 = func(args ...any) (any, error) {
 
 	min := args[0].(int64)
 	for _, arg := range args[1:] {
-		min -= arg.(int64)
+		var err error
+		min, err = subInt(min, arg.(int64))
+		if err != nil {
+			return nil, err
+		}
 	}
 	return min, nil
 }
@@ -176,7 +324,11 @@ var MultiplyInt64 = func(args ...any) (any, error) {
 
 	min := args[0].(int64)
 	for _, arg := range args[1:] {
-		min *= arg.(int64)
+		var err error
+		min, err = mulInt(min, arg.(int64))
+		if err != nil {
+			return nil, err
+		}
 	}
 	return min, nil
 }
@@ -200,7 +352,11 @@ var DivideInt64 = func(args ...any) (any, error) {
 
 	min := args[0].(int64)
 	for _, arg := range args[1:] {
-		min /= arg.(int64)
+		var err error
+		min, err = divInt(min, arg.(int64))
+		if err != nil {
+			return nil, err
+		}
 	}
 	return min, nil
 }
@@ -212,13 +368,19 @@ var DivideFloat64 = func(args ...any) (any, error) {
 
 	min := args[0].(float64)
 	for _, arg := range args[1:] {
+		if arg.(float64) == 0 {
+			return nil, ErrDivideByZero
+		}
 		min /= arg.(float64)
 	}
 	return min, nil
 }
 
 var ChangeDir = func(args ...any) (any, error) {
-	dir := args[0].(string)
+	dir, err := expandHome(args[0].(string))
+	if err != nil {
+		return nil, err
+	}
 
 	// make sure the directory exists before switching to it
 	if _, err := os.Stat(dir); err != nil {
@@ -234,7 +396,7 @@ var ChangeDir = func(args ...any) (any, error) {
 		dir = path.Join(workingDir, dir)
 	}
 
-	err := os.Chdir(dir)
+	err = os.Chdir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to change directories '%w'", err)
 	}
@@ -242,23 +404,38 @@ var ChangeDir = func(args ...any) (any, error) {
 	return nil, nil
 }
 
-var ListFiles = func(args ...any) (any, error) {
-	dir, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get current working directory '%v'", err)
+var Fail = func(args ...any) (any, error) {
+	nookErr := &Error{Message: args[0].(string)}
+	if len(args) > 1 {
+		nookErr.Cause = args[1].(*Error)
 	}
 
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, fmt.Errorf("could not read dir '%s': '%v'", dir, err)
+	return nookErr, nil
+}
+
+var Raise = func(args ...any) (any, error) {
+	switch arg := args[0].(type) {
+	case string:
+		return nil, &Error{Message: arg}
+	case *Error:
+		// the error is copied so raising it does not change the trace of the original value
+		raised := *arg
+		raised.Trace = nil
+		return nil, &raised
+	default:
+		return nil, fmt.Errorf("can not raise value '%v'", arg)
 	}
+}
+
+var ErrorMessage = func(args ...any) (any, error) {
+	return args[0].(*Error).Message, nil
+}
 
-	// TODO: hand back a list of data structures here so that nook-script can interact
-	// with the returned value rather than just getting a list of strings
-	found := []string{}
-	for _, file := range files {
-		found = append(found, "\""+file.Name()+"\"")
+var ErrorCause = func(args ...any) (any, error) {
+	cause := args[0].(*Error).Cause
+	if cause == nil {
+		return nil, nil
 	}
 
-	return "[ " + strings.Join(found, " ") + " ]", nil
+	return cause, nil
 }
@@ -0,0 +1,627 @@
+package builtin
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArithmetic(t *testing.T) {
+	type args struct {
+		fn   func(args ...any) (any, error)
+		args []any
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    any
+		wantErr error
+	}{
+		{
+			name: "add no ints",
+			args: args{fn: AddInt64},
+			want: int64(0),
+		},
+		{
+			name: "add no floats",
+			args: args{fn: AddFloat64},
+			want: float64(0),
+		},
+		{
+			name: "add ints",
+			args: args{fn: AddInt64, args: []any{int64(1), int64(2), int64(3)}},
+			want: int64(6),
+		},
+		{
+			name:    "add overflow",
+			args:    args{fn: AddInt64, args: []any{int64(math.MaxInt64), int64(1)}},
+			wantErr: ErrOverflow,
+		},
+		{
+			name:    "sub overflow",
+			args:    args{fn: MinusInt64, args: []any{int64(math.MinInt64), int64(1)}},
+			wantErr: ErrOverflow,
+		},
+		{
+			name:    "mul overflow",
+			args:    args{fn: MultiplyInt64, args: []any{int64(math.MaxInt64 / 2), int64(3)}},
+			wantErr: ErrOverflow,
+		},
+		{
+			name:    "mul min int by -1",
+			args:    args{fn: MultiplyInt64, args: []any{int64(math.MinInt64), int64(-1)}},
+			wantErr: ErrOverflow,
+		},
+		{
+			name: "mul negative ints",
+			args: args{fn: MultiplyInt64, args: []any{int64(-4), int64(5)}},
+			want: int64(-20),
+		},
+		{
+			name:    "div int by zero",
+			args:    args{fn: DivideInt64, args: []any{int64(1), int64(0)}},
+			wantErr: ErrDivideByZero,
+		},
+		{
+			name:    "div float by zero",
+			args:    args{fn: DivideFloat64, args: []any{float64(1), float64(0)}},
+			wantErr: ErrDivideByZero,
+		},
+		{
+			name:    "div min int by -1",
+			args:    args{fn: DivideInt64, args: []any{int64(math.MinInt64), int64(-1)}},
+			wantErr: ErrOverflow,
+		},
+		{
+			name: "mod takes the sign of the divisor",
+			args: args{fn: ModInt64, args: []any{int64(-7), int64(3)}},
+			want: int64(2),
+		},
+		{
+			name: "rem takes the sign of the dividend",
+			args: args{fn: RemInt64, args: []any{int64(-7), int64(3)}},
+			want: int64(-1),
+		},
+		{
+			name:    "mod by zero",
+			args:    args{fn: ModInt64, args: []any{int64(7), int64(0)}},
+			wantErr: ErrDivideByZero,
+		},
+		{
+			name: "pow",
+			args: args{fn: PowInt64, args: []any{int64(3), int64(4)}},
+			want: int64(81),
+		},
+		{
+			name:    "pow overflow",
+			args:    args{fn: PowInt64, args: []any{int64(2), int64(63)}},
+			wantErr: ErrOverflow,
+		},
+		{
+			name: "pow bigint",
+			args: args{fn: PowBigInt, args: []any{big.NewInt(2), int64(64)}},
+			want: new(big.Int).Lsh(big.NewInt(1), 64),
+		},
+		{
+			name: "mod bigint",
+			args: args{fn: ModBigInt, args: []any{big.NewInt(-7), big.NewInt(3)}},
+			want: big.NewInt(2),
+		},
+		{
+			name:    "bigint out of int range",
+			args:    args{fn: BigIntToInt, args: []any{new(big.Int).Lsh(big.NewInt(1), 64)}},
+			wantErr: ErrOverflow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.fn(tt.args.args...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == nil {
+				t.Errorf("got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemove_confirm(t *testing.T) {
+	type args struct {
+		confirm func(action string) bool
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantErr    error
+		wantExists bool
+	}{
+		{
+			name:       "no hook",
+			args:       args{},
+			wantExists: false,
+		},
+		{
+			name:       "confirmed",
+			args:       args{confirm: func(string) bool { return true }},
+			wantExists: false,
+		},
+		{
+			name:       "canceled",
+			args:       args{confirm: func(string) bool { return false }},
+			wantErr:    ErrCanceled,
+			wantExists: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Confirm = tt.args.confirm
+			defer func() { Confirm = nil }()
+
+			file := filepath.Join(t.TempDir(), "file.txt")
+			if err := os.WriteFile(file, []byte("data"), 0o644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+
+			_, err := Remove(file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			_, err = os.Stat(file)
+			if (err == nil) != tt.wantExists {
+				t.Errorf("file exists = %v, want %v", err == nil, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestWriteFile_confirm(t *testing.T) {
+	type args struct {
+		confirm func(action string) bool
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantErr    error
+		wantAction string
+		wantData   string
+	}{
+		{
+			name:       "confirmed",
+			args:       args{confirm: func(string) bool { return true }},
+			wantAction: "overwrite '{file}'",
+			wantData:   "new",
+		},
+		{
+			name:       "canceled",
+			args:       args{confirm: func(string) bool { return false }},
+			wantErr:    ErrCanceled,
+			wantAction: "overwrite '{file}'",
+			wantData:   "data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := ""
+			Confirm = func(a string) bool {
+				action = a
+				return tt.args.confirm(a)
+			}
+			defer func() { Confirm = nil }()
+
+			file := filepath.Join(t.TempDir(), "file.txt")
+			if err := os.WriteFile(file, []byte("data"), 0o644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+
+			_, err := WriteFile(file, "new")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if want := strings.ReplaceAll(tt.wantAction, "{file}", file); action != want {
+				t.Errorf("action = %q, want %q", action, want)
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read test file: %v", err)
+			}
+			if string(data) != tt.wantData {
+				t.Errorf("data = %q, want %q", data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	type args struct {
+		from string
+		to   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "file",
+			args: args{from: "s.txt", to: "t.txt"},
+		},
+		{
+			name:    "file onto itself",
+			args:    args{from: "s.txt", to: "s.txt"},
+			wantErr: true,
+		},
+		{
+			name:    "file into its own directory",
+			args:    args{from: "s.txt", to: "."},
+			wantErr: true,
+		},
+		{
+			name: "directory",
+			args: args{from: "dd", to: "other"},
+		},
+		{
+			name:    "directory into itself",
+			args:    args{from: "dd", to: "dd/sub"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "s.txt"), []byte("data"), 0o644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+			if err := os.MkdirAll(filepath.Join(dir, "dd"), 0o755); err != nil {
+				t.Fatalf("failed to create test dir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "dd", "a.txt"), []byte("a"), 0o644); err != nil {
+				t.Fatalf("failed to create test file: %v", err)
+			}
+
+			_, err := Copy(filepath.Join(dir, tt.args.from), filepath.Join(dir, tt.args.to))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Copy() err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// a failed copy must leave the source as it was
+			data, err := os.ReadFile(filepath.Join(dir, "s.txt"))
+			if err != nil || string(data) != "data" {
+				t.Errorf("source data = %q, err %v, want %q", data, err, "data")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "dd", "sub")); err == nil {
+				t.Errorf("Copy() created a directory inside of the source")
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type args struct {
+		fn   func(args ...any) (any, error)
+		args []any
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    any
+		wantErr string
+	}{
+		{
+			name: "parse object",
+			args: args{fn: ParseJSON, args: []any{`{"name": "nook", "stars": 10, "ratio": 0.5, "tags": ["a", null, true]}`}},
+			want: map[string]any{
+				"name":  "nook",
+				"stars": int64(10),
+				"ratio": 0.5,
+				"tags":  []any{"a", nil, true},
+			},
+		},
+		{
+			name:    "syntax error line and column",
+			args:    args{fn: ParseJSON, args: []any{"{\n  \"a\": 1,\n  \"b\" 2\n}"}},
+			wantErr: "invalid json at line 3 column 7: invalid character '2' after object key",
+		},
+		{
+			name:    "unexpected end",
+			args:    args{fn: ParseJSON, args: []any{"[1, 2"}},
+			wantErr: "invalid json at line 1 column 6: unexpected end of json",
+		},
+		{
+			name:    "trailing data",
+			args:    args{fn: ParseJSON, args: []any{"1 2"}},
+			wantErr: "invalid json at line 1 column 4: unexpected data after the json value",
+		},
+		{
+			name: "stringify",
+			args: args{fn: StringifyJSON, args: []any{map[string]any{"b": []any{int64(1), "<x>"}, "a": nil}}},
+			want: `{"a":null,"b":[1,"<x>"]}`,
+		},
+		{
+			name: "stringify pretty",
+			args: args{fn: StringifyJSON, args: []any{map[string]any{"a": []any{int64(1)}}, true}},
+			want: "{\n  \"a\": [\n    1\n  ]\n}",
+		},
+		{
+			name: "stringify indent",
+			args: args{fn: StringifyJSON, args: []any{[]any{true}, "\t"}},
+			want: "[\n\ttrue\n]",
+		},
+		{
+			name:    "stringify error",
+			args:    args{fn: StringifyJSON, args: []any{[]any{&Error{Message: "failed"}}}},
+			wantErr: "can not encode error 'failed' as json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.fn(tt.args.args...)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("err = %q, wantErr %q", gotErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == "" {
+				t.Errorf("got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrom(t *testing.T) {
+	type args struct {
+		fn   func(args ...any) (any, error)
+		text string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    any
+		wantErr string
+	}{
+		{
+			name: "table with right aligned columns",
+			args: args{fn: FromTable, text: "" +
+				"USER         PID %CPU COMMAND\n" +
+				"root           1  0.0 /sbin/init splash\n" +
+				"brandon    12345 10.5 nook\n"},
+			want: []any{
+				map[string]any{"user": "root", "pid": "1", "cpu": "0.0", "command": "/sbin/init splash"},
+				map[string]any{"user": "brandon", "pid": "12345", "cpu": "10.5", "command": "nook"},
+			},
+		},
+		{
+			name: "table with spaces in the header and empty values",
+			args: args{fn: FromTable, text: "" +
+				"CONTAINER ID   IMAGE     STATUS         PORTS      NAMES\n" +
+				"4c01db0b339c   nginx     Up 2 hours     80/tcp     web\n" +
+				"d7886598dbe2   redis     Exited (0)                cache\n"},
+			want: []any{
+				map[string]any{"container_id": "4c01db0b339c", "image": "nginx", "status": "Up 2 hours", "ports": "80/tcp", "names": "web"},
+				map[string]any{"container_id": "d7886598dbe2", "image": "redis", "status": "Exited (0)", "ports": "", "names": "cache"},
+			},
+		},
+		{
+			name: "ps aux with values wider than their header",
+			args: args{fn: FromTable, text: "" +
+				"USER         PID %CPU %MEM    VSZ   RSS TTY      STAT START   TIME COMMAND\n" +
+				"root           1  0.0  0.1 167744 11736 ?        Ss   Oct18   0:05 /sbin/init splash\n" +
+				"root         412  0.0  0.0  19472  9740 ?        Ss   Oct18   0:01 /lib/systemd/systemd-journald\n" +
+				"brandon     2231  3.1 12.4 38457344 2039876 ?    Sl   Oct18  41:12 /opt/google/chrome/chrome --type=renderer\n" +
+				"brandon     9876  0.0  0.0  11284  3412 pts/0    R+   09:14   0:00 ps aux\n"},
+			want: []any{
+				map[string]any{"user": "root", "pid": "1", "cpu": "0.0", "mem": "0.1", "vsz": "167744", "rss": "11736", "tty": "?", "stat": "Ss", "start": "Oct18", "time": "0:05", "command": "/sbin/init splash"},
+				map[string]any{"user": "root", "pid": "412", "cpu": "0.0", "mem": "0.0", "vsz": "19472", "rss": "9740", "tty": "?", "stat": "Ss", "start": "Oct18", "time": "0:01", "command": "/lib/systemd/systemd-journald"},
+				map[string]any{"user": "brandon", "pid": "2231", "cpu": "3.1", "mem": "12.4", "vsz": "38457344", "rss": "2039876", "tty": "?", "stat": "Sl", "start": "Oct18", "time": "41:12", "command": "/opt/google/chrome/chrome --type=renderer"},
+				map[string]any{"user": "brandon", "pid": "9876", "cpu": "0.0", "mem": "0.0", "vsz": "11284", "rss": "3412", "tty": "pts/0", "stat": "R+", "start": "09:14", "time": "0:00", "command": "ps aux"},
+			},
+		},
+		{
+			name: "table with multi byte values",
+			args: args{fn: FromTable, text: "" +
+				"NAME     SIZE\n" +
+				"café.txt 1K\n" +
+				"naïve    2K\n"},
+			want: []any{
+				map[string]any{"name": "café.txt", "size": "1K"},
+				map[string]any{"name": "naïve", "size": "2K"},
+			},
+		},
+		{
+			name: "empty table",
+			args: args{fn: FromTable, text: "\n"},
+			want: []any{},
+		},
+		{
+			name:    "table with duplicate columns",
+			args:    args{fn: FromTable, text: "a  A\n1  2\n"},
+			wantErr: "invalid table: header has more than one 'a' column",
+		},
+		{
+			name: "key value lines",
+			args: args{fn: FromKV, text: "" +
+				"# os release\n" +
+				"NAME=\"Ubuntu\"\n" +
+				"VERSION_ID='22.04'\n" +
+				"\n" +
+				"Memory Limit: 1g\n" +
+				"url=http://a.com?x=1\n"},
+			want: map[string]any{"name": "Ubuntu", "version_id": "22.04", "memory_limit": "1g", "url": "http://a.com?x=1"},
+		},
+		{
+			name:    "key value line without a separator",
+			args:    args{fn: FromKV, text: "a=1\nb\n"},
+			wantErr: "invalid key value pair at line 2: 'b'",
+		},
+		{
+			name: "git status",
+			args: args{fn: FromGitStatus, text: "" +
+				"# branch.oid 9f2c1e4\n" +
+				"# branch.head main\n" +
+				"# branch.upstream origin/main\n" +
+				"# branch.ab +2 -1\n" +
+				"1 .M N... 100644 100644 100644 3a1b 3a1b main.go\n" +
+				"1 A. N... 000000 100644 100644 0000 4c2d new file.go\n" +
+				"2 R. N... 100644 100644 100644 5e3f 5e3f R100 b.go\ta.go\n" +
+				"u UU N... 100644 100644 100644 100644 1a 2b 3c conflict.go\n" +
+				"? notes.txt\n"},
+			want: map[string]any{
+				"commit":   "9f2c1e4",
+				"branch":   "main",
+				"upstream": "origin/main",
+				"ahead":    int64(2),
+				"behind":   int64(1),
+				"files": []any{
+					map[string]any{"path": "main.go", "orig_path": "", "kind": "changed", "staged": "", "unstaged": "modified"},
+					map[string]any{"path": "new file.go", "orig_path": "", "kind": "changed", "staged": "added", "unstaged": ""},
+					map[string]any{"path": "b.go", "orig_path": "a.go", "kind": "renamed", "staged": "renamed", "unstaged": ""},
+					map[string]any{"path": "conflict.go", "orig_path": "", "kind": "unmerged", "staged": "unmerged", "unstaged": "unmerged"},
+					map[string]any{"path": "notes.txt", "orig_path": "", "kind": "untracked", "staged": "", "unstaged": ""},
+				},
+			},
+		},
+		{
+			name:    "git status short format",
+			args:    args{fn: FromGitStatus, text: " M main.go\n"},
+			wantErr: "invalid git status at line 1: ' M main.go'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.fn(tt.args.text)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("err = %q, wantErr %q", gotErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == "" {
+				t.Errorf("got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    Size
+		wantStr string
+		wantErr bool
+	}{
+		{name: "bytes", text: "512B", want: 512, wantStr: "512B"},
+		{name: "decimal unit", text: "10KB", want: 10000, wantStr: "10KB"},
+		{name: "binary unit", text: "1.5GiB", want: 1610612736, wantStr: "1.5GiB"},
+		{name: "underscores", text: "1_000MB", want: 1000000000, wantStr: "1GB"},
+		{name: "inexact", text: "1234567B", want: 1234567, wantStr: "1.18MiB"},
+		{name: "fraction of a unit", text: "1.1KB", want: 1100, wantStr: "1.1KB"},
+		{name: "unknown unit", text: "10kb", wantErr: true},
+		{name: "fractional bytes", text: "1.5B", wantErr: true},
+		{name: "fraction of a byte", text: "1.0001KB", wantErr: true},
+		{name: "too large", text: "9000000TiB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSize(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize() = %d, want %d", got, tt.want)
+			}
+			if got.String() != tt.wantStr {
+				t.Errorf("Size.String() = %s, want %s", got.String(), tt.wantStr)
+			}
+		})
+	}
+}
+
+func TestTime(t *testing.T) {
+	type args struct {
+		fn   func(args ...any) (any, error)
+		args []any
+	}
+	date := time.Date(2024, 3, 9, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		args    args
+		want    any
+		wantErr string
+	}{
+		{
+			name: "parse rfc3339",
+			args: args{fn: ParseTime, args: []any{"2024-03-09T14:30:00Z"}},
+			want: date,
+		},
+		{
+			name: "parse named layout",
+			args: args{fn: ParseTime, args: []any{"2024-03-09", "date"}},
+			want: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "parse wrong layout",
+			args:    args{fn: ParseTime, args: []any{"09/03/2024", "date"}},
+			wantErr: "'09/03/2024' does not match the layout 'date'",
+		},
+		{
+			name: "format go layout",
+			args: args{fn: FormatTime, args: []any{date, "Jan 2 15:04"}},
+			want: "Mar 9 14:30",
+		},
+		{
+			name:    "unknown zone",
+			args:    args{fn: InZone, args: []any{date, "Mars/Olympus"}},
+			wantErr: "unknown time zone 'Mars/Olympus'",
+		},
+		{
+			name: "add time",
+			args: args{fn: AddTime, args: []any{date, 90 * time.Minute}},
+			want: date.Add(90 * time.Minute),
+		},
+		{
+			name: "time between",
+			args: args{fn: TimeBetween, args: []any{date, date.Add(-2 * time.Hour)}},
+			want: 2 * time.Hour,
+		},
+		{
+			name: "divide durations",
+			args: args{fn: DivDurations, args: []any{time.Hour, 15 * time.Minute}},
+			want: 4.0,
+		},
+		{
+			name:    "divide duration by zero",
+			args:    args{fn: DivDuration, args: []any{time.Hour, int64(0)}},
+			wantErr: "division by zero",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.args.fn(tt.args.args...)
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("err = %q, wantErr %q", gotErr, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && tt.wantErr == "" {
+				t.Errorf("got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
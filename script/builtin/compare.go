@@ -0,0 +1,159 @@
+package builtin
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, compareBuiltins()...)
+}
+
+// compareBuiltins creates the comparison builtins. Equality is defined for any two values
+// of the same type and compares tuples and dicts field by field, ordering is only defined
+// for ints, floats, strings, paths, atoms, durations, times and sizes.
+func compareBuiltins() []Builtin {
+	// the type variable lets tuples, dicts and slices be compared, but only with values of the same type
+	equalTypes := []ast.TypeExpr{
+		&ast.IntType{}, &ast.FloatType{}, &ast.BigIntType{}, &ast.BoolType{}, &ast.StringType{},
+		&ast.PathType{}, &ast.AtomType{}, &ast.FlagType{}, &ast.DurationType{}, &ast.TimeType{}, &ast.SizeType{},
+		&ast.TypeVar{Name: "T"},
+	}
+	orderedTypes := []ast.TypeExpr{
+		&ast.IntType{}, &ast.FloatType{}, &ast.BigIntType{}, &ast.StringType{}, &ast.PathType{}, &ast.AtomType{},
+		&ast.DurationType{}, &ast.TimeType{}, &ast.SizeType{},
+	}
+
+	builtins := []Builtin{}
+	for _, paramType := range equalTypes {
+		builtins = append(builtins,
+			comparison("eq", paramType, Equal),
+			comparison("ne", paramType, NotEqual),
+		)
+	}
+	for _, paramType := range orderedTypes {
+		builtins = append(builtins,
+			comparison("lt", paramType, Less),
+			comparison("le", paramType, LessEqual),
+			comparison("gt", paramType, Greater),
+			comparison("ge", paramType, GreaterEqual),
+		)
+	}
+
+	builtins = append(builtins, Builtin{
+		Namespace: "logic",
+		Name:      "not",
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.BoolType{}}},
+			},
+			Return: &ast.BoolType{},
+		},
+		Fn: Not,
+	})
+
+	return builtins
+}
+
+// comparison creates a builtin in the cmp namespace that compares two values of the same type
+func comparison(name string, paramType ast.TypeExpr, fn func(args ...any) (any, error)) Builtin {
+	return Builtin{
+		Namespace: "cmp",
+		Name:      name,
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: paramType}, {Type: paramType}},
+			},
+			Return: &ast.BoolType{},
+		},
+		Fn: fn,
+	}
+}
+
+var Equal = func(args ...any) (any, error) {
+	return equal(args[0], args[1]), nil
+}
+
+var NotEqual = func(args ...any) (any, error) {
+	return !equal(args[0], args[1]), nil
+}
+
+// equal compares two values of the same type, tuples and dicts are compared item by item
+func equal(a, b any) bool {
+	if a, ok := a.(*big.Int); ok {
+		b, ok := b.(*big.Int)
+		return ok && a.Cmp(b) == 0
+	}
+	// times in different zones are still the same instant
+	if a, ok := a.(time.Time); ok {
+		b, ok := b.(time.Time)
+		return ok && a.Equal(b)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+var Less = func(args ...any) (any, error) {
+	order, err := compare(args[0], args[1])
+	return order < 0, err
+}
+
+var LessEqual = func(args ...any) (any, error) {
+	order, err := compare(args[0], args[1])
+	return order <= 0, err
+}
+
+var Greater = func(args ...any) (any, error) {
+	order, err := compare(args[0], args[1])
+	return order > 0, err
+}
+
+var GreaterEqual = func(args ...any) (any, error) {
+	order, err := compare(args[0], args[1])
+	return order >= 0, err
+}
+
+var Not = func(args ...any) (any, error) {
+	return !args[0].(bool), nil
+}
+
+// compare orders two values of the same type, strings, paths and atoms are ordered by their bytes
+func compare(a, b any) (int, error) {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case *big.Int:
+		if b, ok := b.(*big.Int); ok {
+			return a.Cmp(b), nil
+		}
+	case time.Duration:
+		if b, ok := b.(time.Duration); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case Size:
+		if b, ok := b.(Size); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b), nil
+		}
+	}
+
+	return 0, fmt.Errorf("can not compare values '%v' and '%v'", a, b)
+}
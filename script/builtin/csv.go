@@ -0,0 +1,192 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, csvBuiltins()...)
+}
+
+// csvBuiltins creates the builtins in the csv and tsv namespaces. Parsed rows are dicts keyed by
+// the header, or tuples if the text has no header. Fields are always parsed as strings.
+func csvBuiltins() []Builtin {
+	strType, boolType := &ast.StringType{}, &ast.BoolType{}
+	rows := &ast.SliceType{Elem: &ast.TraitType{}}
+
+	return []Builtin{
+		namespaced("csv", "parse", []ast.TypeExpr{strType}, rows, ParseCSV(',')),
+		namespaced("csv", "parse", []ast.TypeExpr{strType, boolType}, rows, ParseCSV(',')),
+		namespaced("csv", "parse", []ast.TypeExpr{strType, boolType, strType}, rows, ParseCSV(',')),
+		namespaced("csv", "write", []ast.TypeExpr{rows}, strType, WriteCSV(',')),
+		namespaced("csv", "write", []ast.TypeExpr{rows, strType}, strType, WriteCSV(',')),
+		namespaced("tsv", "parse", []ast.TypeExpr{strType}, rows, ParseCSV('\t')),
+		namespaced("tsv", "parse", []ast.TypeExpr{strType, boolType}, rows, ParseCSV('\t')),
+		namespaced("tsv", "write", []ast.TypeExpr{rows}, strType, WriteCSV('\t')),
+	}
+}
+
+// ParseCSV creates a builtin that parses text with the given delimiter. The optional arguments are
+// whether the first row is a header, which is true by default, and a delimiter to use instead.
+func ParseCSV(delimiter rune) func(args ...any) (any, error) {
+	return func(args ...any) (any, error) {
+		header, comma := true, delimiter
+		if len(args) > 1 {
+			header = args[1].(bool)
+		}
+		if len(args) > 2 {
+			var err error
+			comma, err = csvDelimiter(args[2].(string))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		reader := csv.NewReader(strings.NewReader(args[0].(string)))
+		reader.Comma = comma
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		rows := []any{}
+		if !header {
+			for _, record := range records {
+				row := Tuple{}
+				for _, field := range record {
+					row = append(row, field)
+				}
+				rows = append(rows, row)
+			}
+
+			return rows, nil
+		}
+
+		if len(records) == 0 {
+			return rows, nil
+		}
+
+		names := records[0]
+		for i, name := range names {
+			if slices.Contains(names[:i], name) {
+				return nil, fmt.Errorf("invalid csv: header has more than one '%s' column", name)
+			}
+		}
+
+		for _, record := range records[1:] {
+			row := map[string]any{}
+			for i, field := range record {
+				row[names[i]] = field
+			}
+			rows = append(rows, Dict{Names: names, Fields: row})
+		}
+
+		return rows, nil
+	}
+}
+
+// WriteCSV creates a builtin that writes rows as text with the given delimiter. Rows that are dicts
+// get a header row with the names of their fields in the order of the first row, rows that are
+// tuples or slices do not.
+func WriteCSV(delimiter rune) func(args ...any) (any, error) {
+	return func(args ...any) (any, error) {
+		comma := delimiter
+		if len(args) > 1 {
+			var err error
+			comma, err = csvDelimiter(args[1].(string))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		rows := args[0].([]any)
+		records := [][]string{}
+		var header []string
+		for i, row := range rows {
+			switch row := row.(type) {
+			case Dict:
+				if i == 0 {
+					header = row.Names
+					records = append(records, header)
+				}
+				if header == nil || !sameFields(row, header) {
+					return nil, fmt.Errorf("row %d does not have the same fields as the first row", i)
+				}
+
+				record := []string{}
+				for _, name := range header {
+					record = append(record, csvField(row.Fields[name]))
+				}
+				records = append(records, record)
+			case []any:
+				if header != nil {
+					return nil, fmt.Errorf("row %d does not have the same fields as the first row", i)
+				}
+
+				record := []string{}
+				for _, field := range row {
+					record = append(record, csvField(field))
+				}
+				records = append(records, record)
+			default:
+				return nil, fmt.Errorf("row %d must be a dict, tuple or slice", i)
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		writer := csv.NewWriter(buf)
+		writer.Comma = comma
+		if err := writer.WriteAll(records); err != nil {
+			return nil, fmt.Errorf("failed to write csv: %w", err)
+		}
+
+		return buf.String(), nil
+	}
+}
+
+// csvDelimiter checks that a delimiter is a single character
+func csvDelimiter(delimiter string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size == 0 || size != len(delimiter) || r == '"' || r == '\n' || r == '\r' {
+		return 0, fmt.Errorf("invalid csv delimiter '%s'", delimiter)
+	}
+
+	return r, nil
+}
+
+// csvField formats a single field, none is written as an empty field
+func csvField(field any) string {
+	switch field := field.(type) {
+	case nil:
+		return ""
+	case time.Duration:
+		return FormatDuration(field)
+	case time.Time:
+		return field.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(field)
+	}
+}
+
+// sameFields reports whether a dict has exactly the named fields, in any order
+func sameFields(dict Dict, names []string) bool {
+	if len(dict.Fields) != len(names) {
+		return false
+	}
+
+	for _, name := range names {
+		if _, ok := dict.Fields[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
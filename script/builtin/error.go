@@ -0,0 +1,36 @@
+package builtin
+
+// Error is an error value in nook script. Errors are created with fail, thrown with raise
+// and caught with try. Errors that are not caught keep a trace of the calls they were raised through.
+type Error struct {
+	Message string
+	Cause   *Error
+
+	// Trace is the list of calls the error was raised through, starting with the innermost call
+	Trace []Frame
+}
+
+// Frame is a single function call in the trace of an error
+type Frame struct {
+	Name string
+
+	// Path is the name of the source the call was made in
+	Path string
+	Pos  uint
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	if e.Cause == nil {
+		return nil
+	}
+
+	return e.Cause
+}
@@ -0,0 +1,341 @@
+package builtin
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, fromBuiltins()...)
+}
+
+// gitFileType is a single file in the dict returned by from.git_status
+var gitFileType = &ast.DictType{
+	Fields: []ast.FieldType{
+		{Name: "path", Type: &ast.PathType{}},
+		{Name: "orig_path", Type: &ast.PathType{}},
+		{Name: "kind", Type: &ast.StringType{}},
+		{Name: "staged", Type: &ast.StringType{}},
+		{Name: "unstaged", Type: &ast.StringType{}},
+	},
+}
+
+// gitStatusType is the dict returned by from.git_status
+var gitStatusType = &ast.DictType{
+	Fields: []ast.FieldType{
+		{Name: "commit", Type: &ast.StringType{}},
+		{Name: "branch", Type: &ast.StringType{}},
+		{Name: "upstream", Type: &ast.StringType{}},
+		{Name: "ahead", Type: &ast.IntType{}},
+		{Name: "behind", Type: &ast.IntType{}},
+		{Name: "files", Type: &ast.SliceType{Elem: gitFileType}},
+	},
+}
+
+// fromBuiltins creates the builtins in the from namespace. They parse the output of
+// common commands into dicts so it can be used without parsing strings by hand.
+func fromBuiltins() []Builtin {
+	strType := &ast.StringType{}
+
+	return []Builtin{
+		namespaced("from", "table", []ast.TypeExpr{strType}, &ast.SliceType{Elem: &ast.TraitType{}}, FromTable),
+		namespaced("from", "kv", []ast.TypeExpr{strType}, &ast.TraitType{}, FromKV),
+		namespaced("from", "git_status", []ast.TypeExpr{strType}, gitStatusType, FromGitStatus),
+	}
+}
+
+// FromTable parses text that is aligned into columns (e.g. the output of ps, df or docker ps).
+// The first line is the header and each following line becomes a dict keyed by the header names.
+// Columns are split wherever every line has a space, so values may contain single spaces.
+// Values that are wider than their header (e.g. a large RSS in ps aux) can fill the gap between
+// two header words, so a column with more than one header word is split into one column per word
+// if every line has a single word value for each of them. Columns are counted in runes.
+var FromTable = func(args ...any) (any, error) {
+	lines := [][]rune{}
+	for _, line := range strings.Split(args[0].(string), "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), " \r")
+		if line != "" {
+			lines = append(lines, []rune(line))
+		}
+	}
+
+	rows := []any{}
+	if len(lines) == 0 {
+		return rows, nil
+	}
+
+	header := lines[0]
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line))
+	}
+
+	// blank is true for every column where all the lines have a space
+	blank := make([]bool, width)
+	for i := range blank {
+		blank[i] = true
+		for _, line := range lines {
+			if i < len(line) && line[i] != ' ' {
+				blank[i] = false
+				break
+			}
+		}
+	}
+
+	// a column starts after each blank gap that is followed by more of the header
+	starts := []int{0}
+	for i := 1; i < len(header); i++ {
+		if blank[i-1] && !blank[i] {
+			starts = append(starts, i)
+		}
+	}
+
+	// names holds the field names of each column, a column that is split by its header words has more than one
+	names := [][]string{}
+	seen := []string{}
+	for i, start := range starts {
+		words := strings.Fields(column(header, starts, i))
+		if len(words) < 2 || !splitByWords(lines[1:], starts, i, len(words)) {
+			words = []string{strings.Join(words, " ")}
+		}
+
+		columnNames := []string{}
+		for j, word := range words {
+			name := fieldName(word)
+			if name == "" {
+				name = fmt.Sprintf("column_%d", start+j)
+			}
+			if slices.Contains(seen, name) {
+				return nil, fmt.Errorf("invalid table: header has more than one '%s' column", name)
+			}
+			seen = append(seen, name)
+			columnNames = append(columnNames, name)
+		}
+		names = append(names, columnNames)
+	}
+
+	for _, line := range lines[1:] {
+		row := map[string]any{}
+		for i, columnNames := range names {
+			value := column(line, starts, i)
+			if len(columnNames) == 1 {
+				row[columnNames[0]] = value
+				continue
+			}
+
+			values := strings.Fields(value)
+			for j, name := range columnNames {
+				row[name] = ""
+				if j < len(values) {
+					row[name] = values[j]
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// splitByWords reports whether the i'th column can be split into one column per header word,
+// which is only the case if every line has either no value or one single word value per header word
+func splitByWords(lines [][]rune, starts []int, i, words int) bool {
+	for _, line := range lines {
+		if values := len(strings.Fields(column(line, starts, i))); values != 0 && values != words {
+			return false
+		}
+	}
+
+	return true
+}
+
+// column returns the trimmed text of the i'th column in a line, the last column runs to the end of the line
+func column(line []rune, starts []int, i int) string {
+	start, end := starts[i], len(line)
+	if i+1 < len(starts) {
+		end = min(starts[i+1], len(line))
+	}
+	if start >= end {
+		return ""
+	}
+
+	return strings.TrimSpace(string(line[start:end]))
+}
+
+// fieldName turns a header or key into a dict field name (e.g. 'CONTAINER ID' becomes 'container_id')
+func fieldName(name string) string {
+	builder := strings.Builder{}
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if underscore && builder.Len() > 0 {
+				builder.WriteRune('_')
+			}
+			builder.WriteRune(r)
+			underscore = false
+			continue
+		}
+		underscore = true
+	}
+
+	return builder.String()
+}
+
+// FromKV parses lines of 'key=value' or 'key: value' pairs (e.g. /etc/os-release) into a dict.
+// Blank lines and lines starting with '#' are skipped and quotes around values are removed.
+var FromKV = func(args ...any) (any, error) {
+	fields := map[string]any{}
+	for i, line := range strings.Split(args[0].(string), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid key value pair at line %d: '%s'", i+1, line)
+		}
+
+		key := fieldName(line[:sep])
+		if key == "" {
+			return nil, fmt.Errorf("invalid key at line %d: '%s'", i+1, line)
+		}
+
+		value := strings.TrimSpace(line[sep+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		fields[key] = value
+	}
+
+	return fields, nil
+}
+
+// gitStates are the names of the status codes used by git status
+var gitStates = map[byte]string{
+	'.': "",
+	'M': "modified",
+	'T': "type_changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+	'U': "unmerged",
+}
+
+// FromGitStatus parses the output of 'git status --porcelain=v2'. The branch fields are
+// only set if the --branch flag was also passed to git status.
+var FromGitStatus = func(args ...any) (any, error) {
+	status := map[string]any{
+		"commit":   "",
+		"branch":   "",
+		"upstream": "",
+		"ahead":    int64(0),
+		"behind":   int64(0),
+	}
+
+	files := []any{}
+	for i, line := range strings.Split(args[0].(string), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		invalid := fmt.Errorf("invalid git status at line %d: '%s'", i+1, line)
+		kind, rest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "#":
+			if err := gitBranch(status, rest); err != nil {
+				return nil, invalid
+			}
+		case "1", "2", "u":
+			// the path is the last field, and may contain spaces
+			count := map[string]int{"1": 7, "2": 8, "u": 9}[kind]
+			fields := strings.SplitN(rest, " ", count+1)
+			if len(fields) != count+1 || len(fields[0]) != 2 {
+				return nil, invalid
+			}
+
+			file := map[string]any{
+				"path":      fields[count],
+				"orig_path": "",
+				"kind":      "changed",
+				"staged":    gitStates[fields[0][0]],
+				"unstaged":  gitStates[fields[0][1]],
+			}
+
+			switch kind {
+			case "2":
+				path, origPath, ok := strings.Cut(fields[count], "\t")
+				if !ok {
+					return nil, invalid
+				}
+				file["path"], file["orig_path"] = path, origPath
+				file["kind"] = "renamed"
+				if strings.HasPrefix(fields[count-1], "C") {
+					file["kind"] = "copied"
+				}
+			case "u":
+				file["kind"] = "unmerged"
+			}
+
+			files = append(files, file)
+		case "?", "!":
+			file := map[string]any{
+				"path":      rest,
+				"orig_path": "",
+				"kind":      "untracked",
+				"staged":    "",
+				"unstaged":  "",
+			}
+			if kind == "!" {
+				file["kind"] = "ignored"
+			}
+
+			files = append(files, file)
+		default:
+			return nil, invalid
+		}
+	}
+
+	status["files"] = files
+	return status, nil
+}
+
+// gitBranch parses a '# branch.<name> <value>' header line into the status dict
+func gitBranch(status map[string]any, header string) error {
+	name, value, _ := strings.Cut(header, " ")
+	switch name {
+	case "branch.oid":
+		status["commit"] = value
+	case "branch.head":
+		status["branch"] = value
+	case "branch.upstream":
+		status["upstream"] = value
+	case "branch.ab":
+		ahead, behind, ok := strings.Cut(value, " ")
+		if !ok {
+			return fmt.Errorf("invalid branch.ab header")
+		}
+
+		a, err := strconv.ParseInt(strings.TrimPrefix(ahead, "+"), 10, 64)
+		if err != nil {
+			return err
+		}
+		b, err := strconv.ParseInt(strings.TrimPrefix(behind, "-"), 10, 64)
+		if err != nil {
+			return err
+		}
+
+		status["ahead"], status["behind"] = a, b
+	}
+
+	// other headers (e.g. stash counts) are ignored
+	return nil
+}
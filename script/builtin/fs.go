@@ -0,0 +1,403 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+var ErrCanceled = errors.New("canceled")
+
+// Confirm is called before a builtin destroys data (e.g. removing a file or overwriting one with fs.write).
+// It is passed a description of the action and the action is canceled if it returns false.
+// If Confirm is nil every action is allowed.
+var Confirm func(action string) bool
+
+func init() {
+	Builtins = append(Builtins, fsBuiltins()...)
+}
+
+// fileType is the dict returned by fs.stat and fs.ls
+var fileType = &ast.DictType{
+	Fields: []ast.FieldType{
+		{Name: "name", Type: &ast.StringType{}},
+		{Name: "path", Type: &ast.PathType{}},
+		{Name: "size", Type: &ast.SizeType{}},
+		{Name: "mode", Type: &ast.StringType{}},
+		{Name: "mod_time", Type: &ast.TimeType{}},
+		{Name: "is_dir", Type: &ast.BoolType{}},
+	},
+}
+
+// fsBuiltins creates the builtins for reading and changing files
+func fsBuiltins() []Builtin {
+	pathType, strType, noneType := &ast.PathType{}, &ast.StringType{}, &ast.NoneType{}
+
+	return []Builtin{
+		namespaced("fs", "read", []ast.TypeExpr{pathType}, strType, ReadFile),
+		namespaced("fs", "read_lines", []ast.TypeExpr{pathType}, &ast.SliceType{Elem: strType}, ReadLines),
+		namespaced("fs", "write", []ast.TypeExpr{pathType, strType}, noneType, WriteFile),
+		namespaced("fs", "append", []ast.TypeExpr{pathType, strType}, noneType, AppendFile),
+		namespaced("fs", "stat", []ast.TypeExpr{pathType}, fileType, Stat),
+		namespaced("fs", "ls", []ast.TypeExpr{}, &ast.SliceType{Elem: fileType}, ListFiles),
+		namespaced("fs", "ls", []ast.TypeExpr{pathType}, &ast.SliceType{Elem: fileType}, ListFiles),
+		namespaced("fs", "mkdir", []ast.TypeExpr{pathType}, noneType, MakeDir),
+		namespaced("fs", "rm", []ast.TypeExpr{pathType}, noneType, Remove),
+		namespaced("fs", "mv", []ast.TypeExpr{pathType, pathType}, noneType, Move),
+		namespaced("fs", "cp", []ast.TypeExpr{pathType, pathType}, noneType, Copy),
+		namespaced("fs", "touch", []ast.TypeExpr{pathType}, noneType, Touch),
+		namespaced("fs", "tmpdir", []ast.TypeExpr{}, pathType, TempDir),
+	}
+}
+
+// confirm asks the Confirm hook if an action should be run
+func confirm(action string) error {
+	if Confirm == nil || Confirm(action) {
+		return nil
+	}
+
+	return fmt.Errorf("%s was %w", action, ErrCanceled)
+}
+
+// expandPaths expands the home directory in all the path arguments
+func expandPaths(args []any) ([]string, error) {
+	paths := []string{}
+	for _, arg := range args {
+		path, err := expandHome(arg.(string))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+var ReadFile = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", paths[0], err)
+	}
+
+	return string(data), nil
+}
+
+var ReadLines = func(args ...any) (any, error) {
+	data, err := ReadFile(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []any{}
+	text := strings.TrimSuffix(data.(string), "\n")
+	if text == "" {
+		return lines, nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimSuffix(line, "\r"))
+	}
+
+	return lines, nil
+}
+
+var WriteFile = func(args ...any) (any, error) {
+	paths, err := expandPaths(args[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(paths[0]); err == nil {
+		if err := confirm(fmt.Sprintf("overwrite '%s'", paths[0])); err != nil {
+			return nil, err
+		}
+	}
+
+	err = os.WriteFile(paths[0], []byte(args[1].(string)), 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write '%s': %w", paths[0], err)
+	}
+
+	return nil, nil
+}
+
+var AppendFile = func(args ...any) (any, error) {
+	paths, err := expandPaths(args[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(paths[0], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open '%s': %w", paths[0], err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(args[1].(string))
+	if err != nil {
+		return nil, fmt.Errorf("failed to append to '%s': %w", paths[0], err)
+	}
+
+	return nil, nil
+}
+
+var Stat = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", paths[0], err)
+	}
+
+	return fileInfo(args[0].(string), info), nil
+}
+
+var ListFiles = func(args ...any) (any, error) {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0].(string)
+	}
+
+	expanded, err := expandHome(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory '%s': %w", expanded, err)
+	}
+
+	files := []any{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat '%s': %w", entry.Name(), err)
+		}
+
+		files = append(files, fileInfo(filepath.Join(dir, entry.Name()), info))
+	}
+
+	return files, nil
+}
+
+// fileInfo creates the dict that describes a file, the path is kept the way it was written
+func fileInfo(path string, info fs.FileInfo) map[string]any {
+	return map[string]any{
+		"name":     info.Name(),
+		"path":     path,
+		"size":     Size(info.Size()),
+		"mode":     info.Mode().String(),
+		"mod_time": info.ModTime(),
+		"is_dir":   info.IsDir(),
+	}
+}
+
+var MakeDir = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(paths[0], 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make directory '%s': %w", paths[0], err)
+	}
+
+	return nil, nil
+}
+
+var Remove = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// RemoveAll does not fail for missing files, but a missing file is likely a mistake in the script
+	if _, err := os.Lstat(paths[0]); err != nil {
+		return nil, fmt.Errorf("failed to remove '%s': %w", paths[0], err)
+	}
+
+	if err := confirm(fmt.Sprintf("remove '%s'", paths[0])); err != nil {
+		return nil, err
+	}
+
+	err = os.RemoveAll(paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove '%s': %w", paths[0], err)
+	}
+
+	return nil, nil
+}
+
+var Move = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := destination(paths[0], paths[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(to); err == nil {
+		if err := confirm(fmt.Sprintf("overwrite '%s'", to)); err != nil {
+			return nil, err
+		}
+	}
+
+	err = os.Rename(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move '%s' to '%s': %w", from, to, err)
+	}
+
+	return nil, nil
+}
+
+var Copy = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := destination(paths[0], paths[1])
+	if err != nil {
+		return nil, err
+	}
+
+	// copying a file onto itself would empty it, and copying a directory into itself never ends
+	fromInfo, fromErr := os.Stat(from)
+	if toInfo, err := os.Stat(to); err == nil && fromErr == nil && os.SameFile(fromInfo, toInfo) {
+		return nil, fmt.Errorf("'%s' and '%s' are the same file", from, to)
+	}
+	if within(from, to) {
+		return nil, fmt.Errorf("can not copy '%s' into itself", from)
+	}
+
+	if _, err := os.Stat(to); err == nil {
+		if err := confirm(fmt.Sprintf("overwrite '%s'", to)); err != nil {
+			return nil, err
+		}
+	}
+
+	// directories are copied recursively
+	err = filepath.WalkDir(from, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		return copyFile(path, target, info.Mode().Perm())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy '%s' to '%s': %w", from, to, err)
+	}
+
+	return nil, nil
+}
+
+// destination finds where fs.mv and fs.cp should put a file. Like mv and cp, moving into an
+// existing directory keeps the name of the file, and a path ending in '/' must be a directory.
+func destination(from, to string) (string, string, error) {
+	info, err := os.Stat(to)
+	switch {
+	case err == nil && info.IsDir():
+		return from, filepath.Join(to, filepath.Base(from)), nil
+	case strings.HasSuffix(to, "/"):
+		return "", "", fmt.Errorf("'%s' is not a directory", to)
+	default:
+		return from, to, nil
+	}
+}
+
+// within reports whether path is inside of the directory dir
+func within(dir, path string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copyFile copies the contents of a single file
+func copyFile(from, to string, perm fs.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
+
+var Touch = func(args ...any) (any, error) {
+	paths, err := expandPaths(args)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = os.Chtimes(paths[0], now, now)
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.WriteFile(paths[0], nil, 0o644)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to touch '%s': %w", paths[0], err)
+	}
+
+	return nil, nil
+}
+
+var TempDir = func(args ...any) (any, error) {
+	dir, err := os.MkdirTemp("", "nook-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to make temp directory: %w", err)
+	}
+
+	return dir, nil
+}
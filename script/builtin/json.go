@@ -0,0 +1,182 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, jsonBuiltins()...)
+}
+
+// jsonBuiltins creates the builtins in the json namespace. Parsed json objects become dicts,
+// arrays become slices and numbers become ints if they are whole numbers or floats otherwise.
+// The result can be checked against a type with a typed literal (e.g. {<.name str> (json.parse text)})
+func jsonBuiltins() []Builtin {
+	anyType, strType := &ast.TraitType{}, &ast.StringType{}
+
+	return []Builtin{
+		namespaced("json", "parse", []ast.TypeExpr{strType}, anyType, ParseJSON),
+		namespaced("json", "stringify", []ast.TypeExpr{anyType}, strType, StringifyJSON),
+		namespaced("json", "stringify", []ast.TypeExpr{anyType, &ast.BoolType{}}, strType, StringifyJSON),
+		namespaced("json", "stringify", []ast.TypeExpr{anyType, strType}, strType, StringifyJSON),
+	}
+}
+
+var ParseJSON = func(args ...any) (any, error) {
+	text := args[0].(string)
+
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, jsonError(text, decoder.InputOffset(), err)
+	}
+
+	// only a single json value is allowed in the text
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, jsonError(text, decoder.InputOffset(), fmt.Errorf("unexpected data after the json value"))
+	}
+
+	return fromJSON(decoded)
+}
+
+// jsonError adds the line and column where decoding failed to the error
+func jsonError(text string, offset int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// the offset of a syntax error is after the invalid character
+		offset = syntaxErr.Offset - 1
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		offset = int64(len(text))
+		err = fmt.Errorf("unexpected end of json")
+	}
+
+	offset = max(0, min(offset, int64(len(text))))
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - strings.LastIndex(before, "\n")
+
+	return fmt.Errorf("invalid json at line %d column %d: %w", line, column, err)
+}
+
+// fromJSON converts decoded json into the values used by builtins
+func fromJSON(decoded any) (any, error) {
+	switch decoded := decoded.(type) {
+	case json.Number:
+		if i, err := decoded.Int64(); err == nil {
+			return i, nil
+		}
+
+		f, err := decoded.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid json number '%s'", decoded)
+		}
+		return f, nil
+	case []any:
+		items := []any{}
+		for _, item := range decoded {
+			value, err := fromJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case map[string]any:
+		fields := map[string]any{}
+		for name, field := range decoded {
+			value, err := fromJSON(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = value
+		}
+		return fields, nil
+	default:
+		// strings, bools and null are already the right type
+		return decoded, nil
+	}
+}
+
+var StringifyJSON = func(args ...any) (any, error) {
+	value, err := toJSON(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	indent := ""
+	if len(args) > 1 {
+		switch option := args[1].(type) {
+		case bool:
+			if option {
+				indent = "  "
+			}
+		case string:
+			indent = option
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode json: %w", err)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// toJSON checks that a value can be encoded as json, tuples are encoded as arrays.
+// Sizes are encoded as a number of bytes and durations and times as strings.
+func toJSON(value any) (any, error) {
+	switch value := value.(type) {
+	case nil, bool, int64, float64, string, *big.Int:
+		return value, nil
+	case Size:
+		return int64(value), nil
+	case time.Duration:
+		return FormatDuration(value), nil
+	case time.Time:
+		return value.Format(time.RFC3339), nil
+	case []any:
+		items := []any{}
+		for _, item := range value {
+			encoded, err := toJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, encoded)
+		}
+		return items, nil
+	case Dict:
+		return toJSON(value.Fields)
+	case map[string]any:
+		fields := map[string]any{}
+		for name, field := range value {
+			encoded, err := toJSON(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[name] = encoded
+		}
+		return fields, nil
+	case *Error:
+		return nil, fmt.Errorf("can not encode error '%s' as json", value.Message)
+	case *Func:
+		return nil, fmt.Errorf("can not encode a function as json")
+	default:
+		return nil, fmt.Errorf("can not encode '%v' as json", value)
+	}
+}
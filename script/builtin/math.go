@@ -0,0 +1,340 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+var (
+	ErrDivideByZero = errors.New("division by zero")
+	ErrOverflow     = errors.New("integer overflow, use math.big for arbitrary precision")
+)
+
+func init() {
+	Builtins = append(Builtins, mathBuiltins()...)
+}
+
+// mathBuiltins creates the integer division, exponent and bigint builtins
+func mathBuiltins() []Builtin {
+	intType, floatType, bigType := &ast.IntType{}, &ast.FloatType{}, &ast.BigIntType{}
+
+	return []Builtin{
+		arithmetic("add", bigType, AddBigInt),
+		arithmetic("sub", bigType, MinusBigInt),
+		arithmetic("mul", bigType, MultiplyBigInt),
+		arithmetic("div", bigType, DivideBigInt),
+		binary("mod", intType, intType, intType, ModInt64),
+		binary("mod", bigType, bigType, bigType, ModBigInt),
+		binary("rem", intType, intType, intType, RemInt64),
+		binary("rem", bigType, bigType, bigType, RemBigInt),
+		binary("pow", intType, intType, intType, PowInt64),
+		binary("pow", floatType, floatType, floatType, PowFloat64),
+		binary("pow", bigType, intType, bigType, PowBigInt),
+		{
+			Namespace: "math",
+			Name:      "big",
+			Type: &ast.FuncType{
+				Params: &ast.ParamList{Params: []ast.Param{{Type: intType}}},
+				Return: bigType,
+			},
+			Fn: IntToBigInt,
+		},
+		{
+			Namespace: "math",
+			Name:      "big",
+			Type: &ast.FuncType{
+				Params: &ast.ParamList{Params: []ast.Param{{Type: &ast.StringType{}}}},
+				Return: bigType,
+			},
+			Fn: ParseBigInt,
+		},
+		{
+			Namespace: "math",
+			Name:      "int",
+			Type: &ast.FuncType{
+				Params: &ast.ParamList{Params: []ast.Param{{Type: bigType}}},
+				Return: intType,
+			},
+			Fn: BigIntToInt,
+		},
+		{
+			Namespace: "math",
+			Name:      "int",
+			Type: &ast.FuncType{
+				Params: &ast.ParamList{Params: []ast.Param{{Type: floatType}}},
+				Return: intType,
+			},
+			Fn: FloatToInt,
+		},
+		{
+			Namespace: "math",
+			Name:      "float",
+			Type: &ast.FuncType{
+				Params: &ast.ParamList{Params: []ast.Param{{Type: &ast.NumberType{}}}},
+				Return: floatType,
+			},
+			Fn: NumberToFloat,
+		},
+	}
+}
+
+// arithmetic creates a variadic builtin in the math namespace
+func arithmetic(name string, paramType ast.TypeExpr, fn func(args ...any) (any, error)) Builtin {
+	return Builtin{
+		Namespace: "math",
+		Name:      name,
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: &ast.VariadicType{Type: paramType}}},
+			},
+			Return: paramType,
+		},
+		Fn: fn,
+	}
+}
+
+// binary creates a builtin in the math namespace that takes two arguments
+func binary(name string, a, b, ret ast.TypeExpr, fn func(args ...any) (any, error)) Builtin {
+	return Builtin{
+		Namespace: "math",
+		Name:      name,
+		Type: &ast.FuncType{
+			Params: &ast.ParamList{
+				Params: []ast.Param{{Type: a}, {Type: b}},
+			},
+			Return: ret,
+		},
+		Fn: fn,
+	}
+}
+
+func addInt(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrOverflow
+	}
+
+	return a + b, nil
+}
+
+func subInt(a, b int64) (int64, error) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, ErrOverflow
+	}
+
+	return a - b, nil
+}
+
+func mulInt(a, b int64) (int64, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, ErrOverflow
+	}
+
+	return product, nil
+}
+
+func divInt(a, b int64) (int64, error) {
+	if b == 0 {
+		return 0, ErrDivideByZero
+	}
+	if a == math.MinInt64 && b == -1 {
+		return 0, ErrOverflow
+	}
+
+	return a / b, nil
+}
+
+// ModInt64 returns the modulus of two ints, the result has the same sign as the divisor
+var ModInt64 = func(args ...any) (any, error) {
+	a, b := args[0].(int64), args[1].(int64)
+	if b == 0 {
+		return nil, ErrDivideByZero
+	}
+	if b == -1 {
+		return int64(0), nil
+	}
+
+	mod := a % b
+	if mod != 0 && (mod < 0) != (b < 0) {
+		mod += b
+	}
+	return mod, nil
+}
+
+// RemInt64 returns the remainder of two ints, the result has the same sign as the dividend
+var RemInt64 = func(args ...any) (any, error) {
+	a, b := args[0].(int64), args[1].(int64)
+	if b == 0 {
+		return nil, ErrDivideByZero
+	}
+	if b == -1 {
+		return int64(0), nil
+	}
+
+	return a % b, nil
+}
+
+var PowInt64 = func(args ...any) (any, error) {
+	base, exp := args[0].(int64), args[1].(int64)
+	if exp < 0 {
+		return nil, fmt.Errorf("int exponent must not be negative but got %d", exp)
+	}
+
+	result := int64(1)
+	for exp > 0 {
+		var err error
+		if exp&1 == 1 {
+			result, err = mulInt(result, base)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		exp >>= 1
+		if exp > 0 {
+			base, err = mulInt(base, base)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+var PowFloat64 = func(args ...any) (any, error) {
+	return math.Pow(args[0].(float64), args[1].(float64)), nil
+}
+
+var AddBigInt = func(args ...any) (any, error) {
+	sum := new(big.Int)
+	for _, arg := range args {
+		sum.Add(sum, arg.(*big.Int))
+	}
+	return sum, nil
+}
+
+var MinusBigInt = func(args ...any) (any, error) {
+	if len(args) == 0 {
+		return new(big.Int), nil
+	}
+
+	min := new(big.Int).Set(args[0].(*big.Int))
+	for _, arg := range args[1:] {
+		min.Sub(min, arg.(*big.Int))
+	}
+	return min, nil
+}
+
+var MultiplyBigInt = func(args ...any) (any, error) {
+	if len(args) == 0 {
+		return new(big.Int), nil
+	}
+
+	product := new(big.Int).Set(args[0].(*big.Int))
+	for _, arg := range args[1:] {
+		product.Mul(product, arg.(*big.Int))
+	}
+	return product, nil
+}
+
+var DivideBigInt = func(args ...any) (any, error) {
+	if len(args) == 0 {
+		return new(big.Int), nil
+	}
+
+	quotient := new(big.Int).Set(args[0].(*big.Int))
+	for _, arg := range args[1:] {
+		if arg.(*big.Int).Sign() == 0 {
+			return nil, ErrDivideByZero
+		}
+		// Quo truncates like int division
+		quotient.Quo(quotient, arg.(*big.Int))
+	}
+	return quotient, nil
+}
+
+var ModBigInt = func(args ...any) (any, error) {
+	a, b := args[0].(*big.Int), args[1].(*big.Int)
+	if b.Sign() == 0 {
+		return nil, ErrDivideByZero
+	}
+
+	mod := new(big.Int).Rem(a, b)
+	if mod.Sign() != 0 && mod.Sign() != b.Sign() {
+		mod.Add(mod, b)
+	}
+	return mod, nil
+}
+
+var RemBigInt = func(args ...any) (any, error) {
+	a, b := args[0].(*big.Int), args[1].(*big.Int)
+	if b.Sign() == 0 {
+		return nil, ErrDivideByZero
+	}
+
+	return new(big.Int).Rem(a, b), nil
+}
+
+var PowBigInt = func(args ...any) (any, error) {
+	base, exp := args[0].(*big.Int), args[1].(int64)
+	if exp < 0 {
+		return nil, fmt.Errorf("int exponent must not be negative but got %d", exp)
+	}
+
+	return new(big.Int).Exp(base, big.NewInt(exp), nil), nil
+}
+
+var IntToBigInt = func(args ...any) (any, error) {
+	return big.NewInt(args[0].(int64)), nil
+}
+
+var ParseBigInt = func(args ...any) (any, error) {
+	value, ok := new(big.Int).SetString(args[0].(string), 0)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a valid int", args[0])
+	}
+
+	return value, nil
+}
+
+// FloatToInt truncates a float towards zero
+var FloatToInt = func(args ...any) (any, error) {
+	value := math.Trunc(args[0].(float64))
+	if math.IsNaN(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+		return nil, fmt.Errorf("%v can not be converted to an int", args[0])
+	}
+
+	return int64(value), nil
+}
+
+var NumberToFloat = func(args ...any) (any, error) {
+	switch value := args[0].(type) {
+	case int64:
+		return float64(value), nil
+	case float64:
+		return value, nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(value).Float64()
+		return f, nil
+	default:
+		return nil, fmt.Errorf("'%v' is not a number", value)
+	}
+}
+
+var BigIntToInt = func(args ...any) (any, error) {
+	value := args[0].(*big.Int)
+	if !value.IsInt64() {
+		return nil, ErrOverflow
+	}
+
+	return value.Int64(), nil
+}
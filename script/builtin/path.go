@@ -0,0 +1,153 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, pathBuiltins()...)
+}
+
+// pathBuiltins creates the builtins in the path namespace. Paths that start with '~' are
+// expanded to the users home directory before they are used to look at the file system.
+func pathBuiltins() []Builtin {
+	pathType, strType, boolType := &ast.PathType{}, &ast.StringType{}, &ast.BoolType{}
+
+	return []Builtin{
+		namespaced("path", "join", []ast.TypeExpr{pathType, &ast.VariadicType{Type: pathType}}, pathType, JoinPath),
+		namespaced("path", "join", []ast.TypeExpr{pathType, &ast.VariadicType{Type: strType}}, pathType, JoinPath),
+		namespaced("path", "parent", []ast.TypeExpr{pathType}, pathType, ParentPath),
+		namespaced("path", "base", []ast.TypeExpr{pathType}, strType, BasePath),
+		namespaced("path", "ext", []ast.TypeExpr{pathType}, strType, PathExt),
+		namespaced("path", "stem", []ast.TypeExpr{pathType}, strType, PathStem),
+		namespaced("path", "abs", []ast.TypeExpr{pathType}, pathType, AbsPath),
+		namespaced("path", "rel", []ast.TypeExpr{pathType, pathType}, pathType, RelPath),
+		namespaced("path", "clean", []ast.TypeExpr{pathType}, pathType, CleanPath),
+		namespaced("path", "expand", []ast.TypeExpr{pathType}, pathType, ExpandPath),
+		namespaced("path", "exists", []ast.TypeExpr{pathType}, boolType, PathExists),
+		namespaced("path", "is_dir", []ast.TypeExpr{pathType}, boolType, IsDir),
+		// concatenating strings to a path, with str.concat or +, creates a new path rather than a string
+		namespaced("str", "concat", []ast.TypeExpr{pathType, &ast.VariadicType{Type: strType}}, pathType, Concat),
+		namespaced("math", "add", []ast.TypeExpr{pathType, &ast.VariadicType{Type: strType}}, pathType, Concat),
+	}
+}
+
+var JoinPath = func(args ...any) (any, error) {
+	parts := []string{}
+	for _, arg := range args {
+		parts = append(parts, arg.(string))
+	}
+
+	return filepath.Join(parts...), nil
+}
+
+var ParentPath = func(args ...any) (any, error) {
+	return filepath.Dir(args[0].(string)), nil
+}
+
+var BasePath = func(args ...any) (any, error) {
+	return filepath.Base(args[0].(string)), nil
+}
+
+var PathExt = func(args ...any) (any, error) {
+	return filepath.Ext(args[0].(string)), nil
+}
+
+var PathStem = func(args ...any) (any, error) {
+	base := filepath.Base(args[0].(string))
+	return strings.TrimSuffix(base, filepath.Ext(base)), nil
+}
+
+var AbsPath = func(args ...any) (any, error) {
+	path, err := expandHome(args[0].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for '%s': %w", path, err)
+	}
+
+	return abs, nil
+}
+
+var RelPath = func(args ...any) (any, error) {
+	path, err := expandHome(args[0].(string))
+	if err != nil {
+		return nil, err
+	}
+	base, err := expandHome(args[1].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' can not be made relative to '%s'", path, base)
+	}
+
+	return rel, nil
+}
+
+var CleanPath = func(args ...any) (any, error) {
+	return filepath.Clean(args[0].(string)), nil
+}
+
+var ExpandPath = func(args ...any) (any, error) {
+	return expandHome(args[0].(string))
+}
+
+var PathExists = func(args ...any) (any, error) {
+	path, err := expandHome(args[0].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", path, err)
+	}
+
+	return true, nil
+}
+
+var IsDir = func(args ...any) (any, error) {
+	path, err := expandHome(args[0].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", path, err)
+	}
+
+	return info.IsDir(), nil
+}
+
+// expandHome replaces a leading '~' in a path with the users home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+
+	return filepath.Join(home, path[1:]), nil
+}
@@ -0,0 +1,131 @@
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, regexBuiltins()...)
+}
+
+// regexBuiltins creates the builtins in the regex namespace. Like the str builtins
+// they take the string being searched first and the pattern second.
+func regexBuiltins() []Builtin {
+	strType, regexType := &ast.StringType{}, &ast.RegexType{}
+	strSlice := &ast.SliceType{Elem: strType}
+
+	return []Builtin{
+		namespaced("regex", "match", []ast.TypeExpr{strType, regexType}, &ast.BoolType{}, MatchRegex),
+		namespaced("regex", "find", []ast.TypeExpr{strType, regexType}, strType, FindRegex),
+		namespaced("regex", "find_all", []ast.TypeExpr{strType, regexType}, strSlice, FindAllRegex),
+		namespaced("regex", "captures", []ast.TypeExpr{strType, regexType}, &ast.TraitType{}, Captures),
+		namespaced("regex", "captures_all", []ast.TypeExpr{strType, regexType}, &ast.SliceType{Elem: &ast.TraitType{}}, CapturesAll),
+		namespaced("regex", "replace", []ast.TypeExpr{strType, regexType, strType}, strType, ReplaceRegex),
+		namespaced("regex", "split", []ast.TypeExpr{strType, regexType}, strSlice, SplitRegex),
+	}
+}
+
+// CompileRegex compiles a regex pattern, the error only includes the reason the pattern is invalid
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err == nil {
+		return re, nil
+	}
+
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		return nil, fmt.Errorf("invalid regex '%s': %s '%s'", pattern, syntaxErr.Code, syntaxErr.Expr)
+	}
+
+	return nil, fmt.Errorf("invalid regex '%s': %w", pattern, err)
+}
+
+var MatchRegex = func(args ...any) (any, error) {
+	return args[1].(*regexp.Regexp).MatchString(args[0].(string)), nil
+}
+
+var FindRegex = func(args ...any) (any, error) {
+	text, re := args[0].(string), args[1].(*regexp.Regexp)
+
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		return nil, fmt.Errorf("no match for %s", regexLiteral(re))
+	}
+
+	return text[loc[0]:loc[1]], nil
+}
+
+var FindAllRegex = func(args ...any) (any, error) {
+	found := []any{}
+	for _, match := range args[1].(*regexp.Regexp).FindAllString(args[0].(string), -1) {
+		found = append(found, match)
+	}
+
+	return found, nil
+}
+
+var Captures = func(args ...any) (any, error) {
+	text, re := args[0].(string), args[1].(*regexp.Regexp)
+
+	match := re.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("no match for %s", regexLiteral(re))
+	}
+
+	return captureGroups(re, match), nil
+}
+
+var CapturesAll = func(args ...any) (any, error) {
+	text, re := args[0].(string), args[1].(*regexp.Regexp)
+
+	all := []any{}
+	for _, match := range re.FindAllStringSubmatch(text, -1) {
+		all = append(all, captureGroups(re, match))
+	}
+
+	return all, nil
+}
+
+// captureGroups returns a dict of the named groups in a match. If the pattern
+// has no named groups a tuple of all the groups is returned instead.
+func captureGroups(re *regexp.Regexp, match []string) any {
+	groups := map[string]any{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = match[i]
+		}
+	}
+	if len(groups) > 0 {
+		return groups
+	}
+
+	tuple := Tuple{}
+	for _, group := range match[1:] {
+		tuple = append(tuple, group)
+	}
+
+	return tuple
+}
+
+var ReplaceRegex = func(args ...any) (any, error) {
+	return args[1].(*regexp.Regexp).ReplaceAllString(args[0].(string), args[2].(string)), nil
+}
+
+var SplitRegex = func(args ...any) (any, error) {
+	parts := []any{}
+	for _, part := range args[1].(*regexp.Regexp).Split(args[0].(string), -1) {
+		parts = append(parts, part)
+	}
+
+	return parts, nil
+}
+
+// regexLiteral formats a regex the way it is written in nook script
+func regexLiteral(re *regexp.Regexp) string {
+	return "#/" + re.String() + "/"
+}
@@ -0,0 +1,134 @@
+package builtin
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/token"
+)
+
+// Size is a number of bytes, it is used for size values so they can be told apart from ints
+type Size int64
+
+// sizeOrder is the order units are tried in when a size is formatted
+var sizeOrder = []string{"TiB", "TB", "GiB", "GB", "MiB", "MB", "KiB", "KB"}
+
+// ParseSize parses a size written as a number followed by a unit (e.g. 10KB or 1.5GiB)
+func ParseSize(text string) (Size, error) {
+	bytes, err := token.ParseSize(text)
+	return Size(bytes), err
+}
+
+// String formats the size with the largest unit that shows it exactly with at most two decimals,
+// sizes that can not be shown exactly are rounded to two decimals of a binary unit
+func (s Size) String() string {
+	bytes := int64(s)
+	abs := max(bytes, -bytes)
+	for _, name := range sizeOrder {
+		unit := token.SizeUnits[name]
+		if abs >= unit && abs <= math.MaxInt64/100 && (abs*100)%unit == 0 {
+			return strconv.FormatFloat(float64(bytes)/float64(unit), 'f', -1, 64) + name
+		}
+	}
+
+	for _, name := range []string{"TiB", "GiB", "MiB", "KiB"} {
+		unit := token.SizeUnits[name]
+		if abs >= unit {
+			value := strconv.FormatFloat(float64(bytes)/float64(unit), 'f', 2, 64)
+			return strings.TrimRight(strings.TrimRight(value, "0"), ".") + name
+		}
+	}
+
+	return strconv.FormatInt(bytes, 10) + "B"
+}
+
+func init() {
+	Builtins = append(Builtins, sizeBuiltins()...)
+}
+
+// sizeBuiltins creates the builtins in the size namespace as well as the arithmetic
+// overloads for sizes. Sizes can be added and subtracted, or scaled by an int.
+func sizeBuiltins() []Builtin {
+	sizeType, intType := &ast.SizeType{}, &ast.IntType{}
+
+	return []Builtin{
+		namespaced("size", "parse", []ast.TypeExpr{&ast.StringType{}}, sizeType, ParseSizeString),
+		namespaced("size", "bytes", []ast.TypeExpr{sizeType}, intType, SizeBytes),
+		namespaced("size", "of", []ast.TypeExpr{intType}, sizeType, SizeOf),
+		namespaced("math", "add", []ast.TypeExpr{&ast.VariadicType{Type: sizeType}}, sizeType, AddSize),
+		namespaced("math", "sub", []ast.TypeExpr{&ast.VariadicType{Type: sizeType}}, sizeType, SubSize),
+		namespaced("math", "mul", []ast.TypeExpr{sizeType, intType}, sizeType, MulSize),
+		namespaced("math", "div", []ast.TypeExpr{sizeType, intType}, sizeType, DivSize),
+		namespaced("math", "div", []ast.TypeExpr{sizeType, sizeType}, &ast.FloatType{}, DivSizes),
+	}
+}
+
+var ParseSizeString = func(args ...any) (any, error) {
+	return ParseSize(strings.TrimSpace(args[0].(string)))
+}
+
+var SizeBytes = func(args ...any) (any, error) {
+	return int64(args[0].(Size)), nil
+}
+
+var SizeOf = func(args ...any) (any, error) {
+	return Size(args[0].(int64)), nil
+}
+
+var AddSize = func(args ...any) (any, error) {
+	sum := int64(0)
+	for _, arg := range args {
+		var err error
+		sum, err = addInt(sum, int64(arg.(Size)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Size(sum), nil
+}
+
+var SubSize = func(args ...any) (any, error) {
+	if len(args) == 0 {
+		return Size(0), nil
+	}
+
+	diff := int64(args[0].(Size))
+	for _, arg := range args[1:] {
+		var err error
+		diff, err = subInt(diff, int64(arg.(Size)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Size(diff), nil
+}
+
+var MulSize = func(args ...any) (any, error) {
+	product, err := mulInt(int64(args[0].(Size)), args[1].(int64))
+	if err != nil {
+		return nil, err
+	}
+
+	return Size(product), nil
+}
+
+var DivSize = func(args ...any) (any, error) {
+	quotient, err := divInt(int64(args[0].(Size)), args[1].(int64))
+	if err != nil {
+		return nil, err
+	}
+
+	return Size(quotient), nil
+}
+
+var DivSizes = func(args ...any) (any, error) {
+	if args[1].(Size) == 0 {
+		return nil, ErrDivideByZero
+	}
+
+	return float64(args[0].(Size)) / float64(args[1].(Size)), nil
+}
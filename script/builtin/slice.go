@@ -0,0 +1,236 @@
+package builtin
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, sliceBuiltins()...)
+}
+
+// sliceBuiltins creates the builtins in the slice namespace. They are generic over the element
+// type of the slice, T, and the checker makes sure any functions passed to them accept T.
+func sliceBuiltins() []Builtin {
+	t, u, k := &ast.TypeVar{Name: "T"}, &ast.TypeVar{Name: "U"}, &ast.TypeVar{Name: "K"}
+	intType := &ast.IntType{}
+	sliceT, sliceU := &ast.SliceType{Elem: t}, &ast.SliceType{Elem: u}
+
+	return []Builtin{
+		namespaced("slice", "map", []ast.TypeExpr{sliceT, callback([]ast.TypeExpr{t}, u)}, sliceU, Map),
+		namespaced("slice", "filter", []ast.TypeExpr{sliceT, callback([]ast.TypeExpr{t}, &ast.BoolType{})}, sliceT, Filter),
+		namespaced("slice", "reduce", []ast.TypeExpr{sliceT, callback([]ast.TypeExpr{u, t}, u), u}, u, Reduce),
+		namespaced("slice", "each", []ast.TypeExpr{sliceT, callback([]ast.TypeExpr{t}, u)}, &ast.NoneType{}, Each),
+		namespaced("slice", "sort_by", []ast.TypeExpr{sliceT, callback([]ast.TypeExpr{t}, k)}, sliceT, SortBy),
+		namespaced("slice", "group_by", []ast.TypeExpr{sliceT, callback([]ast.TypeExpr{t}, k)},
+			&ast.SliceType{Elem: &ast.TupleType{Types: []ast.TypeExpr{k, sliceT}}}, GroupBy),
+		namespaced("slice", "zip", []ast.TypeExpr{sliceT, sliceU},
+			&ast.SliceType{Elem: &ast.TupleType{Types: []ast.TypeExpr{t, u}}}, Zip),
+		namespaced("slice", "enumerate", []ast.TypeExpr{sliceT},
+			&ast.SliceType{Elem: &ast.TupleType{Types: []ast.TypeExpr{intType, t}}}, Enumerate),
+		namespaced("slice", "take", []ast.TypeExpr{sliceT, intType}, sliceT, Take),
+		namespaced("slice", "drop", []ast.TypeExpr{sliceT, intType}, sliceT, Drop),
+		namespaced("slice", "uniq", []ast.TypeExpr{sliceT}, sliceT, Uniq),
+		namespaced("slice", "flatten", []ast.TypeExpr{&ast.SliceType{Elem: sliceT}}, sliceT, Flatten),
+	}
+}
+
+// callback creates the type of a function that is passed to a builtin
+func callback(params []ast.TypeExpr, ret ast.TypeExpr) *ast.FuncType {
+	paramList := &ast.ParamList{}
+	for _, param := range params {
+		paramList.Params = append(paramList.Params, ast.Param{Type: param})
+	}
+
+	return &ast.FuncType{Params: paramList, Return: ret}
+}
+
+var Map = func(args ...any) (any, error) {
+	fn := args[1].(*Func)
+
+	mapped := []any{}
+	for _, item := range args[0].([]any) {
+		value, err := fn.Call(item)
+		if err != nil {
+			return nil, err
+		}
+		mapped = append(mapped, value)
+	}
+
+	return mapped, nil
+}
+
+var Filter = func(args ...any) (any, error) {
+	fn := args[1].(*Func)
+
+	filtered := []any{}
+	for _, item := range args[0].([]any) {
+		result, err := fn.Call(item)
+		if err != nil {
+			return nil, err
+		}
+
+		// the checker can not know what untyped functions return
+		keep, ok := result.(bool)
+		if !ok {
+			return nil, fmt.Errorf("filter function must return a bool")
+		}
+		if keep {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered, nil
+}
+
+var Reduce = func(args ...any) (any, error) {
+	fn := args[1].(*Func)
+
+	acc := args[2]
+	for _, item := range args[0].([]any) {
+		var err error
+		acc, err = fn.Call(acc, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return acc, nil
+}
+
+var Each = func(args ...any) (any, error) {
+	fn := args[1].(*Func)
+
+	for _, item := range args[0].([]any) {
+		if _, err := fn.Call(item); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+var SortBy = func(args ...any) (any, error) {
+	fn := args[1].(*Func)
+	items := args[0].([]any)
+
+	keys := []any{}
+	for _, item := range items {
+		key, err := fn.Call(item)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	// the keys are sorted along with the items so each key is only computed once
+	order := []int{}
+	for i := range items {
+		order = append(order, i)
+	}
+
+	var sortErr error
+	slices.SortStableFunc(order, func(a, b int) int {
+		c, err := compare(keys[a], keys[b])
+		if err != nil && sortErr == nil {
+			sortErr = fmt.Errorf("can not sort by '%v': %w", keys[a], err)
+		}
+		return c
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	sorted := []any{}
+	for _, i := range order {
+		sorted = append(sorted, items[i])
+	}
+
+	return sorted, nil
+}
+
+var GroupBy = func(args ...any) (any, error) {
+	fn := args[1].(*Func)
+
+	// groups are kept in the order their keys were first seen
+	groups := []any{}
+	for _, item := range args[0].([]any) {
+		key, err := fn.Call(item)
+		if err != nil {
+			return nil, err
+		}
+
+		i := slices.IndexFunc(groups, func(group any) bool {
+			return equal(group.([]any)[0], key)
+		})
+		if i < 0 {
+			groups = append(groups, []any{key, []any{}})
+			i = len(groups) - 1
+		}
+
+		group := groups[i].([]any)
+		group[1] = append(group[1].([]any), item)
+	}
+
+	return groups, nil
+}
+
+var Zip = func(args ...any) (any, error) {
+	a, b := args[0].([]any), args[1].([]any)
+
+	zipped := []any{}
+	for i := 0; i < min(len(a), len(b)); i++ {
+		zipped = append(zipped, []any{a[i], b[i]})
+	}
+
+	return zipped, nil
+}
+
+var Enumerate = func(args ...any) (any, error) {
+	enumerated := []any{}
+	for i, item := range args[0].([]any) {
+		enumerated = append(enumerated, []any{int64(i), item})
+	}
+
+	return enumerated, nil
+}
+
+var Take = func(args ...any) (any, error) {
+	items, n := args[0].([]any), args[1].(int64)
+	if n < 0 {
+		return nil, fmt.Errorf("can not take %d items", n)
+	}
+
+	return items[:min(int(n), len(items))], nil
+}
+
+var Drop = func(args ...any) (any, error) {
+	items, n := args[0].([]any), args[1].(int64)
+	if n < 0 {
+		return nil, fmt.Errorf("can not drop %d items", n)
+	}
+
+	return items[min(int(n), len(items)):], nil
+}
+
+var Uniq = func(args ...any) (any, error) {
+	unique := []any{}
+	for _, item := range args[0].([]any) {
+		if !slices.ContainsFunc(unique, func(seen any) bool { return equal(seen, item) }) {
+			unique = append(unique, item)
+		}
+	}
+
+	return unique, nil
+}
+
+var Flatten = func(args ...any) (any, error) {
+	flat := []any{}
+	for _, items := range args[0].([]any) {
+		flat = append(flat, items.([]any)...)
+	}
+
+	return flat, nil
+}
@@ -0,0 +1,173 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func init() {
+	Builtins = append(Builtins, strBuiltins()...)
+}
+
+// strBuiltins creates the builtins in the str namespace. Lengths and offsets are in bytes
+// unless the name starts with rune, in which case they count unicode characters.
+func strBuiltins() []Builtin {
+	strType, intType, boolType := &ast.StringType{}, &ast.IntType{}, &ast.BoolType{}
+	strSlice := &ast.SliceType{Elem: strType}
+
+	return []Builtin{
+		namespaced("str", "concat", []ast.TypeExpr{&ast.VariadicType{Type: strType}}, strType, Concat),
+		namespaced("str", "split", []ast.TypeExpr{strType, strType}, strSlice, Split),
+		namespaced("str", "join", []ast.TypeExpr{strSlice, strType}, strType, Join),
+		namespaced("str", "trim", []ast.TypeExpr{strType}, strType, Trim),
+		namespaced("str", "trim", []ast.TypeExpr{strType, strType}, strType, Trim),
+		namespaced("str", "contains", []ast.TypeExpr{strType, strType}, boolType, Contains),
+		namespaced("str", "starts_with", []ast.TypeExpr{strType, strType}, boolType, StartsWith),
+		namespaced("str", "ends_with", []ast.TypeExpr{strType, strType}, boolType, EndsWith),
+		namespaced("str", "replace", []ast.TypeExpr{strType, strType, strType}, strType, Replace),
+		namespaced("str", "upper", []ast.TypeExpr{strType}, strType, Upper),
+		namespaced("str", "lower", []ast.TypeExpr{strType}, strType, Lower),
+		namespaced("str", "len", []ast.TypeExpr{strType}, intType, Length),
+		namespaced("str", "rune_len", []ast.TypeExpr{strType}, intType, RuneLength),
+		namespaced("str", "sub", []ast.TypeExpr{strType, intType, intType}, strType, Substring),
+		namespaced("str", "rune_sub", []ast.TypeExpr{strType, intType, intType}, strType, RuneSubstring),
+		namespaced("str", "bytes", []ast.TypeExpr{strType}, &ast.SliceType{Elem: intType}, Bytes),
+		namespaced("str", "runes", []ast.TypeExpr{strType}, strSlice, Runes),
+		namespaced("str", "format", []ast.TypeExpr{strType, &ast.VariadicType{Type: &ast.TraitType{}}}, strType, Format),
+	}
+}
+
+var Concat = func(args ...any) (any, error) {
+	builder := strings.Builder{}
+	for _, arg := range args {
+		builder.WriteString(arg.(string))
+	}
+
+	return builder.String(), nil
+}
+
+var Split = func(args ...any) (any, error) {
+	parts := []any{}
+	for _, part := range strings.Split(args[0].(string), args[1].(string)) {
+		parts = append(parts, part)
+	}
+
+	return parts, nil
+}
+
+var Join = func(args ...any) (any, error) {
+	parts := []string{}
+	for _, part := range args[0].([]any) {
+		parts = append(parts, part.(string))
+	}
+
+	return strings.Join(parts, args[1].(string)), nil
+}
+
+var Trim = func(args ...any) (any, error) {
+	if len(args) > 1 {
+		return strings.Trim(args[0].(string), args[1].(string)), nil
+	}
+
+	return strings.TrimSpace(args[0].(string)), nil
+}
+
+var Contains = func(args ...any) (any, error) {
+	return strings.Contains(args[0].(string), args[1].(string)), nil
+}
+
+var StartsWith = func(args ...any) (any, error) {
+	return strings.HasPrefix(args[0].(string), args[1].(string)), nil
+}
+
+var EndsWith = func(args ...any) (any, error) {
+	return strings.HasSuffix(args[0].(string), args[1].(string)), nil
+}
+
+var Replace = func(args ...any) (any, error) {
+	return strings.ReplaceAll(args[0].(string), args[1].(string), args[2].(string)), nil
+}
+
+var Upper = func(args ...any) (any, error) {
+	return strings.ToUpper(args[0].(string)), nil
+}
+
+var Lower = func(args ...any) (any, error) {
+	return strings.ToLower(args[0].(string)), nil
+}
+
+var Length = func(args ...any) (any, error) {
+	return int64(len(args[0].(string))), nil
+}
+
+var RuneLength = func(args ...any) (any, error) {
+	return int64(utf8.RuneCountInString(args[0].(string))), nil
+}
+
+var Substring = func(args ...any) (any, error) {
+	s := args[0].(string)
+	start, end, err := bounds(args[1].(int64), args[2].(int64), len(s))
+	if err != nil {
+		return nil, err
+	}
+
+	// slicing in the middle of a character would create an invalid string
+	if !utf8.RuneStart(byteAt(s, start)) || !utf8.RuneStart(byteAt(s, end)) {
+		return nil, fmt.Errorf("[%d %d] splits a character, use str.rune_sub to index by character", start, end)
+	}
+
+	return s[start:end], nil
+}
+
+var RuneSubstring = func(args ...any) (any, error) {
+	runes := []rune(args[0].(string))
+	start, end, err := bounds(args[1].(int64), args[2].(int64), len(runes))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(runes[start:end]), nil
+}
+
+var Bytes = func(args ...any) (any, error) {
+	bytes := []any{}
+	for _, b := range []byte(args[0].(string)) {
+		bytes = append(bytes, int64(b))
+	}
+
+	return bytes, nil
+}
+
+var Runes = func(args ...any) (any, error) {
+	runes := []any{}
+	for _, r := range args[0].(string) {
+		runes = append(runes, string(r))
+	}
+
+	return runes, nil
+}
+
+var Format = func(args ...any) (any, error) {
+	return fmt.Sprintf(args[0].(string), args[1:]...), nil
+}
+
+// bounds checks that start and end are a valid range for a string of the given length
+func bounds(start, end int64, length int) (int, int, error) {
+	if start < 0 || end < start || end > int64(length) {
+		return 0, 0, fmt.Errorf("[%d %d] is out of range for a string of length %d", start, end, length)
+	}
+
+	return int(start), int(end), nil
+}
+
+// byteAt returns the byte at index i, the end of the string is treated as the start of a character
+func byteAt(s string, i int) byte {
+	if i == len(s) {
+		return 0
+	}
+
+	return s[i]
+}
@@ -0,0 +1,221 @@
+package builtin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// time zones are embeded so time.in works on systems without a zone database
+	_ "time/tzdata"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+// Layouts are the names of common time layouts that can be used in place of a go layout
+var Layouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+	"date":     time.DateOnly,
+	"time":     time.TimeOnly,
+	"datetime": time.DateTime,
+	"kitchen":  time.Kitchen,
+}
+
+func init() {
+	Builtins = append(Builtins, timeBuiltins()...)
+}
+
+// timeBuiltins creates the builtins in the time and duration namespaces as well as the arithmetic
+// overloads for durations and times. Durations can be added to or subtracted from times, and
+// subtracting two times gives the duration between them.
+func timeBuiltins() []Builtin {
+	timeType, durationType := &ast.TimeType{}, &ast.DurationType{}
+	strType, intType, floatType := &ast.StringType{}, &ast.IntType{}, &ast.FloatType{}
+
+	return []Builtin{
+		namespaced("time", "now", []ast.TypeExpr{}, timeType, Now),
+		namespaced("time", "parse", []ast.TypeExpr{strType}, timeType, ParseTime),
+		namespaced("time", "parse", []ast.TypeExpr{strType, strType}, timeType, ParseTime),
+		namespaced("time", "format", []ast.TypeExpr{timeType}, strType, FormatTime),
+		namespaced("time", "format", []ast.TypeExpr{timeType, strType}, strType, FormatTime),
+		namespaced("time", "in", []ast.TypeExpr{timeType, strType}, timeType, InZone),
+		namespaced("time", "unix", []ast.TypeExpr{timeType}, intType, Unix),
+		namespaced("time", "from_unix", []ast.TypeExpr{intType}, timeType, FromUnix),
+		namespaced("time", "since", []ast.TypeExpr{timeType}, durationType, Since),
+		namespaced("time", "sleep", []ast.TypeExpr{durationType}, &ast.NoneType{}, Sleep),
+		namespaced("duration", "parse", []ast.TypeExpr{strType}, durationType, ParseDuration),
+		namespaced("duration", "seconds", []ast.TypeExpr{durationType}, floatType, Seconds),
+		namespaced("math", "add", []ast.TypeExpr{&ast.VariadicType{Type: durationType}}, durationType, AddDuration),
+		namespaced("math", "add", []ast.TypeExpr{timeType, durationType}, timeType, AddTime),
+		namespaced("math", "sub", []ast.TypeExpr{&ast.VariadicType{Type: durationType}}, durationType, SubDuration),
+		namespaced("math", "sub", []ast.TypeExpr{timeType, durationType}, timeType, SubTime),
+		namespaced("math", "sub", []ast.TypeExpr{timeType, timeType}, durationType, TimeBetween),
+		namespaced("math", "mul", []ast.TypeExpr{durationType, intType}, durationType, MulDuration),
+		namespaced("math", "div", []ast.TypeExpr{durationType, intType}, durationType, DivDuration),
+		namespaced("math", "div", []ast.TypeExpr{durationType, durationType}, floatType, DivDurations),
+	}
+}
+
+// layout returns the go layout for a layout name, any other layout is used as is
+func layout(name string) string {
+	if layout, ok := Layouts[name]; ok {
+		return layout
+	}
+
+	return name
+}
+
+var Now = func(args ...any) (any, error) {
+	return time.Now(), nil
+}
+
+var ParseTime = func(args ...any) (any, error) {
+	text, name := args[0].(string), "rfc3339"
+	if len(args) > 1 {
+		name = args[1].(string)
+	}
+
+	t, err := time.Parse(layout(name), strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("'%s' does not match the layout '%s'", text, name)
+	}
+
+	return t, nil
+}
+
+var FormatTime = func(args ...any) (any, error) {
+	name := "rfc3339"
+	if len(args) > 1 {
+		name = args[1].(string)
+	}
+
+	return args[0].(time.Time).Format(layout(name)), nil
+}
+
+var InZone = func(args ...any) (any, error) {
+	zone := args[1].(string)
+	switch strings.ToLower(zone) {
+	case "utc":
+		zone = "UTC"
+	case "local":
+		zone = "Local"
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone '%s'", args[1].(string))
+	}
+
+	return args[0].(time.Time).In(location), nil
+}
+
+var Unix = func(args ...any) (any, error) {
+	return args[0].(time.Time).Unix(), nil
+}
+
+var FromUnix = func(args ...any) (any, error) {
+	return time.Unix(args[0].(int64), 0), nil
+}
+
+var Since = func(args ...any) (any, error) {
+	return time.Since(args[0].(time.Time)), nil
+}
+
+var Sleep = func(args ...any) (any, error) {
+	time.Sleep(args[0].(time.Duration))
+	return nil, nil
+}
+
+var ParseDuration = func(args ...any) (any, error) {
+	text := strings.TrimSpace(args[0].(string))
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration '%s'", text)
+	}
+
+	return d, nil
+}
+
+var Seconds = func(args ...any) (any, error) {
+	return args[0].(time.Duration).Seconds(), nil
+}
+
+var AddDuration = func(args ...any) (any, error) {
+	sum := int64(0)
+	for _, arg := range args {
+		var err error
+		sum, err = addInt(sum, int64(arg.(time.Duration)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return time.Duration(sum), nil
+}
+
+var AddTime = func(args ...any) (any, error) {
+	return args[0].(time.Time).Add(args[1].(time.Duration)), nil
+}
+
+var SubDuration = func(args ...any) (any, error) {
+	if len(args) == 0 {
+		return time.Duration(0), nil
+	}
+
+	diff := int64(args[0].(time.Duration))
+	for _, arg := range args[1:] {
+		var err error
+		diff, err = subInt(diff, int64(arg.(time.Duration)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return time.Duration(diff), nil
+}
+
+var SubTime = func(args ...any) (any, error) {
+	return args[0].(time.Time).Add(-args[1].(time.Duration)), nil
+}
+
+var TimeBetween = func(args ...any) (any, error) {
+	return args[0].(time.Time).Sub(args[1].(time.Time)), nil
+}
+
+var MulDuration = func(args ...any) (any, error) {
+	product, err := mulInt(int64(args[0].(time.Duration)), args[1].(int64))
+	if err != nil {
+		return nil, err
+	}
+
+	return time.Duration(product), nil
+}
+
+var DivDuration = func(args ...any) (any, error) {
+	quotient, err := divInt(int64(args[0].(time.Duration)), args[1].(int64))
+	if err != nil {
+		return nil, err
+	}
+
+	return time.Duration(quotient), nil
+}
+
+var DivDurations = func(args ...any) (any, error) {
+	if args[1].(time.Duration) == 0 {
+		return nil, ErrDivideByZero
+	}
+
+	return float64(args[0].(time.Duration)) / float64(args[1].(time.Duration)), nil
+}
+
+// FormatDuration formats a duration without the zero minutes and seconds go adds (e.g. 2h rather than 2h0m0s)
+func FormatDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+
+	return text
+}
@@ -2,6 +2,7 @@ package checker
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/bjatkin/nook/script/ast"
@@ -13,21 +14,78 @@ import (
 type Checker struct {
 	table  *symbol.Table
 	Errors []error
+
+	// root is the top level table, the checker always returns to it after a cell is checked
+	root *symbol.Table
+
+	// transaction holds every table that has been changed since Begin was called
+	transaction []*symbol.Table
 }
 
 func NewChecker() *Checker {
 	table := symbol.NewTable()
 
-	// add builtins to the symbole table
+	aliases := map[string][]string{}
+	for alias, name := range builtin.Aliases {
+		aliases[name] = append(aliases[name], alias)
+	}
+
+	// add builtins to their namespaces in the symbole table
 	for _, builtin := range builtin.Builtins {
-		table.AddBuiltin(builtin)
+		namespace, ok := table.LookupNamespace(builtin.Namespace)
+		if !ok {
+			namespace = table.OpenScope()
+			table.AddNamespace(builtin.Namespace, namespace)
+		}
+		namespace.AddBuiltin(builtin)
+
+		for _, alias := range aliases[builtin.Namespace+"."+builtin.Name] {
+			aliased := builtin
+			aliased.Name = alias
+			table.AddBuiltin(aliased)
+		}
 	}
 
 	return &Checker{
 		table: table,
+		root:  table,
 	}
 }
 
+// Begin starts a new transaction. Symbols added after Begin is called are only
+// kept if Commit is called, Rollback will remove them from the checker.
+func (c *Checker) Begin() {
+	c.Errors = nil
+	c.table.Begin()
+	c.transaction = []*symbol.Table{c.table}
+}
+
+// Commit keeps all the symbols added since Begin was called
+func (c *Checker) Commit() {
+	for _, table := range c.transaction {
+		table.Commit()
+	}
+	c.transaction = nil
+}
+
+// Rollback removes all the symbols added since Begin was called, clears any errors and
+// returns the checker to the top level scope
+func (c *Checker) Rollback() {
+	for _, table := range c.transaction {
+		table.Rollback()
+	}
+	c.transaction = nil
+	c.Errors = nil
+
+	// a panic while checking can leave the checker inside of a nested scope
+	c.table = c.root
+}
+
+// Table returns the symbol table for the current scope of the checker
+func (c *Checker) Table() *symbol.Table {
+	return c.table
+}
+
 func (c *Checker) openScope() {
 	scope := c.table.OpenScope()
 	c.table = scope
@@ -43,6 +101,101 @@ func (c *Checker) addError(err error) {
 	c.Errors = append(c.Errors, err)
 }
 
+// inferSlice checks that every item in the slice matches the element type. If the element
+// type is [_] it is infered from the items, items of different types make it a slice of any.
+// Items that are only known at runtime are converted to the element type, and a typed slice
+// with a single any item converts that value into the whole slice.
+func (c *Checker) inferSlice(expr *ast.Slice) ast.TypeExpr {
+	if expr.Type.Elem != nil {
+		_, elemIsAny := expr.Type.Elem.(*ast.TraitType)
+		for i, item := range expr.Items {
+			itemType := c.Infer(item)
+			if !types.Match(itemType, expr.Type.Elem) {
+				c.addError(fmt.Errorf("can not use '%s' in a slice of '%s'", types.Name(itemType), types.Name(expr.Type.Elem)))
+				continue
+			}
+			if elemIsAny || !types.IsTrait(itemType) {
+				continue
+			}
+
+			if _, isAny := itemType.(*ast.TraitType); isAny && len(expr.Items) == 1 {
+				expr.Value = &ast.Convert{Tok: expr.Tok, Type: expr.Type, Value: item}
+				continue
+			}
+			expr.Items[i] = &ast.Convert{Tok: expr.Tok, Type: expr.Type.Elem, Value: item}
+		}
+
+		return expr.Type
+	}
+
+	var elem ast.TypeExpr
+	for _, item := range expr.Items {
+		itemType := c.Infer(item)
+		if elem == nil {
+			elem = itemType
+			continue
+		}
+
+		unified, ok := types.Unify(elem, itemType)
+		if !ok {
+			unified = &ast.TraitType{}
+		}
+		elem = unified
+	}
+
+	return &ast.SliceType{Tok: expr.Type.Tok, Elem: elem}
+}
+
+// inferIndex finds the type of an item in a slice, tuple or dict. Tuples must be indexed
+// with an int literal and dicts with a property so the type of the item is known.
+func (c *Checker) inferIndex(expr *ast.Index) ast.TypeExpr {
+	valueType := c.Infer(expr.Value)
+
+	if property, ok := expr.Index.(*ast.Property); ok {
+		switch valueType := valueType.(type) {
+		case *ast.DictType:
+			fieldType, ok := types.Field(valueType, property.Name)
+			if !ok {
+				c.addError(fmt.Errorf("'%s' has no field '.%s'", types.Name(valueType), property.Name))
+				return &ast.NoneType{}
+			}
+			return fieldType
+		case *ast.TraitType:
+			return &ast.TraitType{}
+		default:
+			c.addError(fmt.Errorf("can not index '%s' with a property", types.Name(valueType)))
+			return &ast.NoneType{}
+		}
+	}
+
+	indexType := c.Infer(expr.Index)
+	if !types.Match(indexType, &ast.IntType{}) {
+		c.addError(fmt.Errorf("index must be an 'int' but got '%s'", types.Name(indexType)))
+		return &ast.NoneType{}
+	}
+
+	switch valueType := valueType.(type) {
+	case *ast.SliceType:
+		return types.Elem(valueType)
+	case *ast.TupleType:
+		index, ok := expr.Index.(*ast.Int)
+		if !ok {
+			c.addError(fmt.Errorf("tuples must be indexed with an int literal"))
+			return &ast.NoneType{}
+		}
+		if index.Value < 0 || index.Value >= int64(len(valueType.Types)) {
+			c.addError(fmt.Errorf("index %d out of range for '%s'", index.Value, types.Name(valueType)))
+			return &ast.NoneType{}
+		}
+		return valueType.Types[index.Value]
+	case *ast.TraitType:
+		return &ast.TraitType{}
+	default:
+		c.addError(fmt.Errorf("can not index '%s'", types.Name(valueType)))
+		return &ast.NoneType{}
+	}
+}
+
 // Infer infers types for all expressions to prepare for type checking
 func (c *Checker) Infer(expr ast.Expr) ast.TypeExpr {
 	switch expr := expr.(type) {
@@ -50,37 +203,104 @@ func (c *Checker) Infer(expr ast.Expr) ast.TypeExpr {
 		return &ast.IntType{}
 	case *ast.Float:
 		return &ast.FloatType{}
-	case *ast.BoolType:
-		return &ast.BoolType{}
 	case *ast.Atom:
 		return &ast.AtomType{}
 	case *ast.String:
+		return &ast.StringType{}
+	case *ast.Interpolation:
+		for _, part := range expr.Parts {
+			partType := c.Infer(part)
+			if !types.IsStringer(partType) {
+				c.addError(fmt.Errorf("can not interpolate '%s' into a string", types.Name(partType)))
+			}
+		}
+
 		return &ast.StringType{}
 	case *ast.Path:
 		return &ast.PathType{}
 	case *ast.Flag:
 		return &ast.FlagType{}
+	case *ast.Duration:
+		return &ast.DurationType{}
+	case *ast.Size:
+		return &ast.SizeType{}
+	case *ast.Regex:
+		if _, err := builtin.CompileRegex(expr.Value); err != nil {
+			c.addError(err)
+		}
+		return &ast.RegexType{}
 	case *ast.Bool:
 		return &ast.BoolType{}
-	case *ast.Command:
-		return &ast.CommandType{}
 	case *ast.Nil:
 		return &ast.NoneType{}
-	case *ast.Func:
-		c.openScope()
-		bodyType := c.Infer(expr.Body)
-		if !types.Match(bodyType, expr.Type.Return) {
-			c.addError(fmt.Errorf("body type does not expected function return type '%v'", bodyType))
+	case *ast.Tuple:
+		tupleType := &ast.TupleType{}
+		for _, item := range expr.Items {
+			tupleType.Types = append(tupleType.Types, c.Infer(item))
+		}
+
+		return tupleType
+	case *ast.Slice:
+		return c.inferSlice(expr)
+	case *ast.Index:
+		return c.inferIndex(expr)
+	case *ast.Dict:
+		dictType := &ast.DictType{}
+		for _, field := range expr.Fields {
+			dictType.Fields = append(dictType.Fields, ast.FieldType{
+				Name: field.Property.Name,
+				Type: c.Infer(field.Value),
+			})
+		}
+
+		return dictType
+	case *ast.Property:
+		c.addError(fmt.Errorf("property '.%s' can only be used as a dict key", expr.Name))
+		return &ast.NoneType{}
+	case *ast.Convert:
+		valueType := c.Infer(expr.Value)
+		if !types.Convertible(valueType, expr.Type) {
+			c.addError(fmt.Errorf("can not convert '%s' to '%s'", types.Name(valueType), types.Name(expr.Type)))
 		}
-		c.closeScope()
 
 		return expr.Type
+	case *ast.And:
+		return c.inferLogic("and", expr.Exprs)
+	case *ast.Or:
+		return c.inferLogic("or", expr.Exprs)
+	case *ast.Command:
+		for _, arg := range expr.Args {
+			_ = c.Infer(arg)
+		}
+
+		if expr.Stdin != nil {
+			stdinType := c.Infer(expr.Stdin)
+			if !types.Match(stdinType, &ast.StringType{}) && !types.Match(stdinType, &ast.PathType{}) {
+				c.addError(fmt.Errorf("stdin must be a 'str' or 'path' but got '%s'", types.Name(stdinType)))
+			}
+		}
+
+		// commands evaluate to their combined output
+		return &ast.StringType{}
+	case *ast.Builtin:
+		if expr.Type == nil {
+			c.addError(fmt.Errorf("builtin function is missing a type"))
+			return &ast.NoneType{}
+		}
+
+		return expr.Type
+	case *ast.Func:
+		return c.inferFunc(expr)
 	case *ast.Let:
-		// TODO: handle scope
-		c.openScope()
-		exprType := c.Infer(expr.Value)
-		c.closeScope()
+		// bind functions before their body is checked so they can call themselves
+		if fn, ok := expr.Value.(*ast.Func); ok {
+			err := c.table.AddLet(expr, fn.Type)
+			if err != nil {
+				c.addError(err)
+			}
+		}
 
+		exprType := c.Infer(expr.Value)
 		err := c.table.AddLet(expr, exprType)
 		if err != nil {
 			c.addError(err)
@@ -88,19 +308,320 @@ func (c *Checker) Infer(expr ast.Expr) ast.TypeExpr {
 
 		// let expressions return a none value
 		return &ast.NoneType{}
+	case *ast.Impl:
+		// add the overload before the body is checked so it can call itself
+		err := c.table.AddImpl(expr)
+		if err != nil {
+			c.addError(err)
+		}
+
+		_ = c.inferFunc(expr.Func)
+
+		// impl expressions return a none value
+		return &ast.NoneType{}
+	case *ast.Namespace:
+		return c.inferNamespace(expr)
+	case *ast.Import:
+		return c.inferImport(expr)
+	case *ast.Try:
+		return c.inferTry(expr)
+	case *ast.Macro:
+		// macros are expanded by the normalizer so only the definition is left
+		return &ast.NoneType{}
+	case *ast.If:
+		return c.inferIf(expr)
+	case *ast.Match:
+		return c.inferMatch(expr)
+	case *ast.Do:
+		c.openScope()
+		defer c.closeScope()
+
+		// functions are bound before any of the expressions are checked so they can call each other
+		for _, expr := range expr.Exprs {
+			if let, ok := expr.(*ast.Let); ok {
+				if fn, ok := let.Value.(*ast.Func); ok {
+					if err := c.table.AddLet(let, fn.Type); err != nil {
+						c.addError(err)
+					}
+				}
+			}
+		}
+
+		var doType ast.TypeExpr = &ast.NoneType{}
+		for _, expr := range expr.Exprs {
+			doType = c.Infer(expr)
+		}
+
+		return doType
 	case *ast.Identifier:
 		identEntry, ok := c.table.LookupValue(expr.Name)
-		if !ok {
+		if ok {
+			return identEntry.Type
+		}
+
+		entry, ok := c.table.Lookup(expr.Name)
+		_, isNamespace := entry.(*symbol.NamespaceEntry)
+		switch {
+		case symbol.IsPrivate(expr.Name):
+			c.addError(fmt.Errorf("'%s' is private to its namespace", expr.Name))
+		case !ok:
 			c.addError(fmt.Errorf("identifier '%s' has not been defined", expr.Name))
-			return &ast.NoneType{}
+		case isNamespace:
+			c.addError(fmt.Errorf("namespace '%s' can not be used as a value", expr.Name))
+		default:
+			c.addError(fmt.Errorf("'%s' is overloaded and can only be used as a function call", expr.Name))
 		}
 
-		return identEntry.Type
+		return &ast.NoneType{}
 	case *ast.Call:
 		return c.inferCall(expr)
+	case ast.TypeExpr:
+		c.addError(fmt.Errorf("type '%s' can not be used as a value", types.Name(expr)))
+		return &ast.NoneType{}
 	default:
-		panic(fmt.Sprintf("failed to infer type '%v'", expr))
+		c.addError(fmt.Errorf("failed to infer type of expression '%T'", expr))
+		return &ast.NoneType{}
+	}
+}
+
+// inferScoped infers the type of an expression inside of a new scope
+func (c *Checker) inferScoped(expr ast.Expr) ast.TypeExpr {
+	c.openScope()
+	defer c.closeScope()
+
+	return c.Infer(expr)
+}
+
+func (c *Checker) inferFunc(fn *ast.Func) ast.TypeExpr {
+	c.openScope()
+	defer c.closeScope()
+
+	for _, param := range fn.Type.Params.Params {
+		c.table.AddParam(param)
 	}
+
+	bodyType := c.Infer(fn.Body)
+	if !types.Match(bodyType, fn.Type.Return) {
+		c.addError(fmt.Errorf(
+			"function body has type '%s' but the function returns '%s'",
+			types.Name(bodyType),
+			types.Name(fn.Type.Return),
+		))
+	}
+
+	// functions without a return type return whatever their body evaluates to
+	if _, ok := fn.Type.Return.(*ast.TraitType); ok {
+		fn.Type.Return = bodyType
+	}
+
+	return fn.Type
+}
+
+// inferNamespace checks the expressions of a namespace inside of the namespaces table.
+// Declaring a namespace that already exists adds to the existing namespace.
+func (c *Checker) inferNamespace(expr *ast.Namespace) ast.TypeExpr {
+	name := expr.Identifier.Name
+	namespace, ok := c.table.LookupNamespace(name)
+	switch {
+	case !ok:
+		namespace = c.table.OpenScope()
+		err := c.table.AddNamespace(name, namespace)
+		if err != nil {
+			c.addError(err)
+			return &ast.NoneType{}
+		}
+	case c.transaction != nil && !slices.Contains(c.transaction, namespace):
+		// changes to an existing namespace need to be undone if the transaction is rolled back
+		namespace.Begin()
+		c.transaction = append(c.transaction, namespace)
+	}
+
+	prev := c.table
+	c.table = namespace
+	defer func() { c.table = prev }()
+
+	for _, expr := range expr.Exprs {
+		_ = c.Infer(expr)
+	}
+
+	// namespace expressions return a none value
+	return &ast.NoneType{}
+}
+
+// inferImport checks the expressions of an imported file inside of a new namespace.
+// Errors are reported with the path of the file and the line of the expression they were found in.
+// If the same file has already been imported the namespace is kept and the file is not checked again.
+func (c *Checker) inferImport(expr *ast.Import) ast.TypeExpr {
+	module := expr.Module
+	if module == nil {
+		c.addError(fmt.Errorf("import '%s' has not been loaded", expr.Path.Value))
+		return &ast.NoneType{}
+	}
+
+	name := expr.Identifier.Name
+	if entry, ok := c.table.LookupModule(name); ok && entry.Hash == module.Hash {
+		return &ast.NoneType{}
+	}
+
+	namespace := c.table.OpenScope()
+	err := c.table.AddModule(name, module.Hash, namespace)
+	if err != nil {
+		c.addError(err)
+		return &ast.NoneType{}
+	}
+
+	prev := c.table
+	c.table = namespace
+	defer func() { c.table = prev }()
+
+	for i, expr := range module.Exprs {
+		errs := len(c.Errors)
+		_ = c.Infer(expr)
+		for j := errs; j < len(c.Errors); j++ {
+			c.Errors[j] = fmt.Errorf("%s:%d: %w", module.Path, module.Lines[i], c.Errors[j])
+		}
+	}
+
+	// import expressions return a none value
+	return &ast.NoneType{}
+}
+
+// inferTry checks a try expression. A handler must take a single error and return the same type
+// as the body. Without a handler the try evaluates to either the body or an error.
+func (c *Checker) inferTry(expr *ast.Try) ast.TypeExpr {
+	bodyType := c.inferScoped(expr.Body)
+
+	if expr.Handler == nil {
+		// TODO: this should be a union of the body type and error once union types are supported
+		return &ast.TraitType{}
+	}
+
+	handlerType := c.Infer(expr.Handler)
+	if types.IsTrait(handlerType) {
+		return handlerType
+	}
+
+	funcType, ok := handlerType.(*ast.FuncType)
+	if !ok {
+		c.addError(fmt.Errorf("try handler must be a function but got '%s'", types.Name(handlerType)))
+		return bodyType
+	}
+
+	params := funcType.Params.Params
+	if len(params) != 1 || !types.Match(&ast.ErrorType{}, params[0].Type) {
+		c.addError(fmt.Errorf("try handler must take a single error but got '%s'", types.Name(funcType)))
+		return bodyType
+	}
+
+	tryType, ok := types.Unify(bodyType, funcType.Return)
+	if !ok {
+		c.addError(fmt.Errorf("try handler returns '%s' but the body returns '%s'", types.Name(funcType.Return), types.Name(bodyType)))
+		return bodyType
+	}
+
+	return tryType
+}
+
+// inferLogic checks that every operand of an and/or expression is a bool
+func (c *Checker) inferLogic(name string, exprs []ast.Expr) ast.TypeExpr {
+	for _, expr := range exprs {
+		exprType := c.Infer(expr)
+		if !types.Match(exprType, &ast.BoolType{}) {
+			c.addError(fmt.Errorf("%s operands must be bools but got '%s'", name, types.Name(exprType)))
+		}
+	}
+
+	return &ast.BoolType{}
+}
+
+func (c *Checker) inferIf(expr *ast.If) ast.TypeExpr {
+	condType := c.Infer(expr.Cond)
+	if !types.Match(condType, &ast.BoolType{}) {
+		c.addError(fmt.Errorf("if condition must be a bool but got '%s'", types.Name(condType)))
+	}
+
+	thenType := c.inferScoped(expr.Then)
+
+	var elseType ast.TypeExpr = &ast.NoneType{}
+	if expr.Else != nil {
+		elseType = c.inferScoped(expr.Else)
+	}
+
+	ifType, ok := types.Unify(thenType, elseType)
+	if !ok && expr.Else == nil {
+		c.addError(fmt.Errorf("if expression without an else branch must evaluate to none but got '%s'", types.Name(thenType)))
+		return &ast.NoneType{}
+	}
+	if !ok {
+		c.addError(fmt.Errorf(
+			"if branches must have the same type but got '%s' and '%s'",
+			types.Name(thenType),
+			types.Name(elseType),
+		))
+		return &ast.NoneType{}
+	}
+
+	return ifType
+}
+
+func (c *Checker) inferMatch(expr *ast.Match) ast.TypeExpr {
+	valueType := c.Infer(expr.Value)
+
+	var matchType ast.TypeExpr
+	unify := func(caseType ast.TypeExpr) {
+		if matchType == nil {
+			matchType = caseType
+			return
+		}
+
+		unified, ok := types.Unify(matchType, caseType)
+		if !ok {
+			c.addError(fmt.Errorf(
+				"match cases must have the same type but got '%s' and '%s'",
+				types.Name(matchType),
+				types.Name(caseType),
+			))
+			return
+		}
+
+		matchType = unified
+	}
+
+	for _, matchCase := range expr.Cases {
+		switch matchCase.Pattern.(type) {
+		case *ast.Int, *ast.Float, *ast.Bool, *ast.String, *ast.Atom, *ast.Path, *ast.Flag, *ast.Duration, *ast.Size, *ast.Nil:
+		default:
+			c.addError(fmt.Errorf("match patterns must be literal values but got '%T'", matchCase.Pattern))
+			continue
+		}
+
+		patternType := c.Infer(matchCase.Pattern)
+		if !types.Match(valueType, patternType) {
+			c.addError(fmt.Errorf(
+				"match pattern has type '%s' but the value has type '%s'",
+				types.Name(patternType),
+				types.Name(valueType),
+			))
+		}
+
+		unify(c.inferScoped(matchCase.Body))
+	}
+
+	if expr.Else == nil {
+		if matchType == nil {
+			return &ast.NoneType{}
+		}
+
+		if _, ok := types.Unify(matchType, &ast.NoneType{}); !ok {
+			c.addError(fmt.Errorf("match expression without an else case must evaluate to none but got '%s'", types.Name(matchType)))
+			return &ast.NoneType{}
+		}
+
+		return matchType
+	}
+
+	unify(c.inferScoped(expr.Else))
+	return matchType
 }
 
 func (c *Checker) inferCall(call *ast.Call) ast.TypeExpr {
@@ -113,109 +634,180 @@ func (c *Checker) inferCall(call *ast.Call) ast.TypeExpr {
 		argTypes = append(argTypes, argType)
 	}
 
-	switch expr := call.Func.(type) {
-	case *ast.Func:
-		typeExpr := c.Infer(expr)
+	expr, ok := call.Func.(*ast.Identifier)
+	if !ok {
+		return c.checkFuncCall(c.Infer(call.Func), argTypes)
+	}
+
+	entry, ok := c.table.Lookup(expr.Name)
+	if !ok && symbol.IsPrivate(expr.Name) {
+		c.addError(fmt.Errorf("'%s' is private to its namespace", expr.Name))
+		return &ast.NoneType{}
+	}
+	if !ok {
+		c.addError(fmt.Errorf("unknown identifier '%v'", expr.Name))
+		return &ast.NoneType{}
+	}
 
-		funcType := typeExpr.(*ast.FuncType)
+	switch entry := entry.(type) {
+	case *symbol.ValueEntry:
+		return c.checkFuncCall(entry.Type, argTypes)
+	case *symbol.ImplEntry:
+		return c.checkOverloadedCall(call, entry.Name, entry.Candidates(), argTypes)
+	case *symbol.BuiltinEntry:
+		return c.checkOverloadedCall(call, entry.Name, entry.Candidates(), argTypes)
+	default:
+		c.addError(fmt.Errorf("invalid symbol table entry for '%s'", expr.Name))
+		return &ast.NoneType{}
+	}
+}
 
-		if len(argTypes) != len(funcType.Params.Params) {
-			c.addError(fmt.Errorf("arities do not match"))
+// checkOverloadedCall selects the overload that is the most specific match for the
+// arguments and swaps it in as the operator of the call
+func (c *Checker) checkOverloadedCall(call *ast.Call, name string, overloads []ast.Overload, args []ast.TypeExpr) ast.TypeExpr {
+	funcTypes := []*ast.FuncType{}
+	for _, overload := range overloads {
+		funcTypes = append(funcTypes, overload.Type)
+	}
+
+	argTypes := []string{}
+	for _, arg := range args {
+		argTypes = append(argTypes, types.Name(arg))
+	}
+	signature := strings.TrimSpace(fmt.Sprintf("'%s' %s", name, strings.Join(argTypes, " ")))
+
+	matches := types.Resolve(args, funcTypes)
+	unknownArgs := slices.ContainsFunc(args, types.IsTrait)
+
+	// ints are promoted to floats if there is no overload for the original arguments
+	promoted, canPromote := types.Promote(args)
+	switch {
+	case canPromote && unknownArgs:
+		// the promoted overloads are still candidates since the runtime types may need promotion
+		for _, i := range types.Resolve(promoted, funcTypes) {
+			if !slices.Contains(matches, i) {
+				matches = append(matches, i)
+			}
 		}
+	case canPromote && len(matches) == 0:
+		matches = types.Resolve(promoted, funcTypes)
+	case canPromote && !types.Binds(args, funcTypes, matches):
+		// a generic overload like (T T) matched but the arguments have different types, e.g. (== 1 2.5)
+		promotedMatches := types.Resolve(promoted, funcTypes)
+		if types.Binds(promoted, funcTypes, promotedMatches) {
+			matches = promotedMatches
+		}
+	}
 
-		return expr.Type.Return
-	case *ast.Identifier:
-		entry, ok := c.table.Lookup(expr.Name)
-		if !ok {
-			c.addError(fmt.Errorf("unknown identifier '%v'", expr.Name))
+	switch {
+	case len(matches) == 0:
+		c.addError(fmt.Errorf(
+			"could not find a matching overload for (%s)\ncandidates are:\n%s",
+			signature,
+			listSignatures(funcTypes),
+		))
+		return &ast.NoneType{}
+	case len(matches) == 1:
+		selected, funcType := overloads[matches[0]], funcTypes[matches[0]]
+		if unknownArgs {
+			// the overload still has to be checked against the runtime types of the arguments
+			// so a wrong type raises an error instead of reaching the builtin
+			call.Func = &ast.Dispatch{Name: name, Overloads: []ast.Overload{selected}}
+			if types.IsGeneric(funcType) {
+				if bindings, err := types.Bind(funcType, args); err == nil {
+					return types.Substitute(funcType.Return, bindings)
+				}
+				return types.Substitute(funcType.Return, nil)
+			}
+			return funcType.Return
 		}
 
-		switch entry := entry.(type) {
-		case *symbol.ValueEntry:
-			return c.checkFuncCall(entry.Type, argTypes)
-		case *symbol.ImplEntry:
-			impl, ok := c.checkImplCall(entry, argTypes)
-			if !ok {
+		if types.IsGeneric(funcType) {
+			bindings, err := types.Bind(funcType, args)
+			if err != nil {
+				c.addError(fmt.Errorf("invalid arguments for (%s): %w", signature, err))
 				return &ast.NoneType{}
 			}
 
-			// swap the operator out for the actual function
-			call.Func = impl.Decl.Func
-
-			return impl.Type.Return
-		case *symbol.BuiltinEntry:
-			builtin, ok := c.checkBuiltinCall(entry, argTypes)
-			if !ok {
-				return &ast.NoneType{}
+			// the builtin gets the bound signature so the vm knows the types of the values it returns
+			funcType = types.Substitute(funcType, bindings).(*ast.FuncType)
+			if builtin, ok := selected.Func.(*ast.Builtin); ok {
+				selected.Func = &ast.Builtin{Type: funcType, Fn: builtin.Fn}
 			}
+		}
 
-			// swap the operator out for the correct builtin
-			call.Func = builtin.Decl
+		// swap the operator out for the selected overload
+		call.Func = selected.Func
+		promoteArgs(call, args, funcType)
+		return funcType.Return
+	}
 
-			return builtin.Type.Return
-		default:
-			panic("invalid symbole table entry")
-		}
+	candidates := &ast.Dispatch{Name: name}
+	candidateTypes := []*ast.FuncType{}
+	for _, i := range matches {
+		candidates.Overloads = append(candidates.Overloads, overloads[i])
+		candidateTypes = append(candidateTypes, funcTypes[i])
+	}
 
-	default:
-		c.addError(fmt.Errorf("can not call value with type '%v'", expr))
+	if !unknownArgs {
+		c.addError(fmt.Errorf(
+			"ambiguous call (%s) matches more than one overload:\n%s",
+			signature,
+			listSignatures(candidateTypes),
+		))
 		return &ast.NoneType{}
 	}
-}
 
-func (c *Checker) checkBuiltinCall(builtin *symbol.BuiltinEntry, args []ast.TypeExpr) (*symbol.BuiltinOverload, bool) {
-	overload, ok := builtin.Match(args)
-	if !ok {
-		argTypes := []string{}
-		for i := range args {
-			switch args[i].(type) {
-			case *ast.NoneType:
-				argTypes = append(argTypes, "none")
-			case *ast.IntType:
-				argTypes = append(argTypes, "int")
-			case *ast.FloatType:
-				argTypes = append(argTypes, "float")
-			case *ast.BoolType:
-				argTypes = append(argTypes, "bool")
-			case *ast.FlagType:
-				argTypes = append(argTypes, "flag")
-			case *ast.CommandType:
-				argTypes = append(argTypes, "command")
-			case *ast.PathType:
-				argTypes = append(argTypes, "path")
-			case *ast.FuncType:
-				argTypes = append(argTypes, "func")
-			case *ast.TraitType:
-				// TODO: this should be more specific than just an any
-				argTypes = append(argTypes, "any")
-			}
+	// the argument types are not known until runtime so the overload is selected then
+	call.Func = candidates
+
+	returnType := types.Substitute(candidateTypes[0].Return, nil)
+	for _, candidate := range candidateTypes[1:] {
+		unified, ok := types.Unify(returnType, types.Substitute(candidate.Return, nil))
+		switch {
+		case !ok && types.IsNumber(returnType) && types.IsNumber(candidate.Return):
+			returnType = &ast.NumberType{}
+		case !ok:
+			return &ast.TraitType{}
+		default:
+			returnType = unified
 		}
-		c.addError(fmt.Errorf("could not find a matching overload for ('%s' %s)", builtin.Name, strings.Join(argTypes, " ")))
-		return nil, false
 	}
 
-	return overload, true
+	return returnType
 }
 
-func (c *Checker) checkImplCall(impl *symbol.ImplEntry, args []ast.TypeExpr) (*symbol.ImplOverload, bool) {
-	overload, ok := impl.Match(args)
-	if !ok {
-		c.addError(fmt.Errorf("could not find a matching overload"))
-		return nil, false
+// promoteArgs wraps the int and number arguments of a call in a conversion to float
+// where the selected overload takes a float
+func promoteArgs(call *ast.Call, args []ast.TypeExpr, funcType *ast.FuncType) {
+	for i, arg := range args {
+		_, isFloat := arg.(*ast.FloatType)
+		_, wantFloat := types.ParamType(funcType, i).(*ast.FloatType)
+		if types.IsNumber(arg) && !isFloat && wantFloat {
+			call.Args[i] = &ast.Convert{Tok: call.Tok, Type: &ast.FloatType{}, Value: call.Args[i]}
+		}
+	}
+}
+
+func listSignatures(funcTypes []*ast.FuncType) string {
+	signatures := []string{}
+	for _, funcType := range funcTypes {
+		signatures = append(signatures, "  "+types.Name(funcType))
 	}
 
-	return overload, true
+	return strings.Join(signatures, "\n")
 }
 
 func (c *Checker) checkFuncCall(typeExpr ast.TypeExpr, args []ast.TypeExpr) ast.TypeExpr {
-	funcType, ok := typeExpr.(*ast.FuncType)
-	if !ok {
-		c.addError(fmt.Errorf("call type is not a function type %v", funcType))
+	// values with an unknown type may still be functions so they are checked at runtime
+	if _, ok := typeExpr.(*ast.TraitType); ok {
+		return &ast.TraitType{}
 	}
 
-	if funcType == nil {
-		c.addError(fmt.Errorf("invalid function call"))
-		return nil
+	funcType, ok := typeExpr.(*ast.FuncType)
+	if !ok || funcType == nil {
+		c.addError(fmt.Errorf("can not call value with type '%s'", types.Name(typeExpr)))
+		return &ast.NoneType{}
 	}
 
 	if funcType.Params == nil && len(args) != 0 {
@@ -224,14 +816,14 @@ func (c *Checker) checkFuncCall(typeExpr ast.TypeExpr, args []ast.TypeExpr) ast.
 	}
 
 	if len(args) != len(funcType.Params.Params) {
-		c.addError(fmt.Errorf("arities do not match"))
+		c.addError(fmt.Errorf("expected %d arguments but got %d", len(funcType.Params.Params), len(args)))
 		return funcType.Return
 	}
 
 	for i, arg := range args {
 		wantType := funcType.Params.Params[i].Type
 		if !types.Match(arg, wantType) {
-			c.addError(fmt.Errorf("argument type is incorrect got '%v' but wanted '%v'", arg, wantType))
+			c.addError(fmt.Errorf("argument type is incorrect got '%s' but wanted '%s'", types.Name(arg), types.Name(wantType)))
 		}
 	}
 
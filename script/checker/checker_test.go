@@ -0,0 +1,341 @@
+package checker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/builtin"
+	"github.com/bjatkin/nook/script/internal/scripttest"
+	"github.com/bjatkin/nook/script/token"
+)
+
+func TestChecker_Infer(t *testing.T) {
+	type args struct {
+		code string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    ast.TypeExpr
+		wantErr bool
+	}{
+		{
+			name: "if branches unify",
+			args: args{code: `(if true 1 2)`},
+			want: &ast.IntType{},
+		},
+		{
+			name:    "if branches do not unify",
+			args:    args{code: `(if true 1 "two")`},
+			want:    &ast.NoneType{},
+			wantErr: true,
+		},
+		{
+			name:    "if condition is not a bool",
+			args:    args{code: `(if 1 2 3)`},
+			want:    &ast.IntType{},
+			wantErr: true,
+		},
+		{
+			name: "if without else",
+			args: args{code: `(if false (cd ./))`},
+			want: &ast.NoneType{},
+		},
+		{
+			name: "match with else",
+			args: args{code: `(match 'en ('en "English") ('de "German") (else "Unknown"))`},
+			want: &ast.StringType{},
+		},
+		{
+			name:    "match pattern does not match value",
+			args:    args{code: `(match 'en (1 "one") (else "Unknown"))`},
+			want:    &ast.StringType{},
+			wantErr: true,
+		},
+		{
+			name: "do evaluates to the final expression",
+			args: args{code: `(do (let a 1) (+ a 2))`},
+			want: &ast.IntType{},
+		},
+		{
+			name: "impl declaration",
+			args: args{code: `(impl add (fn [a b int] (+ a b)))`},
+			want: &ast.NoneType{},
+		},
+		{
+			name: "function call",
+			args: args{code: `((fn [a int] (+ a 1)) 2)`},
+			want: &ast.IntType{},
+		},
+		{
+			name: "command",
+			args: args{code: `($echo 'hello)`},
+			want: &ast.StringType{},
+		},
+		{
+			name:    "unknown function",
+			args:    args{code: `(missing 1 2)`},
+			want:    &ast.NoneType{},
+			wantErr: true,
+		},
+		{
+			name: "try with a handler",
+			args: args{code: `(try (+ 1 2) (fn [e error] 0))`},
+			want: &ast.IntType{},
+		},
+		{
+			name:    "try handler returns a different type",
+			args:    args{code: `(try (+ 1 2) (fn [e error] "zero"))`},
+			want:    &ast.IntType{},
+			wantErr: true,
+		},
+		{
+			name:    "try handler does not take an error",
+			args:    args{code: `(try (+ 1 2) (fn [e int] e))`},
+			want:    &ast.IntType{},
+			wantErr: true,
+		},
+		{
+			name: "fail creates an error",
+			args: args{code: `(fail "bad")`},
+			want: &ast.ErrorType{},
+		},
+		{
+			name: "regex literal",
+			args: args{code: `(regex.match "v1.2" #/v\d+/)`},
+			want: &ast.BoolType{},
+		},
+		{
+			name:    "invalid regex literal",
+			args:    args{code: `#/a(b/`},
+			want:    &ast.RegexType{},
+			wantErr: true,
+		},
+		{
+			name:    "type used as a value",
+			args:    args{code: `(+ int 1)`},
+			want:    &ast.NoneType{},
+			wantErr: true,
+		},
+		{
+			name: "interpolation",
+			args: args{code: `"${1} ${(+ 1.5 2)} ${./file.txt} ${5s}"`},
+			want: &ast.StringType{},
+		},
+		{
+			name:    "interpolation of a slice",
+			args:    args{code: `"items: ${{[_] 1 2}}"`},
+			want:    &ast.StringType{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			got := c.Infer(scripttest.Normalize(t, tt.args.code))
+			if (len(c.Errors) > 0) != tt.wantErr {
+				t.Errorf("Checker.Infer() errors %v, wantErr %v", c.Errors, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Checker.Infer() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_Infer_overloads(t *testing.T) {
+	type args struct {
+		builtins []builtin.Builtin
+		setup    []string
+		code     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    ast.TypeExpr
+		wantErr string
+	}{
+		{
+			name: "most specific overload",
+			args: args{
+				setup: []string{
+					`(impl add (fn [a b] "any"))`,
+					`(impl add (fn [a b int] (+ a b)))`,
+				},
+				code: `(add 1 2)`,
+			},
+			want: &ast.IntType{},
+		},
+		{
+			name: "ambiguous overloads",
+			args: args{
+				builtins: []builtin.Builtin{
+					{
+						Name: "pick",
+						Type: &ast.FuncType{
+							Params: &ast.ParamList{Params: []ast.Param{{Type: &ast.IntType{}}, {Type: &ast.TraitType{}}}},
+							Return: &ast.IntType{},
+						},
+					},
+					{
+						Name: "pick",
+						Type: &ast.FuncType{
+							Params: &ast.ParamList{Params: []ast.Param{{Type: &ast.TraitType{}}, {Type: &ast.IntType{}}}},
+							Return: &ast.IntType{},
+						},
+					},
+				},
+				code: `(pick 1 2)`,
+			},
+			want: &ast.NoneType{},
+			wantErr: "ambiguous call ('pick' int int) matches more than one overload:\n" +
+				"  <fn [int any] int>\n" +
+				"  <fn [any int] int>",
+		},
+		{
+			name: "no matching overload lists candidates",
+			args: args{
+				setup: []string{
+					`(impl add (fn [a b int] (+ a b)))`,
+					`(impl add (fn [a b float] (+ a b)))`,
+				},
+				code: `(add 1 "two")`,
+			},
+			want: &ast.NoneType{},
+			wantErr: "could not find a matching overload for ('add' int str)\ncandidates are:\n" +
+				"  <fn [int int] int>\n" +
+				"  <fn [float float] float>",
+		},
+		{
+			name: "duplicate signature",
+			args: args{
+				setup: []string{`(impl add (fn [a b int] (+ a b)))`},
+				code:  `(impl add (fn [a b int] (- a b)))`,
+			},
+			want:    &ast.NoneType{},
+			wantErr: "impl 'add' already has an overload with the signature <fn [int int] int>",
+		},
+		{
+			// the arguments could be numbers, durations, times or sizes so the return type is not known
+			name: "unknown arguments are dispatched at runtime",
+			args: args{code: `(fn [a b] (+ a b))`},
+			want: &ast.FuncType{
+				Params: &ast.ParamList{},
+				Return: &ast.TraitType{},
+			},
+		},
+		{
+			name: "unknown arguments added to an int are numbers",
+			args: args{code: `(fn [a b] (+ a b 1))`},
+			want: &ast.FuncType{
+				Params: &ast.ParamList{},
+				Return: &ast.NumberType{},
+			},
+		},
+		{
+			name: "mixed ints and floats use the float overload",
+			args: args{code: `(+ 1 2.5)`},
+			want: &ast.FloatType{},
+		},
+		{
+			name: "explicit conversion",
+			args: args{code: `{float 1}`},
+			want: &ast.FloatType{Tok: token.Token{Pos: 1, Value: "float", Kind: token.FloatType}},
+		},
+		{
+			name:    "invalid conversion",
+			args:    args{code: `{int 1.5}`},
+			want:    &ast.IntType{Tok: token.Token{Pos: 1, Value: "int", Kind: token.IntType}},
+			wantErr: "can not convert 'float' to 'int'",
+		},
+		{
+			name: "number params accept any number",
+			args: args{code: `(math.float 1)`},
+			want: &ast.FloatType{},
+		},
+		{
+			name: "slice element types are infered",
+			args: args{code: `{[_] 1 2}`},
+			want: &ast.SliceType{Tok: token.Token{Pos: 1, Value: "[", Kind: token.OpenSquare}, Elem: &ast.IntType{}},
+		},
+		{
+			name: "mixed slices hold any value",
+			args: args{code: `{[_] 1 "two"}`},
+			want: &ast.SliceType{Tok: token.Token{Pos: 1, Value: "[", Kind: token.OpenSquare}, Elem: &ast.TraitType{}},
+		},
+		{
+			name:    "tuples are indexed with literals",
+			args:    args{setup: []string{`(let i 0)`}, code: `[{1 "a"} i]`},
+			want:    &ast.NoneType{},
+			wantErr: "tuples must be indexed with an int literal",
+		},
+		{
+			name: "tuple index",
+			args: args{code: `[{1 "a"} 1]`},
+			want: &ast.StringType{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			for _, builtin := range tt.args.builtins {
+				if err := c.Table().AddBuiltin(builtin); err != nil {
+					t.Fatalf("failed to add builtin '%s': %v", builtin.Name, err)
+				}
+			}
+			for _, code := range tt.args.setup {
+				_ = c.Infer(scripttest.Normalize(t, code))
+				if len(c.Errors) > 0 {
+					t.Fatalf("failed to check '%s': %v", code, c.Errors)
+				}
+			}
+
+			got := c.Infer(scripttest.Normalize(t, tt.args.code))
+
+			gotErr := ""
+			if len(c.Errors) > 0 {
+				gotErr = c.Errors[0].Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("Checker.Infer() error = %q, want %q", gotErr, tt.wantErr)
+			}
+
+			// only the return type of functions is compared since params include identifiers
+			if funcType, ok := got.(*ast.FuncType); ok {
+				got = &ast.FuncType{Params: &ast.ParamList{}, Return: funcType.Return}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Checker.Infer() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_Infer_unknownNode(t *testing.T) {
+	c := NewChecker()
+	got := c.Infer(&ast.SExpr{Operator: &ast.SCurly{}})
+	if len(c.Errors) != 1 {
+		t.Errorf("Checker.Infer() errors %v, want 1 error", c.Errors)
+	}
+
+	if !reflect.DeepEqual(got, &ast.NoneType{}) {
+		t.Errorf("Checker.Infer() = %#v, want none", got)
+	}
+}
+
+func TestChecker_Rollback_scope(t *testing.T) {
+	c := NewChecker()
+	root := c.Table()
+
+	// a panic while checking leaves the checker inside of the scopes it opened
+	c.Begin()
+	c.openScope()
+	c.openScope()
+	c.Rollback()
+
+	if c.Table() != root {
+		t.Errorf("Checker.Rollback() did not return to the top level scope")
+	}
+}
@@ -0,0 +1,29 @@
+// Package scripttest holds helpers shared by the nook script tests.
+package scripttest
+
+import (
+	"testing"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/normalizer"
+	"github.com/bjatkin/nook/script/parser"
+)
+
+// Normalize parses and normalizes code, failing the test if either step reports an error
+func Normalize(t testing.TB, code string) ast.Expr {
+	t.Helper()
+
+	p := parser.NewParser([]byte(code))
+	expr := p.Parse()
+	if len(p.Errors) > 0 {
+		t.Fatalf("failed to parse '%s': %v", code, p.Errors)
+	}
+
+	n := normalizer.Normalizer{}
+	expr = n.Normalize(expr)
+	if len(n.Errors) > 0 {
+		t.Fatalf("failed to normalize '%s': %v", code, n.Errors)
+	}
+
+	return expr
+}
@@ -0,0 +1,275 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/symbol"
+)
+
+// binding is a let or a paramater that has been declared in a scope
+type binding struct {
+	kind string
+	name string
+	used bool
+
+	// forward is true for functions in a do that are declared before their let is reached
+	forward bool
+	// expanded bindings were written in a macro template so they are never reported
+	expanded bool
+}
+
+type scope struct {
+	parent   *scope
+	bindings map[string]*binding
+	order    []*binding
+
+	// persistent scopes are the top level scope and namespaces, their
+	// bindings can be used by later cells
+	persistent bool
+}
+
+// Linter finds code that is valid but likely to be a mistake.
+// Unlike checker errors, warnings do not stop a cell from running.
+//
+// The linter should be run on the normalized ast before the checker since the
+// checker replaces overloaded calls with the selected overload.
+type Linter struct {
+	table *symbol.Table
+	scope *scope
+
+	// Expanded holds the nodes that came from a macro template, see normalizer.Normalizer.Expanded.
+	// Code the caller did not write is still walked so uses are tracked, but it is never reported.
+	Expanded map[ast.Expr]bool
+	Warnings []error
+}
+
+// NewLinter creates a new linter. The table is used to find the builtins that
+// user bindings may shadow.
+func NewLinter(table *symbol.Table) *Linter {
+	return &Linter{
+		table: table,
+		scope: &scope{bindings: map[string]*binding{}, persistent: true},
+	}
+}
+
+func (l *Linter) addWarning(err error) {
+	l.Warnings = append(l.Warnings, err)
+}
+
+func (l *Linter) openScope() {
+	l.scope = &scope{
+		parent:   l.scope,
+		bindings: map[string]*binding{},
+	}
+}
+
+func (l *Linter) closeScope() {
+	for _, binding := range l.scope.order {
+		// bindings that were replaced have already been checked
+		if l.scope.bindings[binding.name] != binding {
+			continue
+		}
+
+		l.checkUsed(binding)
+	}
+
+	l.scope = l.scope.parent
+}
+
+// checkUsed warns if a binding has never been read. Bindings in the top level scope and
+// in namespaces are not checked since they can be used by later cells, and names starting
+// with '_' are ignored so unused paramaters can be marked explicitly.
+func (l *Linter) checkUsed(binding *binding) {
+	if l.scope.persistent {
+		return
+	}
+	if binding.used || binding.expanded || strings.HasPrefix(binding.name, "_") {
+		return
+	}
+
+	l.addWarning(fmt.Errorf("%s '%s' is declared but never used", binding.kind, binding.name))
+}
+
+// declare adds a binding to the current scope, expanded is true if the binding was written in a macro template
+func (l *Linter) declare(kind string, identifier *ast.Identifier, expanded bool) {
+	name := identifier.Name
+	if prev, ok := l.scope.bindings[name]; ok && prev.forward {
+		prev.forward = false
+		return
+	}
+
+	if _, ok := l.table.LookupBuiltin(name); ok && !expanded {
+		l.addWarning(fmt.Errorf("%s '%s' shadows the builtin '%s'", kind, name, name))
+	}
+
+	if prev, ok := l.scope.bindings[name]; ok {
+		l.checkUsed(prev)
+	}
+
+	entry := &binding{kind: kind, name: name, expanded: expanded}
+	l.scope.bindings[name] = entry
+	l.scope.order = append(l.scope.order, entry)
+}
+
+func (l *Linter) use(name string) {
+	for scope := l.scope; scope != nil; scope = scope.parent {
+		if binding, ok := scope.bindings[name]; ok {
+			binding.used = true
+			return
+		}
+	}
+}
+
+// Lint walks the expression and collects warnings
+func (l *Linter) Lint(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		l.use(expr.Name)
+	case *ast.Let:
+		// functions are declared before their body so they can call themselves
+		if _, ok := expr.Value.(*ast.Func); ok {
+			l.declare("let", expr.Identifier, l.Expanded[expr])
+			l.Lint(expr.Value)
+			return
+		}
+
+		l.Lint(expr.Value)
+		l.declare("let", expr.Identifier, l.Expanded[expr])
+	case *ast.Impl:
+		if !l.Expanded[expr] {
+			l.lintImpl(expr)
+		}
+		l.Lint(expr.Func)
+	case *ast.Func:
+		l.lintFunc(expr, false)
+	case *ast.If:
+		if cond, ok := expr.Cond.(*ast.Bool); ok && !l.Expanded[expr] {
+			l.addWarning(fmt.Errorf("if condition is always %t", cond.Value))
+		}
+
+		l.Lint(expr.Cond)
+		l.lintScoped(expr.Then)
+		if expr.Else != nil {
+			l.lintScoped(expr.Else)
+		}
+	case *ast.Match:
+		l.Lint(expr.Value)
+		for _, matchCase := range expr.Cases {
+			l.lintScoped(matchCase.Body)
+		}
+		if expr.Else != nil {
+			l.lintScoped(expr.Else)
+		}
+	case *ast.Namespace:
+		l.openScope()
+		l.scope.persistent = true
+		defer l.closeScope()
+
+		for _, expr := range expr.Exprs {
+			l.Lint(expr)
+		}
+	case *ast.Try:
+		l.lintScoped(expr.Body)
+		if handler, ok := expr.Handler.(*ast.Func); ok {
+			l.lintFunc(handler, true)
+		} else if expr.Handler != nil {
+			l.Lint(expr.Handler)
+		}
+	case *ast.Do:
+		l.openScope()
+		defer l.closeScope()
+
+		// functions in a do can call each other so they are declared before any of the expressions
+		for _, expr := range expr.Exprs {
+			if let, ok := expr.(*ast.Let); ok {
+				if _, ok := let.Value.(*ast.Func); ok {
+					l.declare("let", let.Identifier, l.Expanded[let])
+					l.scope.bindings[let.Identifier.Name].forward = true
+				}
+			}
+		}
+
+		for _, expr := range expr.Exprs {
+			l.Lint(expr)
+		}
+	case *ast.Call:
+		l.openScope()
+		defer l.closeScope()
+
+		l.Lint(expr.Func)
+		for _, arg := range expr.Args {
+			l.Lint(arg)
+		}
+	case *ast.Command:
+		for _, arg := range expr.Args {
+			l.Lint(arg)
+		}
+		if expr.Stdin != nil {
+			l.Lint(expr.Stdin)
+		}
+	case *ast.And:
+		for _, expr := range expr.Exprs {
+			l.Lint(expr)
+		}
+	case *ast.Or:
+		for _, expr := range expr.Exprs {
+			l.Lint(expr)
+		}
+	case *ast.Convert:
+		l.Lint(expr.Value)
+	case *ast.Tuple:
+		for _, item := range expr.Items {
+			l.Lint(item)
+		}
+	case *ast.Slice:
+		for _, item := range expr.Items {
+			l.Lint(item)
+		}
+	case *ast.Index:
+		l.Lint(expr.Value)
+		l.Lint(expr.Index)
+	case *ast.Interpolation:
+		for _, part := range expr.Parts {
+			l.Lint(part)
+		}
+	case *ast.Dict:
+		for _, field := range expr.Fields {
+			l.Lint(field.Value)
+		}
+	}
+}
+
+// lintFunc lints a function literal. A try handler has to take the error even if it does not
+// need it, so the params of a handler are never reported as unused.
+func (l *Linter) lintFunc(fn *ast.Func, handler bool) {
+	l.openScope()
+	defer l.closeScope()
+
+	for _, param := range fn.Type.Params.Params {
+		l.declare("param", param.Identifier, l.Expanded[fn])
+		if handler {
+			l.scope.bindings[param.Identifier.Name].used = true
+		}
+	}
+
+	l.Lint(fn.Body)
+}
+
+func (l *Linter) lintScoped(expr ast.Expr) {
+	l.openScope()
+	defer l.closeScope()
+
+	l.Lint(expr)
+}
+
+// lintImpl warns if an impl shadows a builtin. Overloads are selected by specificity
+// so an impl is only unreachable if it has the same signature as another overload,
+// which the checker reports as an error.
+func (l *Linter) lintImpl(impl *ast.Impl) {
+	name := impl.Identifier.Name
+	if _, ok := l.table.LookupBuiltin(name); ok {
+		l.addWarning(fmt.Errorf("impl '%s' shadows the builtin '%s'", name, name))
+	}
+}
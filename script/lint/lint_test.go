@@ -0,0 +1,182 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bjatkin/nook/script/checker"
+	"github.com/bjatkin/nook/script/internal/scripttest"
+	"github.com/bjatkin/nook/script/normalizer"
+	"github.com/bjatkin/nook/script/parser"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	type args struct {
+		setup []string
+		code  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "no warnings",
+			args: args{code: `(let add1 (fn [a int] (+ a 1)))`},
+			want: nil,
+		},
+		{
+			name: "top level lets are not reported",
+			args: args{code: `(let a 1)`},
+			want: nil,
+		},
+		{
+			name: "unused param",
+			args: args{code: `(let one (fn [a int] 1))`},
+			want: []string{"param 'a' is declared but never used"},
+		},
+		{
+			name: "ignored param",
+			args: args{code: `(let one (fn [_a int] 1))`},
+			want: nil,
+		},
+		{
+			name: "unused try handler param",
+			args: args{code: `(try (raise "boom") (fn [e error] "ok"))`},
+			want: nil,
+		},
+		{
+			name: "unused let in a try handler",
+			args: args{code: `(try (raise "boom") (fn [e error] (do (let a 1) e)))`},
+			want: []string{"let 'a' is declared but never used"},
+		},
+		{
+			name: "unused let",
+			args: args{code: `(do (let a 1) (let b 2) b)`},
+			want: []string{"let 'a' is declared but never used"},
+		},
+		{
+			name: "replaced let",
+			args: args{code: `(do (let a 1) (let a 2) a)`},
+			want: []string{"let 'a' is declared but never used"},
+		},
+		{
+			name: "functions in a do that call each other",
+			args: args{code: `(do (let even (fn [n int] (odd n))) (let odd (fn [n int] (even n))) (even 1))`},
+			want: nil,
+		},
+		{
+			name: "unused function in a do",
+			args: args{code: `(do (let f (fn [n int] n)) 1)`},
+			want: []string{"let 'f' is declared but never used"},
+		},
+		{
+			name: "shadowed builtin",
+			args: args{code: `(let f (fn [cd] cd))`},
+			want: []string{"param 'cd' shadows the builtin 'cd'"},
+		},
+		{
+			name: "more specific impl after a generic impl",
+			args: args{
+				setup: []string{`(impl add (fn [a b] a))`},
+				code:  `(impl add (fn [a b int] (+ a b)))`,
+			},
+			want: nil,
+		},
+		{
+			name: "impl shadows a builtin",
+			args: args{code: `(impl cd (fn [a int] a))`},
+			want: []string{"impl 'cd' shadows the builtin 'cd'"},
+		},
+		{
+			name: "reachable impl",
+			args: args{
+				setup: []string{`(impl add (fn [a b int] (+ a b)))`},
+				code:  `(impl add (fn [a b float] (+ a b)))`,
+			},
+			want: nil,
+		},
+		{
+			name: "constant if",
+			args: args{code: `(if true 1 2)`},
+			want: []string{"if condition is always true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := checker.NewChecker()
+			for _, code := range tt.args.setup {
+				_ = c.Infer(scripttest.Normalize(t, code))
+				if len(c.Errors) > 0 {
+					t.Fatalf("failed to check '%s': %v", code, c.Errors)
+				}
+			}
+
+			l := NewLinter(c.Table())
+			l.Lint(scripttest.Normalize(t, tt.args.code))
+
+			var got []string
+			for _, warning := range l.Warnings {
+				got = append(got, warning.Error())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Linter.Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinter_Lint_macro(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{
+			name: "constant if in a template",
+			code: `(unless false 1 2)`,
+			want: nil,
+		},
+		{
+			name: "unused binding in a template",
+			code: `(ignore (+ 1 2))`,
+			want: nil,
+		},
+		{
+			name: "arguments are still linted",
+			code: `(unless true (if false 1 2) (do (let a 1) 2))`,
+			want: []string{"let 'a' is declared but never used", "if condition is always false"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := normalizer.Normalizer{}
+			for _, code := range []string{
+				`(macro unless [c a b] (quasiquote (if (unquote c) (unquote b) (unquote a))))`,
+				`(macro ignore [x] (quasiquote (do (let unused (unquote x)) 0)))`,
+			} {
+				n.Normalize(parser.NewParser([]byte(code)).Parse())
+			}
+
+			n.Begin()
+			expr := n.Normalize(parser.NewParser([]byte(tt.code)).Parse())
+			if len(n.Errors) > 0 {
+				t.Fatalf("failed to normalize '%s': %v", tt.code, n.Errors)
+			}
+
+			l := NewLinter(checker.NewChecker().Table())
+			l.Expanded = n.Expanded
+			l.Lint(expr)
+
+			var got []string
+			for _, warning := range l.Warnings {
+				got = append(got, warning.Error())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Linter.Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
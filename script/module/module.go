@@ -0,0 +1,217 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/normalizer"
+	"github.com/bjatkin/nook/script/parser"
+)
+
+// Loader reads the files imported by an expression so they can be checked and evaluated.
+// Parsed files are cached by their path so a file is only parsed again once it has changed.
+// Every load normalizes a new ast since the checker rewrites calls to the overload it selected.
+type Loader struct {
+	cache map[string]parsedFile
+
+	// loading is the stack of files that are currently being loaded, it is used to detect cycles
+	loading []string
+	// imports are the modules imported by the file that is currently being loaded
+	imports []*ast.Module
+	Errors  []error
+}
+
+// parsedFile is the parse result of a file along with the hash of the source it was parsed from
+type parsedFile struct {
+	hash  string
+	exprs []ast.Expr
+	lines []int
+}
+
+// NewLoader creates a new loader with an empty cache
+func NewLoader() *Loader {
+	return &Loader{
+		cache: map[string]parsedFile{},
+	}
+}
+
+func (l *Loader) addError(err error) {
+	l.Errors = append(l.Errors, err)
+}
+
+// Load finds every import in the expression and loads the imported file.
+// Relative paths in the expression are resolved from the current directory.
+func (l *Loader) Load(expr ast.Expr) {
+	l.Errors = nil
+	l.imports = nil
+	l.load(expr, "")
+}
+
+// load resolves the imports in an expression, relative imports are resolved from dir
+func (l *Loader) load(expr ast.Expr, dir string) {
+	switch expr := expr.(type) {
+	case *ast.Import:
+		path := expr.Path.Value
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		expr.Module = l.loadFile(path)
+		l.imports = append(l.imports, expr.Module)
+	case *ast.Namespace:
+		for _, expr := range expr.Exprs {
+			l.load(expr, dir)
+		}
+	case *ast.Do:
+		for _, expr := range expr.Exprs {
+			l.load(expr, dir)
+		}
+	case *ast.Try:
+		l.load(expr.Body, dir)
+		l.load(expr.Handler, dir)
+	case *ast.Let:
+		l.load(expr.Value, dir)
+	case *ast.Impl:
+		l.load(expr.Func, dir)
+	case *ast.Func:
+		l.load(expr.Body, dir)
+	case *ast.If:
+		l.load(expr.Cond, dir)
+		l.load(expr.Then, dir)
+		l.load(expr.Else, dir)
+	case *ast.Match:
+		l.load(expr.Value, dir)
+		for _, matchCase := range expr.Cases {
+			l.load(matchCase.Body, dir)
+		}
+		l.load(expr.Else, dir)
+	case *ast.And:
+		for _, expr := range expr.Exprs {
+			l.load(expr, dir)
+		}
+	case *ast.Or:
+		for _, expr := range expr.Exprs {
+			l.load(expr, dir)
+		}
+	case *ast.Convert:
+		l.load(expr.Value, dir)
+	case *ast.Tuple:
+		for _, item := range expr.Items {
+			l.load(item, dir)
+		}
+	case *ast.Slice:
+		for _, item := range expr.Items {
+			l.load(item, dir)
+		}
+	case *ast.Index:
+		l.load(expr.Value, dir)
+		l.load(expr.Index, dir)
+	case *ast.Interpolation:
+		for _, part := range expr.Parts {
+			l.load(part, dir)
+		}
+	case *ast.Dict:
+		for _, field := range expr.Fields {
+			l.load(field.Value, dir)
+		}
+	case *ast.Call:
+		l.load(expr.Func, dir)
+		for _, arg := range expr.Args {
+			l.load(arg, dir)
+		}
+	}
+}
+
+// loadFile parses and normalizes a file, any imports in the file are loaded as well.
+// Errors in the file are reported with the path of the file and the line they were found on.
+func (l *Loader) loadFile(path string) *ast.Module {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		l.addError(fmt.Errorf("failed to import '%s' %w", path, err))
+		return nil
+	}
+
+	if slices.Contains(l.loading, abs) {
+		cycle := append(slices.Clone(l.loading), abs)
+		l.addError(fmt.Errorf("import cycle %s", strings.Join(cycle, " -> ")))
+		return nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		l.addError(fmt.Errorf("failed to import '%s' %w", path, err))
+		return nil
+	}
+
+	sum := sha256.Sum256(source)
+	hash := hex.EncodeToString(sum[:])
+
+	l.loading = append(l.loading, abs)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	// the cached parse result is replaced whenever the file changes so old versions are not kept
+	parsed, ok := l.cache[abs]
+	if !ok || parsed.hash != hash {
+		p := parser.NewParser(source)
+		exprs, lines := p.ParseFile()
+		for _, err := range p.Errors {
+			l.addError(fmt.Errorf("%s:%w", path, err))
+		}
+		if len(p.Errors) > 0 {
+			delete(l.cache, abs)
+			return nil
+		}
+
+		parsed = parsedFile{hash: hash, exprs: exprs, lines: lines}
+		l.cache[abs] = parsed
+	}
+
+	// macros defined in the file can be used by the expressions that follow them
+	errs := len(l.Errors)
+	n := normalizer.Normalizer{}
+	exprs := make([]ast.Expr, len(parsed.exprs))
+	for i, expr := range parsed.exprs {
+		normErrs := len(n.Errors)
+		exprs[i] = n.Normalize(expr)
+		for _, err := range n.Errors[normErrs:] {
+			l.addError(fmt.Errorf("%s:%d: %w", path, parsed.lines[i], err))
+		}
+	}
+
+	outer := l.imports
+	l.imports = nil
+	for _, expr := range exprs {
+		l.load(expr, filepath.Dir(path))
+	}
+	imports := l.imports
+	l.imports = outer
+
+	if len(l.Errors) > errs {
+		return nil
+	}
+
+	return &ast.Module{
+		Path:  path,
+		Hash:  moduleHash(hash, imports),
+		Exprs: exprs,
+		Lines: parsed.lines,
+	}
+}
+
+// moduleHash combines the hash of a file with the hashes of the modules it imports, so the hash
+// of a module changes whenever any file it depends on changes
+func moduleHash(hash string, imports []*ast.Module) string {
+	h := sha256.New()
+	h.Write([]byte(hash))
+	for _, module := range imports {
+		h.Write([]byte(module.Hash))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,191 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/internal/scripttest"
+)
+
+func TestLoader_Load(t *testing.T) {
+	type args struct {
+		files map[string]string
+		code  string
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantLines []int
+		wantErrs  []string
+	}{
+		{
+			name: "import a file",
+			args: args{
+				files: map[string]string{
+					"git.nk": "(let branch \"main\")\n\n(let remote \"origin\")\n",
+				},
+				code: "(import ./git.nk)",
+			},
+			wantLines: []int{1, 3},
+		},
+		{
+			name: "nested imports are relative to the importing file",
+			args: args{
+				files: map[string]string{
+					"lib/git.nk":  "(import ./util.nk)",
+					"lib/util.nk": "(let a 1)",
+				},
+				code: "(import ./lib/git.nk)",
+			},
+			wantLines: []int{1},
+		},
+		{
+			name: "import cycle",
+			args: args{
+				files: map[string]string{
+					"a.nk": "(import ./b.nk)",
+					"b.nk": "(import ./a.nk)",
+				},
+				code: "(import ./a.nk)",
+			},
+			wantErrs: []string{"import cycle {dir}/a.nk -> {dir}/b.nk -> {dir}/a.nk"},
+		},
+		{
+			name: "errors report the file and line",
+			args: args{
+				files: map[string]string{
+					"bad.nk": "(let a 1)\n(let 1 a)\n",
+				},
+				code: "(import ./bad.nk)",
+			},
+			wantErrs: []string{"{dir}/bad.nk:2: firt operand to 'let' must be an identifier but got '*ast.Int'"},
+		},
+		{
+			name: "missing file",
+			args: args{
+				code: "(import ./missing.nk)",
+			},
+			wantErrs: []string{"failed to import '{dir}/missing.nk' open {dir}/missing.nk: no such file or directory"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.args.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+
+			// imports in the code are relative to the temp dir
+			expr := scripttest.Normalize(t, tt.args.code)
+			l := NewLoader()
+			l.load(expr, dir)
+
+			var gotErrs []string
+			for _, err := range l.Errors {
+				gotErrs = append(gotErrs, err.Error())
+			}
+			var wantErrs []string
+			for _, err := range tt.wantErrs {
+				wantErrs = append(wantErrs, strings.ReplaceAll(err, "{dir}", dir))
+			}
+			if !reflect.DeepEqual(gotErrs, wantErrs) {
+				t.Fatalf("Loader.Load() errors = %v, want %v", gotErrs, wantErrs)
+			}
+			if len(wantErrs) > 0 {
+				return
+			}
+
+			module := expr.(*ast.Import).Module
+			if !reflect.DeepEqual(module.Lines, tt.wantLines) {
+				t.Errorf("Loader.Load() lines = %v, want %v", module.Lines, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestLoader_Load_cache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "git.nk")
+	if err := os.WriteFile(path, []byte("(let branch \"main\")"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	l := NewLoader()
+	first := scripttest.Normalize(t, "(import "+path+")").(*ast.Import)
+	l.Load(first)
+	second := scripttest.Normalize(t, "(import "+path+")").(*ast.Import)
+	l.Load(second)
+	if first.Module.Hash != second.Module.Hash {
+		t.Errorf("Loader.Load() changed the hash of an unchanged file")
+	}
+	if first.Module == second.Module || &first.Module.Exprs[0] == &second.Module.Exprs[0] {
+		t.Errorf("Loader.Load() shared an ast between two loads of the same file")
+	}
+
+	if err := os.WriteFile(path, []byte("(let branch \"dev\")"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	third := scripttest.Normalize(t, "(import "+path+")").(*ast.Import)
+	l.Load(third)
+	if first.Module.Hash == third.Module.Hash {
+		t.Errorf("Loader.Load() did not reload a changed file")
+	}
+	if len(l.cache) != 1 {
+		t.Errorf("Loader.Load() cached %d files, want 1", len(l.cache))
+	}
+}
+
+func TestLoader_Load_cacheImports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.nk":     "(import ./b.nk)",
+		"b.nk":     "(let b 1)",
+		"lib/a.nk": "(import ./b.nk)",
+		"lib/b.nk": "(let b 2)",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	l := NewLoader()
+	first := scripttest.Normalize(t, "(import "+filepath.Join(dir, "a.nk")+")").(*ast.Import)
+	l.Load(first)
+	lib := scripttest.Normalize(t, "(import "+filepath.Join(dir, "lib/a.nk")+")").(*ast.Import)
+	l.Load(lib)
+	if first.Module.Hash == lib.Module.Hash {
+		t.Errorf("Loader.Load() gave files that import different files the same hash")
+	}
+	if lib.Module.Path != filepath.Join(dir, "lib/a.nk") {
+		t.Errorf("Loader.Load() path = %s, want %s", lib.Module.Path, filepath.Join(dir, "lib/a.nk"))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.nk"), []byte("(let b 3)"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	second := scripttest.Normalize(t, "(import "+filepath.Join(dir, "a.nk")+")").(*ast.Import)
+	l.Load(second)
+	if len(l.Errors) > 0 {
+		t.Fatalf("Loader.Load() errors = %v", l.Errors)
+	}
+	if first.Module.Hash == second.Module.Hash {
+		t.Errorf("Loader.Load() did not reload a file when the file it imports changed")
+	}
+}
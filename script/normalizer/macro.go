@@ -0,0 +1,339 @@
+package normalizer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/token"
+)
+
+// maxExpansionDepth limits how many times a macro can expand into another macro call
+// so recursive macros report an error instead of expanding forever
+const maxExpansionDepth = 64
+
+// macro is a template that is expanded into a new s-expression each time it is called
+type macro struct {
+	name   string
+	params []string
+
+	// rest is the name of the param that collects any remaining arguments, it is empty
+	// if the macro takes a fixed number of arguments
+	rest string
+
+	// template is the quasiquoted s-expression, quoted templates have no unquotes
+	template ast.Expr
+}
+
+// Begin starts a new transaction. Macros defined after Begin is called are only
+// kept if Commit is called, Rollback will remove them from the normalizer.
+func (n *Normalizer) Begin() {
+	n.Errors = nil
+	n.Expanded = nil
+	n.journal = map[string]*macro{}
+}
+
+// Commit keeps all the macros defined since Begin was called
+func (n *Normalizer) Commit() {
+	n.journal = nil
+}
+
+// Rollback removes all the macros defined since Begin was called and clears any errors
+func (n *Normalizer) Rollback() {
+	for name, prev := range n.journal {
+		if prev == nil {
+			delete(n.macros, name)
+			continue
+		}
+
+		n.macros[name] = prev
+	}
+
+	n.journal = nil
+	n.Errors = nil
+}
+
+// defineMacro normalizes s-expressions in the form (macro name [params] (quasiquote template)).
+// The final param may be written as (splice name) to collect any remaining arguments.
+func (n *Normalizer) defineMacro(tok token.Token, operands ...ast.Expr) (*ast.Macro, error) {
+	if len(operands) != 3 {
+		return nil, fmt.Errorf("macro expression takes 3 operands (macro [identifier] [params] (quasiquote [template]))")
+	}
+
+	identifier, ok := operands[0].(*ast.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("first operand to 'macro' must be an identifier but got '%T'", operands[0])
+	}
+	if strings.Contains(identifier.Name, ".") {
+		return nil, fmt.Errorf("macro can not declare the qualified name '%s'", identifier.Name)
+	}
+
+	m := &macro{name: identifier.Name}
+
+	params, ok := operands[1].(*ast.SExpr)
+	if !ok {
+		return nil, fmt.Errorf("second operand to 'macro' must be a param list but got '%T'", operands[1])
+	}
+	if _, ok := params.Operator.(*ast.SSquare); !ok {
+		return nil, fmt.Errorf("second operand to 'macro' must be a param list but got '%T'", params.Operator)
+	}
+
+	for i, param := range params.Operands {
+		if ident, ok := param.(*ast.Identifier); ok {
+			m.params = append(m.params, ident.Name)
+			continue
+		}
+
+		name, ok := spliceName(param)
+		if !ok || i != len(params.Operands)-1 {
+			return nil, fmt.Errorf("macro params must be identifiers and only the final param can be spliced")
+		}
+
+		m.rest = name
+	}
+
+	template, ok := operands[2].(*ast.SExpr)
+	if !ok || len(template.Operands) != 1 {
+		return nil, fmt.Errorf("macro template must be in the form (quasiquote [template]) or (quote [template])")
+	}
+	switch template.Operator.(type) {
+	case *ast.SQuasiquote:
+		m.template = template.Operands[0]
+	case *ast.SQuote:
+		m.template = &ast.SExpr{Operator: template.Operator, Operands: template.Operands}
+	default:
+		return nil, fmt.Errorf("macro template must be in the form (quasiquote [template]) or (quote [template])")
+	}
+
+	err := m.validate(m.template)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.macros == nil {
+		n.macros = map[string]*macro{}
+	}
+	if n.journal != nil {
+		if _, ok := n.journal[m.name]; !ok {
+			n.journal[m.name] = n.macros[m.name]
+		}
+	}
+	n.macros[m.name] = m
+
+	return &ast.Macro{Tok: tok, Identifier: identifier}, nil
+}
+
+// validate makes sure every unquote and splice in the template refers to a macro param
+func (m *macro) validate(expr ast.Expr) error {
+	sexpr, ok := expr.(*ast.SExpr)
+	if !ok {
+		return nil
+	}
+
+	switch sexpr.Operator.(type) {
+	case *ast.SQuote:
+		return nil
+	case *ast.SUnquote:
+		name, ok := unquoteName(sexpr)
+		if !ok || !slices.Contains(m.params, name) {
+			return fmt.Errorf("unquote in macro '%s' must refer to one of its params", m.name)
+		}
+		return nil
+	case *ast.SSplice:
+		name, ok := spliceName(sexpr)
+		if !ok || name != m.rest {
+			return fmt.Errorf("splice in macro '%s' must refer to its rest param", m.name)
+		}
+		return nil
+	}
+
+	err := m.validate(sexpr.Operator)
+	if err != nil {
+		return err
+	}
+	for _, op := range sexpr.Operands {
+		err := m.validate(op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expandMacro replaces a macro call with the macro template and normalizes the result
+func (n *Normalizer) expandMacro(m *macro, operands ...ast.Expr) (ast.Expr, error) {
+	switch {
+	case m.rest == "" && len(operands) != len(m.params):
+		return nil, fmt.Errorf("macro '%s' takes %d arguments but got %d", m.name, len(m.params), len(operands))
+	case len(operands) < len(m.params):
+		return nil, fmt.Errorf("macro '%s' takes at least %d arguments but got %d", m.name, len(m.params), len(operands))
+	}
+
+	if n.depth >= maxExpansionDepth {
+		return nil, fmt.Errorf("expansion of macro '%s' is nested more than %d times", m.name, maxExpansionDepth)
+	}
+	n.depth++
+	defer func() { n.depth-- }()
+
+	args := map[string]ast.Expr{}
+	for i, param := range m.params {
+		args[param] = operands[i]
+	}
+
+	// bindings introduced by the template are renamed so they can not capture identifiers
+	// passed in as arguments. '#' can not be written in an identifier so the new names are unique.
+	n.gensym++
+	renames := map[string]string{}
+	for _, name := range templateBindings(m.template) {
+		renames[name] = fmt.Sprintf("%s#%d", name, n.gensym)
+	}
+
+	expanded := expand(m.template, args, operands[len(m.params):], renames)
+	if len(expanded) != 1 {
+		return nil, fmt.Errorf("macro '%s' must expand to a single expression", m.name)
+	}
+
+	// the arguments keep the template state of the caller, everything else came from the template
+	callerArgs := n.args
+	n.args = maps.Clone(callerArgs)
+	if n.args == nil {
+		n.args = map[ast.Expr]bool{}
+	}
+	for _, op := range operands {
+		n.args[op] = n.template
+	}
+
+	errs := len(n.Errors)
+	template := n.template
+	n.template = true
+	expr := n.Normalize(expanded[0])
+	n.template, n.args = template, callerArgs
+	for i := errs; i < len(n.Errors); i++ {
+		n.Errors[i] = fmt.Errorf("in expansion of macro '%s': %w", m.name, n.Errors[i])
+	}
+
+	return expr, nil
+}
+
+// expand copies the template replacing unquotes with args, splices with the rest args and
+// renaming any identifiers that are bound by the template. It returns a slice so splices can
+// add multiple expressions to the parent s-expression.
+func expand(expr ast.Expr, args map[string]ast.Expr, rest []ast.Expr, renames map[string]string) []ast.Expr {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		if name, ok := renames[expr.Name]; ok {
+			return []ast.Expr{&ast.Identifier{Tok: expr.Tok, Name: name}}
+		}
+		return []ast.Expr{expr}
+	case *ast.Interpolation:
+		parts := []ast.Expr{}
+		for _, part := range expr.Parts {
+			parts = append(parts, expand(part, args, rest, renames)...)
+		}
+
+		return []ast.Expr{&ast.Interpolation{Tok: expr.Tok, Parts: parts}}
+	case *ast.SExpr:
+		switch expr.Operator.(type) {
+		case *ast.SQuote:
+			return expr.Operands
+		case *ast.SUnquote:
+			name, _ := unquoteName(expr)
+			return []ast.Expr{args[name]}
+		case *ast.SSplice:
+			return rest
+		}
+
+		operands := []ast.Expr{}
+		for _, op := range expr.Operands {
+			operands = append(operands, expand(op, args, rest, renames)...)
+		}
+
+		operator := expand(expr.Operator, args, rest, renames)
+		if len(operator) == 0 {
+			return []ast.Expr{}
+		}
+
+		return []ast.Expr{&ast.SExpr{
+			Operator: operator[0],
+			Operands: append(operator[1:], operands...),
+		}}
+	default:
+		return []ast.Expr{expr}
+	}
+}
+
+// templateBindings finds the names bound by let and fn expressions written in the template
+func templateBindings(expr ast.Expr) []string {
+	sexpr, ok := expr.(*ast.SExpr)
+	if !ok {
+		return nil
+	}
+
+	names := []string{}
+	switch sexpr.Operator.(type) {
+	case *ast.SQuote, *ast.SUnquote, *ast.SSplice:
+		// these are not part of the template so they do not bind anything
+		return nil
+	case *ast.SLet:
+		if len(sexpr.Operands) > 0 {
+			if ident, ok := sexpr.Operands[0].(*ast.Identifier); ok {
+				names = append(names, ident.Name)
+			}
+		}
+	case *ast.SFunc:
+		if len(sexpr.Operands) > 0 {
+			if params, ok := sexpr.Operands[0].(*ast.SExpr); ok {
+				for _, param := range params.Operands {
+					if ident, ok := param.(*ast.Identifier); ok {
+						names = append(names, ident.Name)
+					}
+				}
+			}
+		}
+	}
+
+	for _, op := range sexpr.Operands {
+		names = append(names, templateBindings(op)...)
+	}
+
+	return names
+}
+
+// unquoteName returns the param name from an (unquote name) expression
+func unquoteName(expr ast.Expr) (string, bool) {
+	sexpr, ok := expr.(*ast.SExpr)
+	if !ok || len(sexpr.Operands) != 1 {
+		return "", false
+	}
+	if _, ok := sexpr.Operator.(*ast.SUnquote); !ok {
+		return "", false
+	}
+
+	ident, ok := sexpr.Operands[0].(*ast.Identifier)
+	if !ok {
+		return "", false
+	}
+
+	return ident.Name, true
+}
+
+// spliceName returns the param name from a (splice name) expression
+func spliceName(expr ast.Expr) (string, bool) {
+	sexpr, ok := expr.(*ast.SExpr)
+	if !ok || len(sexpr.Operands) != 1 {
+		return "", false
+	}
+	if _, ok := sexpr.Operator.(*ast.SSplice); !ok {
+		return "", false
+	}
+
+	ident, ok := sexpr.Operands[0].(*ast.Identifier)
+	if !ok {
+		return "", false
+	}
+
+	return ident.Name, true
+}
@@ -0,0 +1,123 @@
+package normalizer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/parser"
+)
+
+func TestNormalizer_Normalize_macro(t *testing.T) {
+	type args struct {
+		setup []string
+		code  string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr string
+	}{
+		{
+			name: "unquote params",
+			args: args{
+				setup: []string{`(macro unless [c a b] (quasiquote (if (unquote c) (unquote b) (unquote a))))`},
+				code:  `(unless true 1 2)`,
+			},
+			want: "*ast.If",
+		},
+		{
+			name: "splice rest params",
+			args: args{
+				setup: []string{`(macro sum [(splice xs)] (quasiquote (+ (splice xs))))`},
+				code:  `(sum 1 2 3)`,
+			},
+			want: "*ast.Call",
+		},
+		{
+			name: "quoted template",
+			args: args{
+				setup: []string{`(macro three [] (quote (+ 1 2)))`},
+				code:  `(three)`,
+			},
+			want: "*ast.Call",
+		},
+		{
+			name: "wrong number of arguments",
+			args: args{
+				setup: []string{`(macro unless [c a b] (quasiquote (if (unquote c) (unquote b) (unquote a))))`},
+				code:  `(unless true 1)`,
+			},
+			wantErr: "macro 'unless' takes 3 arguments but got 2",
+		},
+		{
+			name: "unquote must refer to a param",
+			args: args{
+				code: `(macro bad [a] (quasiquote (+ (unquote b) 1)))`,
+			},
+			wantErr: "unquote in macro 'bad' must refer to one of its params",
+		},
+		{
+			name: "recursive macro",
+			args: args{
+				setup: []string{`(macro loop [a] (quasiquote (loop (unquote a))))`},
+				code:  `(loop 1)`,
+			},
+			wantErr: "expansion of macro 'loop' is nested more than 64 times",
+		},
+		{
+			name: "unquote outside of a macro",
+			args: args{
+				code: `(unquote a)`,
+			},
+			wantErr: "quote, quasiquote, unquote and splice can only be used in a macro template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Normalizer{}
+			for _, code := range tt.args.setup {
+				_ = n.Normalize(parser.NewParser([]byte(code)).Parse())
+				if len(n.Errors) > 0 {
+					t.Fatalf("failed to normalize '%s': %v", code, n.Errors)
+				}
+			}
+
+			got := n.Normalize(parser.NewParser([]byte(tt.args.code)).Parse())
+
+			gotErr := ""
+			if len(n.Errors) > 0 {
+				gotErr = n.Errors[len(n.Errors)-1].Error()
+			}
+			// errors from nested expansions are wrapped so only the end of the error is compared
+			if !strings.HasSuffix(gotErr, tt.wantErr) {
+				t.Fatalf("Normalizer.Normalize() error = %q, want %q", gotErr, tt.wantErr)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			if gotType := reflect.TypeOf(got).String(); gotType != tt.want {
+				t.Errorf("Normalizer.Normalize() = %s, want %s", gotType, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Normalize_hygiene(t *testing.T) {
+	n := &Normalizer{}
+	_ = n.Normalize(parser.NewParser([]byte(`(macro withone [body] (quasiquote (do (let one 1) (unquote body))))`)).Parse())
+	got := n.Normalize(parser.NewParser([]byte(`(withone one)`)).Parse())
+	if len(n.Errors) > 0 {
+		t.Fatalf("Normalizer.Normalize() errors %v", n.Errors)
+	}
+
+	do := got.(*ast.Do)
+	let := do.Exprs[0].(*ast.Let)
+	body := do.Exprs[1].(*ast.Identifier)
+	if let.Identifier.Name == body.Name {
+		t.Errorf("Normalizer.Normalize() macro binding '%s' captured the argument '%s'", let.Identifier.Name, body.Name)
+	}
+}
@@ -2,6 +2,8 @@ package normalizer
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/bjatkin/nook/script/ast"
 	"github.com/bjatkin/nook/script/token"
@@ -10,6 +12,21 @@ import (
 // Normalizer normalizes the initial ast into a semantically correct ast
 type Normalizer struct {
 	Errors []error
+
+	// Expanded holds the nodes that were written in a macro template rather than by the caller
+	// of the macro, so the linter does not warn about code the caller did not write. Begin clears it.
+	Expanded map[ast.Expr]bool
+
+	// macros are kept between calls to Normalize so macros can be used after they are defined
+	macros  map[string]*macro
+	journal map[string]*macro
+	depth   int
+	gensym  int
+
+	// template is true while normalizing code that came from a macro template, args maps the
+	// arguments of the macros being expanded to whether the caller was a template as well
+	template bool
+	args     map[ast.Expr]bool
 }
 
 // addError adds a new error to the Normalizer struct
@@ -19,6 +36,24 @@ func (n *Normalizer) addError(err error) {
 
 // Normalize takes an expression and normalizes it into a semantically correct ast node
 func (n *Normalizer) Normalize(expr ast.Expr) ast.Expr {
+	template := n.template
+	defer func() { n.template = template }()
+	if caller, ok := n.args[expr]; ok {
+		n.template = caller
+	}
+
+	normalized := n.normalize(expr)
+	if n.template && normalized != nil {
+		if n.Expanded == nil {
+			n.Expanded = map[ast.Expr]bool{}
+		}
+		n.Expanded[normalized] = true
+	}
+
+	return normalized
+}
+
+func (n *Normalizer) normalize(expr ast.Expr) ast.Expr {
 	switch expr := expr.(type) {
 	case *ast.SExpr:
 		normalized, err := n.normalizeSExpr(expr.Operator, expr.Operands...)
@@ -28,6 +63,13 @@ func (n *Normalizer) Normalize(expr ast.Expr) ast.Expr {
 		}
 
 		return normalized
+	case *ast.Interpolation:
+		parts := []ast.Expr{}
+		for _, part := range expr.Parts {
+			parts = append(parts, n.Normalize(part))
+		}
+
+		return &ast.Interpolation{Tok: expr.Tok, Parts: parts}
 	default:
 		return expr
 	}
@@ -40,6 +82,20 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 		// convert from $git -> git
 		name := operator.Tok.Value[1:]
 
+		// a '<' redirects the final operand to the command's stdin (e.g. ($psql < query))
+		var stdin ast.Expr
+		for i, op := range operands {
+			if ident, ok := op.(*ast.Identifier); ok && ident.Tok.Kind == token.LessThan {
+				if i != len(operands)-2 {
+					return nil, fmt.Errorf("'<' must be followed by a single expression at the end of the command")
+				}
+
+				stdin = n.Normalize(operands[i+1])
+				operands = operands[:i]
+				break
+			}
+		}
+
 		normArgs := []ast.Expr{}
 		for _, op := range operands {
 			expr := n.Normalize(op)
@@ -47,9 +103,10 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 		}
 
 		return &ast.Command{
-			Tok:  operator.Tok,
-			Name: name,
-			Args: normArgs,
+			Tok:   operator.Tok,
+			Name:  name,
+			Args:  normArgs,
+			Stdin: stdin,
 		}, nil
 	case *ast.SLet:
 		if len(operands) != 2 {
@@ -60,6 +117,9 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 		if !ok {
 			return nil, fmt.Errorf("firt operand to 'let' must be an identifier but got '%T'", operands[0])
 		}
+		if strings.Contains(identifier.Name, ".") {
+			return nil, fmt.Errorf("let can not bind the qualified name '%s'", identifier.Name)
+		}
 
 		return &ast.Let{
 			Tok:        operator.Tok,
@@ -67,17 +127,168 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 			Value:      n.Normalize(operands[1]),
 		}, nil
 	case *ast.SFunc:
-		// support functions in the form (fn [params] type [body])
-		// as well as (fn [params] [body]) where the return type is infered
-		switch len(operands) {
-		case 2:
-			return n.normalizeUntypedFunc(operator.Tok, operands...)
-		case 3:
-			return n.normalizeTypedFunc(operator.Tok, operands...)
-		default:
-			return nil, fmt.Errorf("fn expression takes either 3 or 4 operands (fn [params] <return type> [body])")
+		return n.normalizeFunc(operator.Tok, operands...)
+	case *ast.SImpl:
+		if len(operands) != 2 {
+			return nil, fmt.Errorf("impl expression takes 2 operands (impl [identifier] [function])")
+		}
+
+		identifier, ok := operands[0].(*ast.Identifier)
+		if !ok {
+			return nil, fmt.Errorf("first operand to 'impl' must be an identifier but got '%T'", operands[0])
+		}
+		if strings.Contains(identifier.Name, ".") {
+			return nil, fmt.Errorf("impl can not bind the qualified name '%s'", identifier.Name)
+		}
+
+		fnExpr, ok := operands[1].(*ast.SExpr)
+		if !ok {
+			return nil, fmt.Errorf("second operand to 'impl' must be a function literal but got '%T'", operands[1])
+		}
+		fnOp, ok := fnExpr.Operator.(*ast.SFunc)
+		if !ok {
+			return nil, fmt.Errorf("second operand to 'impl' must be a function literal but got '%T'", fnExpr.Operator)
+		}
+
+		fn, err := n.normalizeFunc(fnOp.Tok, fnExpr.Operands...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ast.Impl{
+			Tok:        operator.Tok,
+			Identifier: identifier,
+			Func:       fn,
+		}, nil
+	case *ast.SNamespace:
+		if len(operands) == 0 {
+			return nil, fmt.Errorf("ns expression requires a name (ns [identifier] [expr]...)")
+		}
+
+		identifier, ok := operands[0].(*ast.Identifier)
+		if !ok {
+			return nil, fmt.Errorf("first operand to 'ns' must be an identifier but got '%T'", operands[0])
+		}
+		if strings.Contains(identifier.Name, ".") {
+			return nil, fmt.Errorf("ns can not declare the qualified name '%s'", identifier.Name)
+		}
+
+		exprs := []ast.Expr{}
+		for _, op := range operands[1:] {
+			exprs = append(exprs, n.Normalize(op))
+		}
+
+		return &ast.Namespace{
+			Tok:        operator.Tok,
+			Identifier: identifier,
+			Exprs:      exprs,
+		}, nil
+	case *ast.SImport:
+		if len(operands) != 1 {
+			return nil, fmt.Errorf("import expression takes exactly 1 operand (import [path])")
+		}
+
+		path, ok := operands[0].(*ast.Path)
+		if !ok {
+			return nil, fmt.Errorf("operand to 'import' must be a path but got '%T'", operands[0])
+		}
+
+		// the namespace is named after the file without its extension
+		name := filepath.Base(path.Value)
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		if strings.Contains(name, ".") {
+			return nil, fmt.Errorf("can not import '%s' since '%s' is not a valid namespace name", path.Value, name)
+		}
+
+		return &ast.Import{
+			Tok:        operator.Tok,
+			Path:       path,
+			Identifier: &ast.Identifier{Tok: path.Tok, Name: name},
+		}, nil
+	case *ast.SIf:
+		// support if expressions in the form (if [cond] [then] [else])
+		// as well as (if [cond] [then]) where the else branch is omitted
+		if len(operands) != 2 && len(operands) != 3 {
+			return nil, fmt.Errorf("if expression takes either 2 or 3 operands (if [condition] [then] <else>)")
+		}
+
+		ifExpr := &ast.If{
+			Tok:  operator.Tok,
+			Cond: n.Normalize(operands[0]),
+			Then: n.Normalize(operands[1]),
+		}
+		if len(operands) == 3 {
+			ifExpr.Else = n.Normalize(operands[2])
+		}
+
+		return ifExpr, nil
+	case *ast.SMatch:
+		return n.normalizeMatch(operator.Tok, operands...)
+	case *ast.SDo:
+		if len(operands) == 0 {
+			return nil, fmt.Errorf("do expression requires at least 1 operand (do [expr]...)")
+		}
+
+		exprs := []ast.Expr{}
+		for _, op := range operands {
+			exprs = append(exprs, n.Normalize(op))
+		}
+
+		return &ast.Do{
+			Tok:   operator.Tok,
+			Exprs: exprs,
+		}, nil
+	case *ast.STry:
+		// support try expressions in the form (try [expr] [handler]) as well as (try [expr])
+		// where the error is returned as the value of the try expression
+		if len(operands) != 1 && len(operands) != 2 {
+			return nil, fmt.Errorf("try expression takes either 1 or 2 operands (try [expr] <handler>)")
+		}
+
+		tryExpr := &ast.Try{
+			Tok:  operator.Tok,
+			Body: n.Normalize(operands[0]),
+		}
+		if len(operands) == 2 {
+			tryExpr.Handler = n.Normalize(operands[1])
+		}
+
+		return tryExpr, nil
+	case *ast.SAnd:
+		exprs := []ast.Expr{}
+		for _, op := range operands {
+			exprs = append(exprs, n.Normalize(op))
+		}
+
+		return &ast.And{Tok: operator.Tok, Exprs: exprs}, nil
+	case *ast.SOr:
+		exprs := []ast.Expr{}
+		for _, op := range operands {
+			exprs = append(exprs, n.Normalize(op))
 		}
+
+		return &ast.Or{Tok: operator.Tok, Exprs: exprs}, nil
+	case *ast.SCurly:
+		return n.normalizeCurly(operator.Tok, operands...)
+	case *ast.SSquare:
+		if len(operands) != 2 {
+			return nil, fmt.Errorf("index expression takes 2 operands [[value] [index]]")
+		}
+
+		return &ast.Index{
+			Tok:   operator.Tok,
+			Value: n.Normalize(operands[0]),
+			Index: n.Normalize(operands[1]),
+		}, nil
+	case *ast.SMacro:
+		return n.defineMacro(operator.Tok, operands...)
+	case *ast.SQuote, *ast.SQuasiquote, *ast.SUnquote, *ast.SSplice:
+		return nil, fmt.Errorf("quote, quasiquote, unquote and splice can only be used in a macro template")
 	case *ast.Identifier:
+		if m, ok := n.macros[operator.Name]; ok {
+			return n.expandMacro(m, operands...)
+		}
+
 		// assume this is a function call, this will be validated in the type checker since we need to evaluate
 		// identifier types before we can know the identifiers type for certian
 		normArgs := []ast.Expr{}
@@ -87,6 +298,7 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 		}
 
 		return &ast.Call{
+			Tok:  operator.Tok,
 			Func: operator,
 			Args: normArgs,
 		}, nil
@@ -105,6 +317,7 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 		// Functions literals can be called directly if they are the s-expression operator
 		if fn, ok := normalizedOp.(*ast.Func); ok {
 			return &ast.Call{
+				Tok:  fn.Tok,
 				Func: fn,
 				Args: normArgs,
 			}, nil
@@ -114,6 +327,7 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 		// Type checking will happen later, for now assume it's a valid call
 		if call, ok := normalizedOp.(*ast.Call); ok {
 			return &ast.Call{
+				Tok:  call.Tok,
 				Func: call,
 				Args: normArgs,
 			}, nil
@@ -125,17 +339,141 @@ func (n *Normalizer) normalizeSExpr(operator ast.Expr, operands ...ast.Expr) (as
 	}
 }
 
+// normalizeFunc normalizes s-expressions in the form (fn [params] type (body)) as well as
+// (fn [params] (body)) where the return type is infered
+func (n *Normalizer) normalizeFunc(fn token.Token, operands ...ast.Expr) (*ast.Func, error) {
+	switch len(operands) {
+	case 2:
+		return n.normalizeUntypedFunc(fn, operands...)
+	case 3:
+		return n.normalizeTypedFunc(fn, operands...)
+	default:
+		return nil, fmt.Errorf("fn expression takes either 2 or 3 operands (fn [params] <return type> [body])")
+	}
+}
+
+// normalizeMatch normalizes s-expressions in the form (match value (pattern body)... (else body))
+func (n *Normalizer) normalizeMatch(tok token.Token, operands ...ast.Expr) (*ast.Match, error) {
+	if len(operands) < 2 {
+		return nil, fmt.Errorf("match expression requires a value and at least 1 case (match [value] ([pattern] [body])...)")
+	}
+
+	match := &ast.Match{
+		Tok:   tok,
+		Value: n.Normalize(operands[0]),
+	}
+
+	for _, op := range operands[1:] {
+		caseExpr, ok := op.(*ast.SExpr)
+		if !ok || len(caseExpr.Operands) != 1 {
+			return nil, fmt.Errorf("match cases must be in the form ([pattern] [body]) but got '%v'", op)
+		}
+
+		if _, ok := caseExpr.Operator.(*ast.SElse); ok {
+			if match.Else != nil {
+				return nil, fmt.Errorf("match expression can only have one else case")
+			}
+
+			match.Else = n.Normalize(caseExpr.Operands[0])
+			continue
+		}
+
+		if match.Else != nil {
+			return nil, fmt.Errorf("else must be the final case in a match expression")
+		}
+
+		match.Cases = append(match.Cases, ast.Case{
+			Pattern: n.Normalize(caseExpr.Operator),
+			Body:    n.Normalize(caseExpr.Operands[0]),
+		})
+	}
+
+	return match, nil
+}
+
+// normalizeCurly normalizes s-expressions in the form {.name value ...} into a dict literal,
+// s-expressions in the form {type value} into a conversion and s-expressions in the form
+// {value ...} into a tuple literal
+func (n *Normalizer) normalizeCurly(tok token.Token, operands ...ast.Expr) (ast.Expr, error) {
+	if len(operands) == 0 {
+		return &ast.Tuple{Tok: tok}, nil
+	}
+
+	typeExpr, size, err := normalizeTypeAt(operands)
+	if err != nil {
+		return nil, err
+	}
+
+	if sliceType, ok := typeExpr.(*ast.SliceType); ok {
+		items := []ast.Expr{}
+		for _, op := range operands[size:] {
+			items = append(items, n.Normalize(op))
+		}
+
+		return &ast.Slice{Tok: tok, Type: sliceType, Items: items}, nil
+	}
+
+	if typeExpr != nil {
+		// {none} is the only typed literal that does not need a value
+		if _, isNone := typeExpr.(*ast.NoneType); isNone && len(operands) == 1 {
+			return &ast.Convert{Tok: tok, Type: typeExpr, Value: &ast.Nil{Tok: tok}}, nil
+		}
+		if len(operands) != size+1 {
+			return nil, fmt.Errorf("typed literals must be in the form {type value}")
+		}
+
+		return &ast.Convert{Tok: tok, Type: typeExpr, Value: n.Normalize(operands[size])}, nil
+	}
+
+	if _, ok := operands[0].(*ast.Property); !ok {
+		items := []ast.Expr{}
+		for _, op := range operands {
+			if _, ok := op.(*ast.Property); ok {
+				return nil, fmt.Errorf("tuple literals can not contain properties, dict literals must start with a property")
+			}
+
+			items = append(items, n.Normalize(op))
+		}
+
+		return &ast.Tuple{Tok: tok, Items: items}, nil
+	}
+
+	if len(operands)%2 != 0 {
+		return nil, fmt.Errorf("dict literals must be in the form {.name value ...}")
+	}
+
+	dict := &ast.Dict{Tok: tok}
+	names := map[string]bool{}
+	for i := 0; i < len(operands); i += 2 {
+		property, ok := operands[i].(*ast.Property)
+		if !ok {
+			return nil, fmt.Errorf("dict literals must be in the form {.name value ...}")
+		}
+		if names[property.Name] {
+			return nil, fmt.Errorf("dict literal has more than one '.%s' field", property.Name)
+		}
+		names[property.Name] = true
+
+		dict.Fields = append(dict.Fields, ast.Field{
+			Property: property,
+			Value:    n.Normalize(operands[i+1]),
+		})
+	}
+
+	return dict, nil
+}
+
 // normalizeUntypedFunc normalizes s-expressions in the form (fn [params] (body)) into a function literal
 func (n *Normalizer) normalizeUntypedFunc(fn token.Token, operands ...ast.Expr) (*ast.Func, error) {
 	if len(operands) != 2 {
 		return nil, fmt.Errorf("expected expression in the form (fn [<params>] (body))")
 	}
 
-	params, ok := operands[0].(ast.SExpr)
+	params, ok := operands[0].(*ast.SExpr)
 	if !ok {
 		return nil, fmt.Errorf("first argument to a function definition must be a parameter list not '%v'", operands[0])
 	}
-	_, ok = params.Operator.(ast.SSquare)
+	_, ok = params.Operator.(*ast.SSquare)
 	if !ok {
 		return nil, fmt.Errorf("first argument to a function definition must be a parameter list not '%v'", operands[0])
 	}
@@ -145,7 +483,7 @@ func (n *Normalizer) normalizeUntypedFunc(fn token.Token, operands ...ast.Expr)
 		return nil, fmt.Errorf("invalid paramater list for function %w", err)
 	}
 
-	body := operands[1]
+	body := n.Normalize(operands[1])
 
 	return &ast.Func{
 		Tok: fn,
@@ -163,11 +501,11 @@ func (n *Normalizer) normalizeTypedFunc(fn token.Token, operands ...ast.Expr) (*
 		return nil, fmt.Errorf("expected expression in the form (fn [<param>] type (body))")
 	}
 
-	params, ok := operands[0].(ast.SExpr)
+	params, ok := operands[0].(*ast.SExpr)
 	if !ok {
 		return nil, fmt.Errorf("first argument to a function definition must be a parameter list not '%v'", operands[0])
 	}
-	_, ok = params.Operator.(ast.SSquare)
+	_, ok = params.Operator.(*ast.SSquare)
 	if !ok {
 		return nil, fmt.Errorf("first argument to a function definition must be a parameter list not '%v'", operands[0])
 	}
@@ -177,12 +515,12 @@ func (n *Normalizer) normalizeTypedFunc(fn token.Token, operands ...ast.Expr) (*
 		return nil, fmt.Errorf("invalid paramater list for function %w", err)
 	}
 
-	returnType, ok := operands[1].(ast.TypeExpr)
-	if !ok {
+	returnType := normalizeType(operands[1])
+	if returnType == nil {
 		return nil, fmt.Errorf("second argument to a function definition must be a return type '%v'", operands[1])
 	}
 
-	body := operands[2]
+	body := n.Normalize(operands[2])
 
 	return &ast.Func{
 		Tok: fn,
@@ -194,6 +532,98 @@ func (n *Normalizer) normalizeTypedFunc(fn token.Token, operands ...ast.Expr) (*
 	}, nil
 }
 
+// normalizeType normalizes type expressions, slice types are written as [type] or as [_] if
+// the element type should be infered. It returns nil if the expression is not a type.
+func normalizeType(expr ast.Expr) ast.TypeExpr {
+	if typeExpr, ok := expr.(ast.TypeExpr); ok {
+		return typeExpr
+	}
+
+	sexpr, ok := expr.(*ast.SExpr)
+	if !ok || len(sexpr.Operands) == 0 {
+		return nil
+	}
+	square, ok := sexpr.Operator.(*ast.SSquare)
+	if !ok {
+		return nil
+	}
+
+	if ident, ok := sexpr.Operands[0].(*ast.Identifier); ok && ident.Name == "_" && len(sexpr.Operands) == 1 {
+		return &ast.SliceType{Tok: square.Tok}
+	}
+
+	elem, size, err := normalizeTypeAt(sexpr.Operands)
+	if err != nil || elem == nil || size != len(sexpr.Operands) {
+		return nil
+	}
+
+	return &ast.SliceType{Tok: square.Tok, Elem: elem}
+}
+
+// normalizeTypeAt normalizes the type at the start of exprs and returns the number of exprs it used.
+// Tuple and dict types span several exprs since they are written between angle brackets
+// (e.g. <int str> or <.name str .age int>). It returns a nil type if exprs does not start with a type.
+func normalizeTypeAt(exprs []ast.Expr) (ast.TypeExpr, int, error) {
+	if len(exprs) == 0 {
+		return nil, 0, nil
+	}
+
+	if !isAngle(exprs[0], "<") {
+		return normalizeType(exprs[0]), 1, nil
+	}
+
+	items := []ast.TypeExpr{}
+	names := []*ast.Property{}
+	for i := 1; i < len(exprs); {
+		if isAngle(exprs[i], ">") {
+			if len(names) == 0 {
+				return &ast.TupleType{Types: items}, i + 1, nil
+			}
+			if len(names) != len(items) {
+				return nil, 0, fmt.Errorf("dict types must be in the form <.name type ...>")
+			}
+
+			dictType := &ast.DictType{}
+			for j, name := range names {
+				dictType.Fields = append(dictType.Fields, ast.FieldType{Name: name.Name, Type: items[j]})
+			}
+			return dictType, i + 1, nil
+		}
+
+		// dict types alternate between property names and types
+		if property, ok := exprs[i].(*ast.Property); ok {
+			if len(names) != len(items) {
+				return nil, 0, fmt.Errorf("dict types must be in the form <.name type ...>")
+			}
+			names = append(names, property)
+			i++
+			continue
+		}
+		if len(names) > 0 && len(names) != len(items)+1 {
+			return nil, 0, fmt.Errorf("dict types must be in the form <.name type ...>")
+		}
+
+		item, size, err := normalizeTypeAt(exprs[i:])
+		if err != nil {
+			return nil, 0, err
+		}
+		if item == nil {
+			return nil, 0, fmt.Errorf("invalid type '%v' in angle brackets", exprs[i])
+		}
+
+		items = append(items, item)
+		i += size
+	}
+
+	return nil, 0, fmt.Errorf("unclosed type, expected a closing '>'")
+}
+
+// isAngle reports whether expr is the angle bracket that opens or closes tuple and dict types
+func isAngle(expr ast.Expr, angle string) bool {
+	ident, ok := expr.(*ast.Identifier)
+	return ok && ident.Name == angle
+}
+
 // normalizeParamList normalizes a paramater list in the form [ident type ...] or [ident ...]
 func normalizeParamList(exprs []ast.Expr) (*ast.ParamList, error) {
 	if len(exprs) == 0 {
@@ -202,29 +632,34 @@ func normalizeParamList(exprs []ast.Expr) (*ast.ParamList, error) {
 
 	identifiers := []*ast.Identifier{}
 	types := []ast.TypeExpr{}
-	for _, expr := range exprs {
-		if param, ok := expr.(*ast.Identifier); ok {
+	for i := 0; i < len(exprs); {
+		expr := exprs[i]
+		if param, ok := expr.(*ast.Identifier); ok && !isAngle(param, "<") {
 			identifiers = append(identifiers, param)
+			i++
 			continue
 		}
 
 		// this allows for the syntatic shorthand [a, b, c int] where 'a' 'b' and 'c'
 		// are all typed as integers
-		if typeExpr, ok := expr.(ast.TypeExpr); ok {
+		typeExpr, size, err := normalizeTypeAt(exprs[i:])
+		if err != nil {
+			return nil, err
+		}
+		if typeExpr != nil {
 			for len(types) < len(identifiers) {
 				types = append(types, typeExpr)
 			}
+			i += size
 			continue
 		}
 
 		return nil, fmt.Errorf("invalid expression in param list '%v'", expr)
 	}
 
-	// if there are no types in the paramater list assume all the types must be infered
-	if len(types) == 0 {
-		for len(types) < len(identifiers) {
-			types = append(types, &ast.TraitType{})
-		}
+	// any trailing paramaters without a type must be infered
+	for len(types) < len(identifiers) {
+		types = append(types, &ast.TraitType{})
 	}
 
 	paramList := &ast.ParamList{}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"bytes"
+	"unicode/utf8"
+
 	"github.com/bjatkin/nook/script/token"
 )
 
@@ -9,10 +12,15 @@ var matchers = []matcher{
 	matchDoubleChar,
 	matchFloat,
 	matchInt,
+	matchDuration,
+	matchSize,
 	matchAtom,
+	matchProperty,
 	matchLongPath,
 	matchFlag,
 	matchString,
+	matchRegex,
+	matchHeredoc,
 	matchComment,
 	matchCommand,
 	matchIdentifier,
@@ -46,19 +54,27 @@ func NewVerboseLexer(source []byte) Lexer {
 
 func (l *Lexer) Lex() []token.Token {
 	tokens := []token.Token{}
-	tok := l.next()
-	for tok.Kind != token.EOF {
-		switch {
-		case l.includeIgnoredTokens:
-			tokens = append(tokens, tok)
-		case tok.Kind != token.Whitespace && tok.Kind != token.Comment:
-			tokens = append(tokens, tok)
-		}
+	for tok := l.Next(); tok.Kind != token.EOF; tok = l.Next() {
+		tokens = append(tokens, tok)
+	}
 
+	return tokens
+}
+
+// Next lexes a single token so callers can stop lexing part way through the source,
+// an EOF token is returned once the whole source has been lexed
+func (l *Lexer) Next() token.Token {
+	tok := l.next()
+	for !l.includeIgnoredTokens && (tok.Kind == token.Whitespace || tok.Kind == token.Comment) {
 		tok = l.next()
 	}
 
-	return tokens
+	return tok
+}
+
+// line returns the line number of a position in the source, starting at 1
+func (l *Lexer) line(pos uint) int {
+	return bytes.Count(l.source[:pos], []byte{'\n'}) + 1
 }
 
 func (l *Lexer) next() token.Token {
@@ -69,6 +85,11 @@ func (l *Lexer) next() token.Token {
 		}
 	}
 
+	// bytes that are not valid utf-8 are never part of a token
+	if r, size := utf8.DecodeRune(l.source[l.pos:]); r == utf8.RuneError && size <= 1 {
+		return l.emit(&match{len: 1, kind: token.Invalid})
+	}
+
 	var bestMatch *match
 	for _, match := range matchers {
 		found := match(l.source[l.pos:])
@@ -87,11 +108,24 @@ func (l *Lexer) next() token.Token {
 		bestMatch = matchUnknownToken(l.source[l.pos:])
 	}
 
+	return l.emit(bestMatch)
+}
+
+// emit creates a token from a match and moves the lexer past it. Tokens that contain
+// invalid utf-8 (e.g. a string with a stray byte in it) are always invalid.
+func (l *Lexer) emit(found *match) token.Token {
 	start := l.pos
-	l.pos += bestMatch.len
+	value := l.source[start : start+found.len]
+
+	kind := found.kind
+	if !utf8.Valid(value) {
+		kind = token.Invalid
+	}
+
+	l.pos += found.len
 	return token.Token{
 		Pos:   start,
-		Value: string(l.source[start:l.pos]),
-		Kind:  bestMatch.kind,
+		Value: string(value),
+		Kind:  kind,
 	}
 }
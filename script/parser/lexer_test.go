@@ -34,6 +34,73 @@ func Test_lexer_lex(t *testing.T) {
 				{Pos: 8, Value: ")", Kind: token.CloseParen},
 			},
 		},
+		{
+			name: "comparison operators",
+			fields: fields{
+				source: []byte("< <= > >= == !="),
+			},
+			want: []token.Token{
+				{Pos: 0, Value: "<", Kind: token.LessThan},
+				{Pos: 2, Value: "<=", Kind: token.LessEqual},
+				{Pos: 5, Value: ">", Kind: token.GreaterThan},
+				{Pos: 7, Value: ">=", Kind: token.GreaterEqual},
+				{Pos: 10, Value: "==", Kind: token.Equal},
+				{Pos: 13, Value: "!=", Kind: token.NotEqual},
+			},
+		},
+		{
+			name: "regex and comment",
+			fields: fields{
+				source: []byte("(regex.match s #/a b/) # #/x/"),
+			},
+			want: []token.Token{
+				{Pos: 0, Value: "(", Kind: token.OpenParen},
+				{Pos: 1, Value: "regex.match", Kind: token.Identifier},
+				{Pos: 13, Value: "s", Kind: token.Identifier},
+				{Pos: 15, Value: "#/a b/", Kind: token.Regex},
+				{Pos: 21, Value: ")", Kind: token.CloseParen},
+			},
+		},
+		{
+			name: "dict literal",
+			fields: fields{
+				source: []byte("{.title ./x .. .}"),
+			},
+			want: []token.Token{
+				{Pos: 0, Value: "{", Kind: token.OpenCurly},
+				{Pos: 1, Value: ".title", Kind: token.Property},
+				{Pos: 8, Value: "./x", Kind: token.Path},
+				{Pos: 12, Value: "..", Kind: token.Path},
+				{Pos: 15, Value: ".", Kind: token.Path},
+				{Pos: 16, Value: "}", Kind: token.CloseCurly},
+			},
+		},
+		{
+			name: "unicode positions",
+			fields: fields{
+				source: []byte(`(let café "☕")`),
+			},
+			want: []token.Token{
+				{Pos: 0, Value: "(", Kind: token.OpenParen},
+				{Pos: 1, Value: "let", Kind: token.Let},
+				{Pos: 5, Value: "café", Kind: token.Identifier},
+				{Pos: 11, Value: `"☕"`, Kind: token.String},
+				{Pos: 16, Value: ")", Kind: token.CloseParen},
+			},
+		},
+		{
+			name: "invalid utf-8",
+			fields: fields{
+				source: []byte("(a \xff \"b\xc3\")"),
+			},
+			want: []token.Token{
+				{Pos: 0, Value: "(", Kind: token.OpenParen},
+				{Pos: 1, Value: "a", Kind: token.Identifier},
+				{Pos: 3, Value: "\xff", Kind: token.Invalid},
+				{Pos: 5, Value: "\"b\xc3\"", Kind: token.Invalid},
+				{Pos: 9, Value: ")", Kind: token.CloseParen},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
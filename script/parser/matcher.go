@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/bjatkin/nook/script/token"
 )
 
@@ -44,6 +48,23 @@ func isAlpha(char byte) bool {
 	return false
 }
 
+// isLetter reports whether a rune can start an identifier, this includes unicode letters (e.g. é or 日)
+func isLetter(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentifierRune reports whether a rune can continue an identifier. Combining marks are
+// included so decomposed characters (e.g. e followed by U+0301) stay part of the identifier.
+func isIdentifierRune(r rune) bool {
+	return isLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc)
+}
+
+// startsIdentifier reports whether the bytes start with a rune that can start an identifier
+func startsIdentifier(bytes []byte) bool {
+	r, _ := utf8.DecodeRune(bytes)
+	return len(bytes) > 0 && isLetter(r)
+}
+
 type match struct {
 	len  uint
 	kind token.Kind
@@ -105,9 +126,11 @@ func matchDoubleChar(bytes []byte) *match {
 	case ">=":
 		return &match{len: 2, kind: token.GreaterEqual}
 	case "<=":
-		return &match{len: 2, kind: token.LessThan}
+		return &match{len: 2, kind: token.LessEqual}
 	case "==":
 		return &match{len: 2, kind: token.Equal}
+	case "!=":
+		return &match{len: 2, kind: token.NotEqual}
 	case "./":
 		return &match{len: 2, kind: token.Path}
 	case "..":
@@ -117,30 +140,94 @@ func matchDoubleChar(bytes []byte) *match {
 	}
 }
 
-// matchLongPath matches only paths that start with either '/' or './' or '../'
+// durationUnits are the units that can be used in duration literals
+var durationUnits = []string{"ns", "us", "ms", "s", "m", "h"}
+
+// matchDuration matches duration literals like 5s, 1.5h or 1h30m
+func matchDuration(bytes []byte) *match {
+	i := uint(0)
+	if len(bytes) > 0 && bytes[0] == '-' {
+		i++
+	}
+
+	// durations can be made of more than one part (e.g. 1h30m)
+	parts := 0
+	for {
+		length, unit := matchQuantity(bytes[i:], durationUnits)
+		if unit == "" {
+			break
+		}
+		i += length
+		parts++
+	}
+
+	if parts == 0 || endsIdentifier(bytes[i:]) {
+		return nil
+	}
+
+	return &match{len: i, kind: token.Duration}
+}
+
+// matchSize matches size literals like 10KB or 1.5GiB
+func matchSize(bytes []byte) *match {
+	length, unit := matchQuantity(bytes, token.SizeUnitNames)
+	if unit == "" || endsIdentifier(bytes[length:]) {
+		return nil
+	}
+
+	return &match{len: uint(length), kind: token.Size}
+}
+
+// matchQuantity matches a decimal number followed by the longest of the units
+func matchQuantity(bytes []byte, units []string) (uint, string) {
+	number, ok := matchDecimal(bytes)
+	if !ok || bytes[0] == '_' {
+		return 0, ""
+	}
+	if int(number) < len(bytes)-1 && bytes[number] == '.' && isDecimal(bytes[number+1]) {
+		fraction, _ := matchDecimal(bytes[number+1:])
+		number += fraction + 1
+	}
+
+	found := ""
+	for _, unit := range units {
+		if strings.HasPrefix(string(bytes[number:]), unit) && len(unit) > len(found) {
+			found = unit
+		}
+	}
+
+	return number + uint(len(found)), found
+}
+
+// endsIdentifier reports whether the bytes after a literal would continue it as an identifier (e.g. 5sec)
+func endsIdentifier(bytes []byte) bool {
+	r, _ := utf8.DecodeRune(bytes)
+	return len(bytes) > 0 && isIdentifierRune(r)
+}
+
+// matchLongPath matches only paths that start with either '/', './', '../' or '~/'
 func matchLongPath(bytes []byte) *match {
 	if !matchPathPrefix(bytes) {
 		return nil
 	}
 
 	// TODO: this needs to be WAAAAAYYY more robust, I'm missing a ton of valid paths here
-	for i, char := range bytes {
-		if char == '.' {
+	for i, size := 0, 0; i < len(bytes); i += size {
+		var char rune
+		char, size = utf8.DecodeRune(bytes[i:])
+		if char == '~' && i == 0 {
 			continue
 		}
-		if char == '/' {
+		if char == '.' {
 			continue
 		}
-		if char == '_' {
+		if char == '/' {
 			continue
 		}
 		if char == '-' {
 			continue
 		}
-		if isAlpha(char) {
-			continue
-		}
-		if isDecimal(char) {
+		if isIdentifierRune(char) {
 			continue
 		}
 
@@ -175,6 +262,13 @@ func matchPathPrefix(bytes []byte) bool {
 		return true
 	}
 
+	// paths in the home directory
+	if len(bytes) >= 2 &&
+		bytes[0] == '~' &&
+		bytes[1] == '/' {
+		return true
+	}
+
 	return false
 }
 
@@ -208,43 +302,88 @@ func matchFlag(bytes []byte) *match {
 	return &match{len: uint(len(bytes)), kind: token.Flag}
 }
 
+// matchString matches strings in double quotes, which can contain escapes and interpolated
+// expressions (e.g. "hello ${name}\n"), and raw strings in backticks. Both can span many lines.
 func matchString(bytes []byte) *match {
-	if bytes[0] != '"' {
+	if len(bytes) == 0 {
 		return nil
 	}
 
-	escape := false
-	for i, char := range bytes[1:] {
-		if char == '\\' {
-			escape = true
-			continue
-		}
-		if char == '"' && !escape {
-			return &match{len: uint(i + 2), kind: token.String}
-		}
-		if char == '\n' {
-			break
+	switch bytes[0] {
+	case '`':
+		for i, char := range bytes[1:] {
+			if char == '`' {
+				return &match{len: uint(i + 2), kind: token.String}
+			}
 		}
+		return nil
+	case '"':
+	default:
+		return nil
+	}
 
-		escape = false
+	for i := 1; i < len(bytes); i++ {
+		switch {
+		case bytes[i] == '\\':
+			// skip the escaped character
+			i++
+		case bytes[i] == '"':
+			return &match{len: uint(i + 1), kind: token.String}
+		case bytes[i] == '$' && i+1 < len(bytes) && bytes[i+1] == '{':
+			length, ok := matchInterpolation(bytes[i+2:])
+			if !ok {
+				return nil
+			}
+			i += int(length) + 1
+		}
 	}
 
 	return nil
 }
 
+// matchInterpolation matches the expression inside of '${' and '}' in a string. It returns the
+// length up to and including the closing '}', strings inside the expression are skipped over.
+func matchInterpolation(bytes []byte) (uint, bool) {
+	depth := 1
+	for i := 0; i < len(bytes); i++ {
+		switch bytes[i] {
+		case '"', '`':
+			found := matchString(bytes[i:])
+			if found == nil {
+				return 0, false
+			}
+			i += int(found.len) - 1
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return uint(i + 1), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// matchIdentifier matches identifiers as well as qualified identifiers (e.g. vec.add)
+// where each segment of the name is separated by a '.'
 func matchIdentifier(bytes []byte) *match {
 	if len(bytes) == 0 {
 		return nil
 	}
 
-	for i, char := range bytes {
-		if isAlpha(char) {
+	for i, size := 0, 0; i < len(bytes); i += size {
+		var char rune
+		char, size = utf8.DecodeRune(bytes[i:])
+		if isLetter(char) {
 			continue
 		}
-		if i > 0 && isDecimal(char) {
+		if i > 0 && isIdentifierRune(char) {
 			continue
 		}
-		if i > 0 && char == '_' {
+		// a '.' is only part of the identifier if it separates two segments of the name
+		if i > 0 && char == '.' && bytes[i-1] != '.' && startsIdentifier(bytes[i+1:]) {
 			continue
 		}
 		if i > 0 {
@@ -264,6 +403,11 @@ func matchComment(bytes []byte) *match {
 		return nil
 	}
 
+	// regex literals also start with a '#'
+	if matchRegex(bytes) != nil {
+		return nil
+	}
+
 	for i, char := range bytes {
 		if char == '\n' {
 			return &match{len: uint(i), kind: token.Comment}
@@ -273,6 +417,136 @@ func matchComment(bytes []byte) *match {
 	return &match{len: uint(len(bytes)), kind: token.Comment}
 }
 
+// matchRegex matches regex literals in the form #/pattern/, a '/' in the pattern is escaped as '\/'
+func matchRegex(bytes []byte) *match {
+	if len(bytes) < 2 || bytes[0] != '#' || bytes[1] != '/' {
+		return nil
+	}
+
+	escape := false
+	for i, char := range bytes[2:] {
+		if char == '\n' {
+			break
+		}
+		if char == '/' && !escape {
+			return &match{len: uint(i + 3), kind: token.Regex}
+		}
+
+		escape = char == '\\' && !escape
+	}
+
+	return nil
+}
+
+// matchHeredoc matches heredoc literals that start with <<TAG at the end of a line and run until the
+// next line that only holds the TAG. Heredocs that are missing the closing TAG match the rest of the
+// source so the parser can report the missing TAG rather than lexing the body as code.
+func matchHeredoc(bytes []byte) *match {
+	tag, start, ok := heredocOpen(bytes)
+	if !ok {
+		return nil
+	}
+
+	_, end, _ := heredocClose(bytes, tag, start)
+	return &match{len: uint(end), kind: token.Heredoc}
+}
+
+// heredocOpen matches the <<TAG that opens a heredoc, it returns the TAG and the start of the body
+func heredocOpen(bytes []byte) (string, int, bool) {
+	if len(bytes) < 3 || bytes[0] != '<' || bytes[1] != '<' || !isAlpha(bytes[2]) {
+		return "", 0, false
+	}
+
+	i := 3
+	for endsIdentifier(bytes[i:]) {
+		i++
+	}
+	tag := string(bytes[2:i])
+
+	for i < len(bytes) && (bytes[i] == ' ' || bytes[i] == '\t' || bytes[i] == '\r') {
+		i++
+	}
+	if i >= len(bytes) || bytes[i] != '\n' {
+		return "", 0, false
+	}
+
+	return tag, i + 1, true
+}
+
+// heredocClose finds the line that closes a heredoc, the line may be indented and the TAG may be followed by
+// closing brackets (e.g. "  EOF)"). It returns the end of the body and the end of the closing TAG.
+func heredocClose(bytes []byte, tag string, start int) (int, int, bool) {
+	line := start
+	for line < len(bytes) {
+		i := line
+		for i < len(bytes) && (bytes[i] == ' ' || bytes[i] == '\t') {
+			i++
+		}
+
+		end := i + len(tag)
+		if end <= len(bytes) && string(bytes[i:end]) == tag && onlyClosers(bytes[end:]) {
+			return line, end, true
+		}
+
+		for line < len(bytes) && bytes[line] != '\n' {
+			line++
+		}
+		line++
+	}
+
+	return len(bytes), len(bytes), false
+}
+
+// onlyClosers reports whether the rest of the line only has whitespace and closing brackets,
+// so a body line that starts with the TAG (e.g. "EOF is the tag") does not close the heredoc
+func onlyClosers(bytes []byte) bool {
+	for _, b := range bytes {
+		switch b {
+		case '\n':
+			return true
+		case ' ', '\t', '\r', ')', '}', ']':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsUnclosedHeredoc reports whether a token is a heredoc that is missing its closing TAG
+func IsUnclosedHeredoc(tok token.Token) bool {
+	if tok.Kind != token.Heredoc {
+		return false
+	}
+
+	source := []byte(tok.Value)
+	tag, start, _ := heredocOpen(source)
+	_, _, ok := heredocClose(source, tag, start)
+	return !ok
+}
+
+// matchProperty matches dict keys in the form .name
+func matchProperty(bytes []byte) *match {
+	if len(bytes) < 2 || bytes[0] != '.' {
+		return nil
+	}
+	if !startsIdentifier(bytes[1:]) {
+		return nil
+	}
+
+	for i, size := 1, 0; i < len(bytes); i += size {
+		var char rune
+		char, size = utf8.DecodeRune(bytes[i:])
+		if isIdentifierRune(char) {
+			continue
+		}
+
+		return &match{len: uint(i), kind: token.Property}
+	}
+
+	return &match{len: uint(len(bytes)), kind: token.Property}
+}
+
 func matchAtom(bytes []byte) *match {
 	if bytes[0] != '\'' {
 		return nil
@@ -282,12 +556,14 @@ func matchAtom(bytes []byte) *match {
 		return nil
 	}
 
-	for i, char := range bytes[1:] {
-		if isAlpha(char) {
+	for i, size := 1, 0; i < len(bytes); i += size {
+		var char rune
+		char, size = utf8.DecodeRune(bytes[i:])
+		if unicode.IsLetter(char) {
 			continue
 		}
-		if i > 0 {
-			return &match{len: uint(i + 1), kind: token.Atom}
+		if i > 1 {
+			return &match{len: uint(i), kind: token.Atom}
 		}
 	}
 
@@ -518,12 +794,48 @@ func identifierKind(value string) token.Kind {
 	switch value {
 	case "let":
 		return token.Let
+	case "fn":
+		return token.Fn
+	case "impl":
+		return token.Impl
+	case "if":
+		return token.If
+	case "else":
+		return token.Else
+	case "match":
+		return token.Match
+	case "do":
+		return token.Do
+	case "ns":
+		return token.Ns
+	case "import":
+		return token.Import
+	case "macro":
+		return token.Macro
+	case "quote":
+		return token.Quote
+	case "quasiquote":
+		return token.Quasiquote
+	case "unquote":
+		return token.Unquote
+	case "splice":
+		return token.Splice
+	case "try":
+		return token.Try
+	case "and":
+		return token.And
+	case "or":
+		return token.Or
 	case "true":
 		return token.Bool
 	case "false":
 		return token.Bool
 	case "int":
 		return token.IntType
+	case "bigint":
+		return token.BigIntType
+	case "number":
+		return token.NumberType
 	case "float":
 		return token.FloatType
 	case "bool":
@@ -534,10 +846,20 @@ func identifierKind(value string) token.Kind {
 		return token.PathType
 	case "flag":
 		return token.FlagType
+	case "regex":
+		return token.RegexType
+	case "duration":
+		return token.DurationType
+	case "time":
+		return token.TimeType
+	case "size":
+		return token.SizeType
 	case "atom":
 		return token.AtomType
 	case "command":
 		return token.CommandType
+	case "error":
+		return token.ErrorType
 	case "none":
 		return token.NoneType
 	case "nil":
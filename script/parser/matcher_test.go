@@ -72,6 +72,22 @@ func Test_matchLongPath(t *testing.T) {
 				kind: token.Path,
 			},
 		},
+		{
+			name: "unicode path",
+			args: args{bytes: []byte("~/文档/résumé.pdf)")},
+			want: &match{
+				len:  21,
+				kind: token.Path,
+			},
+		},
+		{
+			name: "home dir",
+			args: args{bytes: []byte("~/notes.txt")},
+			want: &match{
+				len:  11,
+				kind: token.Path,
+			},
+		},
 		{
 			name: "invalid directory",
 			args: args{bytes: []byte("test/this/path")},
@@ -136,6 +152,36 @@ func Test_matchString(t *testing.T) {
 			args: args{bytes: []byte("\"hello world\"")},
 			want: &match{len: 13, kind: token.String},
 		},
+		{
+			name: "escaped quote",
+			args: args{bytes: []byte(`"a\"b" c`)},
+			want: &match{len: 6, kind: token.String},
+		},
+		{
+			name: "interpolation with a nested string",
+			args: args{bytes: []byte(`"x ${(+ "}" "{")} y" z`)},
+			want: &match{len: 20, kind: token.String},
+		},
+		{
+			name: "raw string",
+			args: args{bytes: []byte("`a\\nb\\` c")},
+			want: &match{len: 7, kind: token.String},
+		},
+		{
+			name: "multi-line string",
+			args: args{bytes: []byte("\"one\ntwo\"")},
+			want: &match{len: 9, kind: token.String},
+		},
+		{
+			name: "unterminated string",
+			args: args{bytes: []byte(`"hello`)},
+			want: nil,
+		},
+		{
+			name: "unterminated interpolation",
+			args: args{bytes: []byte(`"${name"`)},
+			want: nil,
+		},
 		{
 			name: "invalid string",
 			args: args{bytes: []byte("test")},
@@ -152,6 +198,201 @@ func Test_matchString(t *testing.T) {
 	}
 }
 
+func Test_matchHeredoc(t *testing.T) {
+	type args struct {
+		bytes []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want *match
+	}{
+		{
+			name: "heredoc",
+			args: args{bytes: []byte("<<SQL\nselect 1;\nSQL")},
+			want: &match{len: 19, kind: token.Heredoc},
+		},
+		{
+			name: "indented closing tag followed by closing brackets",
+			args: args{bytes: []byte("<<EOF\n    a\n  EOF)} \n(+ 1 2)")},
+			want: &match{len: 17, kind: token.Heredoc},
+		},
+		{
+			name: "body line that starts with the tag",
+			args: args{bytes: []byte("<<EOF\nEOF is the tag\nEOF")},
+			want: &match{len: 24, kind: token.Heredoc},
+		},
+		{
+			name: "closing tag can not be followed by code",
+			args: args{bytes: []byte("<<EOF\na\nEOF) (+ 1 2)")},
+			want: &match{len: 20, kind: token.Heredoc},
+		},
+		{
+			name: "tag inside of a longer word",
+			args: args{bytes: []byte("<<END\nENDING\nEND\n")},
+			want: &match{len: 16, kind: token.Heredoc},
+		},
+		{
+			name: "unclosed heredoc",
+			args: args{bytes: []byte("<<END\nsome text\n")},
+			want: &match{len: 16, kind: token.Heredoc},
+		},
+		{
+			name: "tag must end the line",
+			args: args{bytes: []byte("<<END text\nEND")},
+			want: nil,
+		},
+		{
+			name: "less than",
+			args: args{bytes: []byte("<< 1 2")},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchHeredoc(tt.args.bytes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchHeredoc() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchRegex(t *testing.T) {
+	type args struct {
+		bytes []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want *match
+	}{
+		{
+			name: "valid regex",
+			args: args{bytes: []byte(`#/v\d+/ "a"`)},
+			want: &match{len: 7, kind: token.Regex},
+		},
+		{
+			name: "escaped slash",
+			args: args{bytes: []byte(`#/a\/b/`)},
+			want: &match{len: 7, kind: token.Regex},
+		},
+		{
+			name: "escaped backslash before the end",
+			args: args{bytes: []byte(`#/a\\/b/`)},
+			want: &match{len: 6, kind: token.Regex},
+		},
+		{
+			name: "unterminated regex",
+			args: args{bytes: []byte("#/abc\n/")},
+			want: nil,
+		},
+		{
+			name: "comment",
+			args: args{bytes: []byte("# a comment")},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchRegex(tt.args.bytes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchRegex() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchDuration(t *testing.T) {
+	type args struct {
+		bytes []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want *match
+	}{
+		{
+			name: "seconds",
+			args: args{bytes: []byte("5s)")},
+			want: &match{len: 2, kind: token.Duration},
+		},
+		{
+			name: "milliseconds",
+			args: args{bytes: []byte("250ms")},
+			want: &match{len: 5, kind: token.Duration},
+		},
+		{
+			name: "fraction",
+			args: args{bytes: []byte("1.5h")},
+			want: &match{len: 4, kind: token.Duration},
+		},
+		{
+			name: "more than one part",
+			args: args{bytes: []byte("-1h30m ")},
+			want: &match{len: 6, kind: token.Duration},
+		},
+		{
+			name: "followed by an identifier",
+			args: args{bytes: []byte("5sec")},
+			want: nil,
+		},
+		{
+			name: "int",
+			args: args{bytes: []byte("5 s")},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchDuration(tt.args.bytes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchDuration() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchSize(t *testing.T) {
+	type args struct {
+		bytes []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want *match
+	}{
+		{
+			name: "decimal unit",
+			args: args{bytes: []byte("10KB")},
+			want: &match{len: 4, kind: token.Size},
+		},
+		{
+			name: "binary unit",
+			args: args{bytes: []byte("1.5GiB)")},
+			want: &match{len: 6, kind: token.Size},
+		},
+		{
+			name: "bytes",
+			args: args{bytes: []byte("512B")},
+			want: &match{len: 4, kind: token.Size},
+		},
+		{
+			name: "unknown unit",
+			args: args{bytes: []byte("10kb")},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchSize(tt.args.bytes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchSize() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_lexer_matchIdentifier(t *testing.T) {
 	type args struct {
 		bytes []byte
@@ -380,3 +621,72 @@ func Test_matchFloat(t *testing.T) {
 		})
 	}
 }
+
+func Test_matchIdentifier(t *testing.T) {
+	type args struct {
+		bytes []byte
+	}
+	tests := []struct {
+		name string
+		args args
+		want *match
+	}{
+		{
+			name: "identifier",
+			args: args{bytes: []byte("user_name ")},
+			want: &match{len: 9, kind: token.Identifier},
+		},
+		{
+			name: "unicode identifier",
+			args: args{bytes: []byte("größe_2 ")},
+			want: &match{len: 9, kind: token.Identifier},
+		},
+		{
+			name: "combining mark",
+			args: args{bytes: []byte("cafe\u0301 ")},
+			want: &match{len: 6, kind: token.Identifier},
+		},
+		{
+			name: "qualified unicode identifier",
+			args: args{bytes: []byte("日本.東京)")},
+			want: &match{len: 13, kind: token.Identifier},
+		},
+		{
+			name: "symbols are not letters",
+			args: args{bytes: []byte("→x")},
+			want: nil,
+		},
+		{
+			name: "keyword",
+			args: args{bytes: []byte("ns vec")},
+			want: &match{len: 2, kind: token.Ns},
+		},
+		{
+			name: "qualified identifier",
+			args: args{bytes: []byte("vec.add 1 2")},
+			want: &match{len: 7, kind: token.Identifier},
+		},
+		{
+			name: "nested qualified identifier",
+			args: args{bytes: []byte("geo.vec._add)")},
+			want: &match{len: 12, kind: token.Identifier},
+		},
+		{
+			name: "trailing dot",
+			args: args{bytes: []byte("vec. ")},
+			want: &match{len: 3, kind: token.Identifier},
+		},
+		{
+			name: "double dot",
+			args: args{bytes: []byte("vec..add")},
+			want: &match{len: 3, kind: token.Identifier},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchIdentifier(tt.args.bytes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchIdentifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
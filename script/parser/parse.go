@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/bjatkin/nook/script/ast"
 	"github.com/bjatkin/nook/script/token"
@@ -32,8 +34,31 @@ func (p *Parser) Parse() ast.Expr {
 	return p.parse()
 }
 
+// ParseFile parses every expression in a source file and returns them along with the
+// line each expression starts on. Errors are prefixed with the line they were found on.
+func (p *Parser) ParseFile() ([]ast.Expr, []int) {
+	p.tokens = p.lexer.Lex()
+
+	exprs := []ast.Expr{}
+	lines := []int{}
+	for p.peek().Kind != token.EOF {
+		line := p.lexer.line(p.peek().Pos)
+		errs := len(p.Errors)
+
+		expr := p.parse()
+		for i := errs; i < len(p.Errors); i++ {
+			p.Errors[i] = fmt.Errorf("%d: %w", line, p.Errors[i])
+		}
+
+		exprs = append(exprs, expr)
+		lines = append(lines, line)
+	}
+
+	return exprs, lines
+}
+
 func (p *Parser) peek() token.Token {
-	if p.nextToken > uint(len(p.tokens)) {
+	if p.nextToken >= uint(len(p.tokens)) {
 		return token.Token{Kind: token.EOF}
 	}
 
@@ -94,9 +119,10 @@ func (p *Parser) parse() ast.Expr {
 
 	case token.String:
 		tok := p.take()
-		value := tok.Value
-		value = value[1 : len(value)-1]
-		return &ast.String{Tok: tok, Value: value}
+		return p.parseString(tok)
+	case token.Heredoc:
+		tok := p.take()
+		return p.parseHeredoc(tok)
 
 	case token.Int:
 		tok := p.take()
@@ -155,18 +181,150 @@ func (p *Parser) parse() ast.Expr {
 	case token.Flag:
 		tok := p.take()
 		return &ast.Flag{Tok: tok, Value: tok.Value}
+	case token.Duration:
+		tok := p.take()
+		value, err := time.ParseDuration(strings.ReplaceAll(tok.Value, "_", ""))
+		if err != nil {
+			p.addError(fmt.Errorf("invalid duration '%s'", tok.Value))
+			return nil
+		}
+
+		return &ast.Duration{Tok: tok, Value: value}
+	case token.Size:
+		tok := p.take()
+		value, err := token.ParseSize(tok.Value)
+		if err != nil {
+			p.addError(err)
+			return nil
+		}
+
+		return &ast.Size{Tok: tok, Value: value}
+	case token.Regex:
+		tok := p.take()
+		value := tok.Value[2 : len(tok.Value)-1]
+		value = strings.ReplaceAll(value, `\/`, "/")
+		return &ast.Regex{Tok: tok, Value: value}
 	case token.Path:
 		tok := p.take()
 		return &ast.Path{Tok: tok, Value: tok.Value}
 	case token.Identifier:
 		tok := p.take()
 		return &ast.Identifier{Tok: tok, Name: tok.Value}
-	case token.Plus, token.Minus, token.Multiply, token.Divide:
+	case token.Plus, token.Minus, token.Multiply, token.Divide,
+		token.GreaterThan, token.LessThan, token.GreaterEqual, token.LessEqual, token.Equal, token.NotEqual:
 		tok := p.take()
 		return &ast.Identifier{Tok: tok, Name: tok.Value}
+	case token.Property:
+		tok := p.take()
+		return &ast.Property{Tok: tok, Name: tok.Value[1:]}
 	case token.Let:
 		tok := p.take()
 		return &ast.SLet{Tok: tok}
+	case token.Fn:
+		tok := p.take()
+		return &ast.SFunc{Tok: tok}
+	case token.Impl:
+		tok := p.take()
+		return &ast.SImpl{Tok: tok}
+	case token.If:
+		tok := p.take()
+		return &ast.SIf{Tok: tok}
+	case token.Else:
+		tok := p.take()
+		return &ast.SElse{Tok: tok}
+	case token.Match:
+		tok := p.take()
+		return &ast.SMatch{Tok: tok}
+	case token.Do:
+		tok := p.take()
+		return &ast.SDo{Tok: tok}
+	case token.Ns:
+		tok := p.take()
+		return &ast.SNamespace{Tok: tok}
+	case token.Import:
+		tok := p.take()
+		return &ast.SImport{Tok: tok}
+	case token.Macro:
+		tok := p.take()
+		return &ast.SMacro{Tok: tok}
+	case token.Quote:
+		tok := p.take()
+		return &ast.SQuote{Tok: tok}
+	case token.Quasiquote:
+		tok := p.take()
+		return &ast.SQuasiquote{Tok: tok}
+	case token.Unquote:
+		tok := p.take()
+		return &ast.SUnquote{Tok: tok}
+	case token.Splice:
+		tok := p.take()
+		return &ast.SSplice{Tok: tok}
+	case token.Try:
+		tok := p.take()
+		return &ast.STry{Tok: tok}
+	case token.And:
+		tok := p.take()
+		return &ast.SAnd{Tok: tok}
+	case token.Or:
+		tok := p.take()
+		return &ast.SOr{Tok: tok}
+	case token.IntType:
+		tok := p.take()
+		return &ast.IntType{Tok: tok}
+	case token.BigIntType:
+		tok := p.take()
+		return &ast.BigIntType{Tok: tok}
+	case token.NumberType:
+		tok := p.take()
+		return &ast.NumberType{Tok: tok}
+	case token.FloatType:
+		tok := p.take()
+		return &ast.FloatType{Tok: tok}
+	case token.BoolType:
+		tok := p.take()
+		return &ast.BoolType{Tok: tok}
+	case token.StringType:
+		tok := p.take()
+		return &ast.StringType{Tok: tok}
+	case token.PathType:
+		tok := p.take()
+		return &ast.PathType{Tok: tok}
+	case token.FlagType:
+		tok := p.take()
+		return &ast.FlagType{Tok: tok}
+	case token.RegexType:
+		tok := p.take()
+		return &ast.RegexType{Tok: tok}
+	case token.DurationType:
+		tok := p.take()
+		return &ast.DurationType{Tok: tok}
+	case token.TimeType:
+		tok := p.take()
+		return &ast.TimeType{Tok: tok}
+	case token.SizeType:
+		tok := p.take()
+		return &ast.SizeType{Tok: tok}
+	case token.AtomType:
+		tok := p.take()
+		return &ast.AtomType{Tok: tok}
+	case token.CommandType:
+		tok := p.take()
+		return &ast.CommandType{Tok: tok}
+	case token.NoneType:
+		tok := p.take()
+		return &ast.NoneType{Tok: tok}
+	case token.ErrorType:
+		tok := p.take()
+		return &ast.ErrorType{Tok: tok}
+	case token.Invalid:
+		tok := p.take()
+		if !utf8.ValidString(tok.Value) {
+			p.addError(fmt.Errorf("invalid utf-8 in %q", tok.Value))
+			return nil
+		}
+
+		p.addError(fmt.Errorf("invalid token '%s'", tok.Value))
+		return nil
 	default:
 		p.addError(fmt.Errorf("unsupported expression '%#v'", p.take()))
 		return nil
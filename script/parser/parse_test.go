@@ -67,6 +67,64 @@ func TestParser_Parse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:   "comparison",
+			fields: fields{lexer: newLexer([]byte("(<= 1 2)"))},
+			want: &ast.SExpr{
+				Operator: &ast.Identifier{Tok: token.Token{Pos: 1, Value: "<=", Kind: token.LessEqual}, Name: "<="},
+				Operands: []ast.Expr{
+					&ast.Int{Tok: token.Token{Pos: 4, Value: "1", Kind: token.Int}, Value: 1},
+					&ast.Int{Tok: token.Token{Pos: 6, Value: "2", Kind: token.Int}, Value: 2},
+				},
+			},
+		},
+		{
+			name:   "dict",
+			fields: fields{lexer: newLexer([]byte("{.year 1965}"))},
+			want: &ast.SExpr{
+				Operator: &ast.SCurly{Tok: token.Token{Pos: 0, Value: "{", Kind: token.OpenCurly}},
+				Operands: []ast.Expr{
+					&ast.Property{Tok: token.Token{Pos: 1, Value: ".year", Kind: token.Property}, Name: "year"},
+					&ast.Int{Tok: token.Token{Pos: 7, Value: "1965", Kind: token.Int}, Value: 1965},
+				},
+			},
+		},
+		{
+			name:   "string escapes",
+			fields: fields{lexer: newLexer([]byte(`"a\tb\n\"\u{e9}\$"`))},
+			want: &ast.String{
+				Tok:   token.Token{Pos: 0, Value: `"a\tb\n\"\u{e9}\$"`, Kind: token.String},
+				Value: "a\tb\n\"\u00e9$",
+			},
+		},
+		{
+			name:   "raw string",
+			fields: fields{lexer: newLexer([]byte("`a\\n${b}`"))},
+			want: &ast.String{
+				Tok:   token.Token{Pos: 0, Value: "`a\\n${b}`", Kind: token.String},
+				Value: "a\\n${b}",
+			},
+		},
+		{
+			name:   "heredoc",
+			fields: fields{lexer: newLexer([]byte("<<SQL\n    select *\n\n      from t;\n    SQL"))},
+			want: &ast.String{
+				Tok:   token.Token{Pos: 0, Value: "<<SQL\n    select *\n\n      from t;\n    SQL", Kind: token.Heredoc},
+				Value: "select *\n\n  from t;\n",
+			},
+		},
+		{
+			name:   "interpolation",
+			fields: fields{lexer: newLexer([]byte(`"hi ${name}!"`))},
+			want: &ast.Interpolation{
+				Tok: token.Token{Pos: 0, Value: `"hi ${name}!"`, Kind: token.String},
+				Parts: []ast.Expr{
+					&ast.String{Tok: token.Token{Pos: 0, Value: `"hi ${name}!"`, Kind: token.String}, Value: "hi "},
+					&ast.Identifier{Tok: token.Token{Pos: 6, Value: "name", Kind: token.Identifier}, Name: "name"},
+					&ast.String{Tok: token.Token{Pos: 0, Value: `"hi ${name}!"`, Kind: token.String}, Value: "!"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,3 +139,77 @@ func TestParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_ParseFile(t *testing.T) {
+	type fields struct {
+		lexer Lexer
+	}
+	tests := []struct {
+		name      string
+		fields    fields
+		wantLines []int
+		wantErrs  []string
+	}{
+		{
+			name:      "multiple expressions",
+			fields:    fields{lexer: newLexer([]byte("(let a 1)\n\n# comment\n(let b 2)\n"))},
+			wantLines: []int{1, 4},
+		},
+		{
+			name:      "errors report the line",
+			fields:    fields{lexer: newLexer([]byte("(let a 1)\n(let b ())"))},
+			wantLines: []int{1, 2},
+			wantErrs:  []string{"2: () is not a valid s-expression"},
+		},
+		{
+			name:      "multi-line strings",
+			fields:    fields{lexer: newLexer([]byte("(let a \"one\ntwo\")\n(let b 2)"))},
+			wantLines: []int{1, 3},
+		},
+		{
+			name:      "invalid escape",
+			fields:    fields{lexer: newLexer([]byte("(let a 1)\n(let b \"\\q\")"))},
+			wantLines: []int{1, 2},
+			wantErrs:  []string{"2: invalid escape sequence '\\q'"},
+		},
+		{
+			name:      "invalid utf-8",
+			fields:    fields{lexer: newLexer([]byte("(let a \"\xff\")"))},
+			wantLines: []int{1},
+			wantErrs:  []string{`1: invalid utf-8 in "\"\xff\""`},
+		},
+		{
+			name:      "unclosed heredoc",
+			fields:    fields{lexer: newLexer([]byte("(let a 1)\n<<EOF\ntext"))},
+			wantLines: []int{1, 2},
+			wantErrs:  []string{"2: heredoc is missing the closing 'EOF'"},
+		},
+		{
+			name:      "invalid interpolation",
+			fields:    fields{lexer: newLexer([]byte(`(let a "${}")`))},
+			wantLines: []int{1},
+			wantErrs:  []string{"1: interpolation '${}' must contain an expression"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{
+				lexer: tt.fields.lexer,
+			}
+
+			_, lines := p.ParseFile()
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("Parser.ParseFile() lines = %v, want %v", lines, tt.wantLines)
+			}
+
+			var errs []string
+			for _, err := range p.Errors {
+				errs = append(errs, err.Error())
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("Parser.ParseFile() errors = %v, want %v", errs, tt.wantErrs)
+			}
+		})
+	}
+}
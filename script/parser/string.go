@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/token"
+)
+
+// parseString decodes the escapes in a string literal and parses any interpolated expressions.
+// Raw strings are used exactly as they are written.
+func (p *Parser) parseString(tok token.Token) ast.Expr {
+	content := tok.Value[1 : len(tok.Value)-1]
+	if tok.Value[0] == '`' {
+		return &ast.String{Tok: tok, Value: content}
+	}
+
+	parts := []ast.Expr{}
+	builder := strings.Builder{}
+	for i := 0; i < len(content); i++ {
+		switch {
+		case content[i] == '\\':
+			decoded, length, err := decodeEscape(content[i:])
+			if err != nil {
+				p.addError(err)
+				return nil
+			}
+			builder.WriteString(decoded)
+			i += length - 1
+		case content[i] == '$' && i+1 < len(content) && content[i+1] == '{':
+			length, _ := matchInterpolation([]byte(content[i+2:]))
+			start := tok.Pos + 1 + uint(i) + 2
+			source := content[i+2 : i+2+int(length)-1]
+
+			expr := p.parseInterpolation(source, start)
+			if expr == nil {
+				return nil
+			}
+
+			if builder.Len() > 0 {
+				parts = append(parts, &ast.String{Tok: tok, Value: builder.String()})
+				builder.Reset()
+			}
+			parts = append(parts, expr)
+			i += int(length) + 1
+		default:
+			builder.WriteByte(content[i])
+		}
+	}
+
+	if len(parts) == 0 {
+		return &ast.String{Tok: tok, Value: builder.String()}
+	}
+	if builder.Len() > 0 {
+		parts = append(parts, &ast.String{Tok: tok, Value: builder.String()})
+	}
+
+	return &ast.Interpolation{Tok: tok, Parts: parts}
+}
+
+// parseHeredoc parses a heredoc into a string, the indentation shared by every line is removed
+// and each line ends with a newline, the same way it would if it were read from a file
+func (p *Parser) parseHeredoc(tok token.Token) ast.Expr {
+	source := []byte(tok.Value)
+	tag, start, _ := heredocOpen(source)
+	end, _, ok := heredocClose(source, tag, start)
+	if !ok {
+		p.addError(fmt.Errorf("heredoc is missing the closing '%s'", tag))
+		return nil
+	}
+
+	return &ast.String{Tok: tok, Value: dedent(tok.Value[start:end])}
+}
+
+// dedent removes the leading whitespace that is shared by every line that is not blank
+func dedent(body string) string {
+	if body == "" {
+		return ""
+	}
+
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	indent := ""
+	found := false
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		lines[i] = line
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = lead, true
+			continue
+		}
+
+		// the shared indent is the longest prefix of both indents
+		n := 0
+		for n < len(indent) && n < len(lead) && indent[n] == lead[n] {
+			n++
+		}
+		indent = indent[:n]
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// parseInterpolation parses the single expression inside of '${' and '}',
+// start is the position of the expression in the original source
+func (p *Parser) parseInterpolation(source string, start uint) ast.Expr {
+	inner := NewParser([]byte(source))
+	inner.tokens = inner.lexer.Lex()
+	for i := range inner.tokens {
+		inner.tokens[i].Pos += start
+	}
+
+	if inner.peek().Kind == token.EOF {
+		p.addError(fmt.Errorf("interpolation '${}' must contain an expression"))
+		return nil
+	}
+
+	expr := inner.parse()
+	if inner.peek().Kind != token.EOF {
+		inner.addError(fmt.Errorf("interpolation '${%s}' must contain a single expression", source))
+	}
+	if len(inner.Errors) > 0 {
+		p.Errors = append(p.Errors, inner.Errors...)
+		return nil
+	}
+
+	return expr
+}
+
+// decodeEscape decodes the escape sequence at the start of the text,
+// it returns the decoded string and the length of the escape sequence
+func decodeEscape(text string) (string, int, error) {
+	if len(text) < 2 {
+		return "", 0, fmt.Errorf("invalid escape sequence '%s'", text)
+	}
+
+	switch text[1] {
+	case 'n':
+		return "\n", 2, nil
+	case 't':
+		return "\t", 2, nil
+	case 'r':
+		return "\r", 2, nil
+	case '0':
+		return "\x00", 2, nil
+	case '"', '\\', '$', '`':
+		return text[1:2], 2, nil
+	case 'u':
+		// unicode escapes are written as \u{1F600}
+		end := strings.IndexByte(text, '}')
+		if len(text) < 4 || text[2] != '{' || end < 0 {
+			return "", 0, fmt.Errorf("invalid unicode escape, escapes must look like '\\u{1F600}'")
+		}
+
+		code, err := strconv.ParseUint(text[3:end], 16, 32)
+		if err != nil || code > 0x10FFFF || (code >= 0xD800 && code <= 0xDFFF) {
+			return "", 0, fmt.Errorf("invalid unicode escape '%s'", text[:end+1])
+		}
+
+		return string(rune(code)), end + 1, nil
+	default:
+		return "", 0, fmt.Errorf("invalid escape sequence '%s'", text[:2])
+	}
+}
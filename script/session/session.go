@@ -0,0 +1,181 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bjatkin/nook/script/builtin"
+	"github.com/bjatkin/nook/script/checker"
+	"github.com/bjatkin/nook/script/lint"
+	"github.com/bjatkin/nook/script/module"
+	"github.com/bjatkin/nook/script/normalizer"
+	"github.com/bjatkin/nook/script/parser"
+	"github.com/bjatkin/nook/script/vm"
+)
+
+// Session holds the checker and vm state that is shared between cells.
+// Each cell is run as a transaction so a cell that fails to check or evaluate
+// leaves the session exactly as it was before the cell was run.
+type Session struct {
+	normalizer *normalizer.Normalizer
+	checker    *checker.Checker
+	vm         *vm.VM
+	loader     *module.Loader
+
+	// sources holds the code for every cell that has been run so error traces
+	// can report where calls were made
+	sources map[string][]byte
+
+	// Warnings are the lint warnings for the last cell that was run.
+	// Unlike errors they do not stop the cell from running.
+	Warnings []error
+}
+
+// NewSession creates a new session with only the builtins defined
+func NewSession() *Session {
+	return &Session{
+		normalizer: &normalizer.Normalizer{},
+		checker:    checker.NewChecker(),
+		vm:         vm.NewVM(),
+		loader:     module.NewLoader(),
+		sources:    map[string][]byte{},
+	}
+}
+
+// SetMaxDepth sets the number of nested calls a cell can make before a stack overflow
+// error is raised. The depth must be greater than 0 since the limit can not be removed.
+func (s *Session) SetMaxDepth(depth int) error {
+	if depth <= 0 {
+		return fmt.Errorf("max depth must be greater than 0 but got %d", depth)
+	}
+
+	s.vm.MaxDepth = depth
+	return nil
+}
+
+// Run parses, checks and evaluates a single cell.
+// Compile time errors are returned in errs, while errors that happen during
+// evaluation are returned as runtimeErr. In either case the cell is rolled back.
+func (s *Session) Run(code []byte) (value vm.Value, errs []error, runtimeErr error) {
+	s.Warnings = nil
+
+	p := parser.NewParser(code)
+	expr := p.Parse()
+	if len(p.Errors) > 0 {
+		return vm.NoneValue, p.Errors, nil
+	}
+
+	s.normalizer.Begin()
+	s.checker.Begin()
+	s.vm.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			s.rollback()
+
+			value = vm.NoneValue
+			errs = nil
+			runtimeErr = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	expr = s.normalizer.Normalize(expr)
+	if len(s.normalizer.Errors) > 0 {
+		errs := s.normalizer.Errors
+		s.rollback()
+		return vm.NoneValue, errs, nil
+	}
+
+	s.loader.Load(expr)
+	if len(s.loader.Errors) > 0 {
+		s.rollback()
+		return vm.NoneValue, s.loader.Errors, nil
+	}
+
+	// the linter needs to run before the checker replaces overloaded calls
+	linter := lint.NewLinter(s.checker.Table())
+	linter.Expanded = s.normalizer.Expanded
+	linter.Lint(expr)
+
+	_ = s.checker.Infer(expr)
+	if len(s.checker.Errors) > 0 {
+		errs := s.checker.Errors
+		s.rollback()
+		return vm.NoneValue, errs, nil
+	}
+
+	// warnings are only useful once the cell type checks
+	s.Warnings = linter.Warnings
+
+	path := fmt.Sprintf("cell %d", len(s.sources)+1)
+	s.sources[path] = code
+	s.vm.SetPath(path)
+
+	value, err := s.vm.Eval(expr)
+	if err != nil {
+		s.rollback()
+		return vm.NoneValue, nil, s.uncaught(err)
+	}
+
+	s.normalizer.Commit()
+	s.checker.Commit()
+	s.vm.Commit()
+	return value, nil, nil
+}
+
+// maxTraceFrames is the number of calls shown at each end of a long error trace
+const maxTraceFrames = 10
+
+// uncaught adds the trace of an error that was not caught by try to the error message.
+// Each call in the trace reports the cell or file, line and column it was made from.
+func (s *Session) uncaught(err error) error {
+	var nookErr *builtin.Error
+	if !errors.As(err, &nookErr) || len(nookErr.Trace) == 0 {
+		return err
+	}
+
+	trace := []string{}
+	for i, frame := range nookErr.Trace {
+		// deep traces (e.g. from a stack overflow) only show the innermost and outermost calls
+		hidden := len(nookErr.Trace) - 2*maxTraceFrames
+		if hidden > 0 && i == maxTraceFrames {
+			trace = append(trace, fmt.Sprintf("  ... %d more calls", hidden))
+		}
+		if hidden > 0 && i >= maxTraceFrames && i < maxTraceFrames+hidden {
+			continue
+		}
+
+		source, ok := s.sources[frame.Path]
+		if !ok {
+			source, _ = os.ReadFile(frame.Path)
+		}
+
+		line, col := position(source, frame.Pos)
+		trace = append(trace, fmt.Sprintf("  at %s (%s:%d:%d)", frame.Name, frame.Path, line, col))
+	}
+
+	return fmt.Errorf("%w\n%s", err, strings.Join(trace, "\n"))
+}
+
+// position converts a byte offset in the source into a line and column, both starting at 1.
+// The column counts runes so characters that take more than one byte are only counted once.
+func position(source []byte, pos uint) (int, int) {
+	if int(pos) > len(source) {
+		return 0, 0
+	}
+
+	before := source[:pos]
+	line := bytes.Count(before, []byte{'\n'}) + 1
+	col := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return line, col
+}
+
+// rollback undoes all the changes made by the current cell
+func (s *Session) rollback() {
+	s.normalizer.Rollback()
+	s.checker.Rollback()
+	s.vm.Rollback()
+}
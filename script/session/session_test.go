@@ -0,0 +1,1136 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type cell struct {
+	code           string
+	want           string
+	wantErr        bool
+	wantRuntimeErr bool
+}
+
+func TestSession_Run(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []cell
+	}{
+		{
+			name: "bindings persist across cells",
+			cells: []cell{
+				{code: "(let a 10)", want: "<nil>"},
+				{code: "(let b (+ a 5))", want: "<nil>"},
+				{code: "(+ a b)", want: "25"},
+			},
+		},
+		{
+			name: "functions persist across cells",
+			cells: []cell{
+				{code: "(let add1 (fn [a int] (+ a 1)))", want: "<nil>"},
+				{code: "(add1 41)", want: "42"},
+			},
+		},
+		{
+			name: "check errors roll back the cell",
+			cells: []cell{
+				{code: "(let a 1)", want: "<nil>"},
+				{code: "(let a (+ a \"two\"))", wantErr: true},
+				{code: "(+ a 1)", want: "2"},
+			},
+		},
+		{
+			name: "check errors do not leave new symbols behind",
+			cells: []cell{
+				{code: "(let b (missing 1))", wantErr: true},
+				{code: "(+ b 1)", wantErr: true},
+			},
+		},
+		{
+			name: "runtime errors roll back the cell",
+			cells: []cell{
+				{code: "(let a 1)", want: "<nil>"},
+				{code: "(let a (cd ./this/path/does/not/exist))", wantRuntimeErr: true},
+				{code: "(+ a 1)", want: "2"},
+			},
+		},
+		{
+			name: "failed impl overloads are removed",
+			cells: []cell{
+				{code: "(impl add (fn [a b int] (+ a b)))", want: "<nil>"},
+				{code: "(impl add (fn [a b float] (+ a b 'bad)))", wantErr: true},
+				{code: "(add 1 2)", want: "3"},
+				{code: "(add 1.5 2.5)", wantErr: true},
+			},
+		},
+		{
+			name: "untyped functions select overloads at runtime",
+			cells: []cell{
+				{code: "(let add (fn [a b] (+ a b)))", want: "<nil>"},
+				{code: "(add 1 2)", want: "3"},
+				{code: "(add 1.5 2.25)", want: "3.75"},
+				{code: "(add 1 2.5)", want: "3.5"},
+				{code: "(add 1 \"two\")", wantRuntimeErr: true},
+			},
+		},
+		{
+			name: "builtins with a single overload check unknown arguments at runtime",
+			cells: []cell{
+				{code: "(let f (fn [x] (str.upper x)))", want: "<nil>"},
+				{code: "(f \"a\")", want: "A"},
+				{code: "(f 1)", wantRuntimeErr: true},
+				{code: "(err.message (try (f 1)))", want: "could not select an overload for ('upper' int)"},
+				{code: "(str.upper [(json.parse \"{\\\"a\\\":1.5}\") .a])", wantRuntimeErr: true},
+			},
+		},
+		{
+			name: "namespace members are qualified",
+			cells: []cell{
+				{code: "(ns geo (let origin 1) (impl dist (fn [a b int] (- b a))))", want: "<nil>"},
+				{code: "(geo.dist geo.origin 10)", want: "9"},
+				{code: "origin", wantErr: true},
+				{code: "geo", wantErr: true},
+			},
+		},
+		{
+			name: "private members are only used inside the namespace",
+			cells: []cell{
+				{code: "(ns geo (let _scale 2) (let double (fn [a int] (* a _scale))))", want: "<nil>"},
+				{code: "(geo.double 4)", want: "8"},
+				{code: "geo._scale", wantErr: true},
+			},
+		},
+		{
+			name: "namespaces can be reopened",
+			cells: []cell{
+				{code: "(ns geo (let a 1))", want: "<nil>"},
+				{code: "(ns geo (let b (+ a 1)))", want: "<nil>"},
+				{code: "(+ geo.a geo.b)", want: "3"},
+			},
+		},
+		{
+			name: "reopened namespaces are rolled back",
+			cells: []cell{
+				{code: "(ns geo (let a 1))", want: "<nil>"},
+				{code: "(ns geo (let b (+ a \"two\")))", wantErr: true},
+				{code: "geo.b", wantErr: true},
+				{code: "(ns geo (let b (cd ./this/path/does/not/exist)))", wantRuntimeErr: true},
+				{code: "geo.b", wantErr: true},
+				{code: "geo.a", want: "1"},
+			},
+		},
+		{
+			name: "builtins are namespaced",
+			cells: []cell{
+				{code: "(math.add 1 2)", want: "3"},
+				{code: "(+ 1 2)", want: "3"},
+			},
+		},
+		{
+			name: "macros persist across cells",
+			cells: []cell{
+				{code: "(macro unless [c a b] (quasiquote (if (unquote c) (unquote b) (unquote a))))", want: "<nil>"},
+				{code: "(unless false 1 2)", want: "1"},
+			},
+		},
+		{
+			name: "macro bindings do not capture arguments",
+			cells: []cell{
+				{code: "(macro withone [body] (quasiquote (do (let one 1) (unquote body))))", want: "<nil>"},
+				{code: "(let one 100)", want: "<nil>"},
+				{code: "(withone (+ one 1))", want: "101"},
+			},
+		},
+		{
+			name: "failed cells remove their macros",
+			cells: []cell{
+				{code: "(do (macro three [] (quote 3)) (+ 1 \"two\"))", wantErr: true},
+				{code: "(three)", wantErr: true},
+			},
+		},
+		{
+			name: "try returns the error",
+			cells: []cell{
+				{code: "(try (raise \"boom\"))", want: "boom"},
+				{code: "(try (+ 1 2))", want: "3"},
+			},
+		},
+		{
+			name: "try handler",
+			cells: []cell{
+				{code: "(try (cd ./this/path/does/not/exist) (fn [e error] nil))", want: "<nil>"},
+				{code: "(try (raise (fail \"outer\" (fail \"inner\"))) (fn [e error] (err.message e)))", want: "outer"},
+				{code: "(try 1 (fn [e error] \"one\"))", wantErr: true},
+			},
+		},
+		{
+			name: "errors are values",
+			cells: []cell{
+				{code: "(let e (fail \"bad\" (fail \"cause\")))", want: "<nil>"},
+				{code: "e", want: "bad: cause"},
+				{code: "(err.cause e)", want: "cause"},
+				{code: "(err.cause (err.cause e))", want: "<nil>"},
+			},
+		},
+		{
+			name: "failed commands can be caught",
+			cells: []cell{
+				{code: "($false)", wantRuntimeErr: true},
+				{code: "(try ($false) (fn [e error] (err.message e)))", want: "'false' exited with status 1"},
+			},
+		},
+		{
+			name: "comparisons",
+			cells: []cell{
+				{code: "(< 1 2)", want: "true"},
+				{code: "(<= 2 2)", want: "true"},
+				{code: "(> 1.5 2.5)", want: "false"},
+				{code: "(>= \"b\" \"a\")", want: "true"},
+				{code: "(< ./a ./b)", want: "true"},
+				{code: "(== 'ok 'ok)", want: "true"},
+				{code: "(!= 'ok 'err)", want: "true"},
+				{code: "(< true false)", wantErr: true},
+				{code: "(== 1 \"1\")", wantErr: true},
+				{code: "(== 1 1.0)", want: "true"},
+				{code: "(!= 2.5 2)", want: "true"},
+				{code: "(== (json.parse \"1\") \"1\")", want: "false"},
+				{code: "(== (json.parse \"1\") 1.0)", want: "true"},
+			},
+		},
+		{
+			name: "tuples and dicts are compared structurally",
+			cells: []cell{
+				{code: "(let point {5 {10 \"up\"}})", want: "<nil>"},
+				{code: "point", want: "{5 {10 \"up\"}}"},
+				{code: "(== point {5 {10 \"up\"}})", want: "true"},
+				{code: "(== point {5 {11 \"up\"}})", want: "false"},
+				{code: "(== {.a 1 .b 'x} {.b 'x .a 1})", want: "true"},
+				{code: "(!= {.a 1} {.a 2})", want: "true"},
+				{code: "{.year 1965 .title \"dune\"}", want: "{.title \"dune\" .year 1965}"},
+				{code: "{.a 1 .a 2}", wantErr: true},
+			},
+		},
+		{
+			name: "and, or and not",
+			cells: []cell{
+				{code: "(and (< 1 2) (not false))", want: "true"},
+				{code: "(or false (== 1 2))", want: "false"},
+				{code: "(and)", want: "true"},
+				{code: "(or)", want: "false"},
+				{code: "(and 1 true)", wantErr: true},
+			},
+		},
+		{
+			name: "and and or short circuit",
+			cells: []cell{
+				{code: "(and false (raise \"unreachable\"))", want: "false"},
+				{code: "(or true (raise \"unreachable\"))", want: "true"},
+				{code: "(or false (raise \"reached\"))", wantRuntimeErr: true},
+			},
+		},
+		{
+			name: "ints are promoted to floats",
+			cells: []cell{
+				{code: "(+ 1 2.5)", want: "3.5"},
+				{code: "(* 2 1.5 2)", want: "6"},
+				{code: "(pow 2 0.5)", want: "1.4142135623730951"},
+				{code: "(+ 1 2)", want: "3"},
+				{code: "(/ {float 1} 4)", want: "0.25"},
+				{code: "(math.float (math.big 3))", want: "3"},
+				{code: "(let half (fn [n number] (/ n 2.0)))", want: "<nil>"},
+				{code: "(half 3)", want: "1.5"},
+			},
+		},
+		{
+			name: "typed literals",
+			cells: []cell{
+				{code: "{float 1}", want: "1"},
+				{code: "(+ {bigint 1} (math.big 2))", want: "3"},
+				{code: "(cd {path \".\"})", want: "<nil>"},
+				{code: "{none}", want: "<nil>"},
+				{code: "{int 1.5}", wantErr: true},
+			},
+		},
+		{
+			name: "arithmetic errors can be caught",
+			cells: []cell{
+				{code: "(/ 1 0)", wantRuntimeErr: true},
+				{code: "(err.message (try (/ 1 0)))", want: "division by zero"},
+				{code: "(try (/ 1 0) (fn [e error] -1))", want: "-1"},
+				{code: "(+ 9223372036854775807 1)", wantRuntimeErr: true},
+				{code: "(* 4611686018427387904 2)", wantRuntimeErr: true},
+				{code: "(mod -7 3)", want: "2"},
+				{code: "(rem -7 3)", want: "-1"},
+				{code: "(pow 2 10)", want: "1024"},
+			},
+		},
+		{
+			name: "bigints",
+			cells: []cell{
+				{code: "(let big (math.big 9223372036854775807))", want: "<nil>"},
+				{code: "(+ big (math.big 1))", want: "9223372036854775808"},
+				{code: "(pow (math.big 2) 100)", want: "1267650600228229401496703205376"},
+				{code: "(== (math.big \"10\") (math.big 10))", want: "true"},
+				{code: "(< big (math.big \"9223372036854775808\"))", want: "true"},
+				{code: "(math.int (* big (math.big 2)))", wantRuntimeErr: true},
+				{code: "(+ big 1)", wantErr: true},
+			},
+		},
+		{
+			name: "tail calls run in constant stack",
+			cells: []cell{
+				{code: "(let count (fn [n acc int] int (match n (0 acc) (else (count (- n 1) (+ acc 1))))))", want: "<nil>"},
+				{code: "(count 100000 0)", want: "100000"},
+			},
+		},
+		{
+			name: "mutual tail calls run in constant stack",
+			cells: []cell{
+				{code: "(let ping (fn [n int pong] (match n (0 'ping) (else (pong (- n 1) ping)))))", want: "<nil>"},
+				{code: "(let pong (fn [n int ping] (match n (0 'pong) (else (ping (- n 1) pong)))))", want: "<nil>"},
+				{code: "(ping 100001 pong)", want: "'pong"},
+			},
+		},
+		{
+			name: "functions in a do can call each other",
+			cells: []cell{
+				{code: `(do
+					(let even (fn [n int] bool (match n (0 true) (else (odd (- n 1))))))
+					(let odd (fn [n int] bool (match n (0 false) (else (even (- n 1))))))
+					{(even 100000) (odd 7)})`, want: "{true true}"},
+				{code: "(do (let a (b)) (let b (fn [] 1)))", wantRuntimeErr: true},
+			},
+		},
+		{
+			name: "stack overflows can be caught",
+			cells: []cell{
+				{code: "(let deep (fn [n int] int (match n (0 0) (else (+ 1 (deep (- n 1)))))))", want: "<nil>"},
+				{code: "(deep 100)", want: "100"},
+				{code: "(deep 100000)", wantRuntimeErr: true},
+				{code: "(try (deep 100000) (fn [e error] -1))", want: "-1"},
+			},
+		},
+		{
+			name: "slices",
+			cells: []cell{
+				{code: "(let ints {[int] 5 10 15})", want: "<nil>"},
+				{code: "ints", want: "[5 10 15]"},
+				{code: "[ints 1]", want: "10"},
+				{code: "[ints 3]", wantRuntimeErr: true},
+				{code: "{[_] \"a\" \"b\"}", want: "[\"a\" \"b\"]"},
+				{code: "{[int] 1 \"two\"}", wantErr: true},
+				{code: "(let first (fn [xs [int]] int [xs 0]))", want: "<nil>"},
+				{code: "(first ints)", want: "5"},
+				{code: "(== ints {[int] 5 10 15})", want: "true"},
+				{code: "[{1 \"x\"} 1]", want: "x"},
+				{code: "[{.a 1 .b 2} .b]", want: "2"},
+				{code: "[{.a 1} .c]", wantErr: true},
+			},
+		},
+		{
+			name: "string builtins",
+			cells: []cell{
+				{code: "(str.concat \"a\" \"b\" \"c\")", want: "abc"},
+				{code: "(str.split \"a,b,c\" \",\")", want: "[\"a\" \"b\" \"c\"]"},
+				{code: "(str.join (str.split \"a b\" \" \") \"-\")", want: "a-b"},
+				{code: "(str.trim \"  hi  \")", want: "hi"},
+				{code: "(str.trim \"--hi--\" \"-\")", want: "hi"},
+				{code: "(str.contains \"nook\" \"oo\")", want: "true"},
+				{code: "(str.replace \"a.b.c\" \".\" \"/\")", want: "a/b/c"},
+				{code: "(str.upper \"nook\")", want: "NOOK"},
+				{code: "(str.lower \"NOOK\")", want: "nook"},
+				{code: "(str.format \"%s has %d items\" \"list\" 3)", want: "list has 3 items"},
+			},
+		},
+		{
+			name: "strings can be indexed by byte or by rune",
+			cells: []cell{
+				{code: "(str.len \"héllo\")", want: "6"},
+				{code: "(str.rune_len \"héllo\")", want: "5"},
+				{code: "(str.sub \"héllo\" 0 3)", want: "hé"},
+				{code: "(str.sub \"héllo\" 0 2)", wantRuntimeErr: true},
+				{code: "(str.rune_sub \"héllo\" 1 3)", want: "él"},
+				{code: "(str.rune_sub \"héllo\" 1 10)", wantRuntimeErr: true},
+				{code: "(str.runes \"hé\")", want: "[\"h\" \"é\"]"},
+				{code: "(str.bytes \"hé\")", want: "[104 195 169]"},
+			},
+		},
+		{
+			name: "path builtins",
+			cells: []cell{
+				{code: "(path.join ./docs \"notes\" \"todo.md\")", want: "docs/notes/todo.md"},
+				{code: "(path.join /usr ./local)", want: "/usr/local"},
+				{code: "(path.parent /usr/local/bin)", want: "/usr/local"},
+				{code: "(path.base /usr/local/bin)", want: "bin"},
+				{code: "(path.ext ./notes.tar.gz)", want: ".gz"},
+				{code: "(path.stem ./notes.txt)", want: "notes"},
+				{code: "(path.rel /usr/local/bin /usr)", want: "local/bin"},
+				{code: "(path.clean ./a/../b/./c)", want: "b/c"},
+				{code: "(exists ./session.go)", want: "true"},
+				{code: "(path.exists ./missing.go)", want: "false"},
+				{code: "(path.is_dir ../session)", want: "true"},
+				{code: "(path.is_dir ./session.go)", want: "false"},
+				{code: "(== (path.abs ./session.go) (path.join (path.abs ../session) \"session.go\"))", want: "true"},
+				{code: "(str.concat ./notes \".txt\")", want: "./notes.txt"},
+				{code: "(path.ext (str.concat ./notes \".txt\"))", want: ".txt"},
+				{code: "(str.upper (str.concat ./notes \".txt\"))", wantErr: true},
+				{code: "(+ ./notes \".md\")", want: "./notes.md"},
+				{code: "(path.ext (+ ./notes \"-v2\" \".md\"))", want: ".md"},
+				{code: "(+ \"notes\" ./a)", wantErr: true},
+			},
+		},
+		{
+			name: "higher order slice builtins",
+			cells: []cell{
+				{code: "(let ints {[int] 3 1 2})", want: "<nil>"},
+				{code: "(map ints (fn [x] (* x 2)))", want: "[6 2 4]"},
+				{code: "(map ints (fn [x int] str (str.format \"%d\" x)))", want: "[\"3\" \"1\" \"2\"]"},
+				{code: "(filter ints (fn [x] (> x 1)))", want: "[3 2]"},
+				{code: "(reduce ints (fn [acc x] (+ acc x)) 0)", want: "6"},
+				{code: "(each ints (fn [x] x))", want: "<nil>"},
+				{code: "(sort_by ints (fn [x] x))", want: "[1 2 3]"},
+				{code: "(sort_by {[_] \"bb\" \"a\" \"ccc\"} str.len)", wantErr: true},
+				{code: "(sort_by {[_] \"bb\" \"a\" \"ccc\"} (fn [s str] int (str.len s)))", want: "[\"a\" \"bb\" \"ccc\"]"},
+				{code: "(group_by ints (fn [x] (mod x 2)))", want: "[{1 [3 1]} {0 [2]}]"},
+				{code: "(zip ints {[_] 'a 'b})", want: "[{3 'a} {1 'b}]"},
+				{code: "(enumerate {[_] 'a 'b})", want: "[{0 'a} {1 'b}]"},
+				{code: "(take ints 2)", want: "[3 1]"},
+				{code: "(drop ints 2)", want: "[2]"},
+				{code: "(take ints 5)", want: "[3 1 2]"},
+				{code: "(uniq {[_] 1 2 1 3 2})", want: "[1 2 3]"},
+				{code: "(flatten {[_] {[_] 1 2} {[int]} {[_] 3}})", want: "[1 2 3]"},
+			},
+		},
+		{
+			name: "callbacks are checked against the element type",
+			cells: []cell{
+				{code: "(map {[_] 1 2} (fn [s str] s))", wantErr: true},
+				{code: "(filter {[_] 1 2} (fn [x int] int x))", wantErr: true},
+				{code: "(filter {[_] 1 2} (fn [x] x))", wantRuntimeErr: true},
+				{code: "(reduce {[_] 1 2} (fn [acc str x int] acc) 0)", wantErr: true},
+				{code: "(map {[_] 1 2} (fn [a b] a))", wantErr: true},
+				{code: "(str.join (map {[_] 'a 'b} (fn [a atom] str \"x\")) \",\")", want: "x,x"},
+				{code: "[(map {[_] 'a 'b} (fn [a] a)) 0]", want: "'a"},
+			},
+		},
+		{
+			name: "errors in callbacks can be caught",
+			cells: []cell{
+				{code: "(map {[_] 1 0} (fn [x] (/ 1 x)))", wantRuntimeErr: true},
+				{code: "(try (map {[_] 1 0} (fn [x] (/ 1 x))) (fn [e error] {[int]}))", want: "[]"},
+			},
+		},
+		{
+			name: "errors are cleared between cells",
+			cells: []cell{
+				{code: "(+ 1 \"two\")", wantErr: true},
+				{code: "(+ 1 2)", want: "3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession()
+			for i, cell := range tt.cells {
+				got, errs, err := s.Run([]byte(cell.code))
+				if (len(errs) > 0) != cell.wantErr {
+					t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+				}
+				if (err != nil) != cell.wantRuntimeErr {
+					t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+				}
+				if cell.wantErr || cell.wantRuntimeErr {
+					continue
+				}
+
+				if got.String() != cell.want {
+					t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_Run_import(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"git.nk":    "(let branch \"main\")\n(impl short (fn [a str] a))\n",
+		"broken.nk": "(let one 1)\n(let two (+ one \"one\"))\n",
+		"m.nk":      "(let total (+ 1 2 3))\n(let shout (fn [s str] (str.upper s)))\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		cells []cell
+	}{
+		{
+			name: "imported names are namespaced",
+			cells: []cell{
+				{code: "(import " + dir + "/git.nk)", want: "<nil>"},
+				{code: "(git.short git.branch)", want: "main"},
+				{code: "branch", wantErr: true},
+			},
+		},
+		{
+			name: "unchanged files are only imported once",
+			cells: []cell{
+				{code: "(import " + dir + "/git.nk)", want: "<nil>"},
+				{code: "(import " + dir + "/git.nk)", want: "<nil>"},
+				{code: "git.branch", want: "main"},
+			},
+		},
+		{
+			name: "failed imports are rolled back",
+			cells: []cell{
+				{code: "(import " + dir + "/broken.nk)", wantErr: true},
+				{code: "broken.one", wantErr: true},
+			},
+		},
+		{
+			name: "a file can be imported again after a rolled back import",
+			cells: []cell{
+				{code: "(do (import " + dir + "/m.nk) (cd " + dir + "/nope))", wantRuntimeErr: true},
+				{code: "(import " + dir + "/m.nk)", want: "<nil>"},
+				{code: "(m.shout \"hi\")", want: "HI"},
+			},
+		},
+		{
+			name: "a file can be imported into more than one namespace",
+			cells: []cell{
+				{code: "(ns a (import " + dir + "/m.nk))", want: "<nil>"},
+				{code: "(ns b (import " + dir + "/m.nk))", want: "<nil>"},
+				{code: "b.m.total", want: "6"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession()
+			for i, cell := range tt.cells {
+				got, errs, err := s.Run([]byte(cell.code))
+				if (len(errs) > 0) != cell.wantErr {
+					t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+				}
+				if (err != nil) != cell.wantRuntimeErr {
+					t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+				}
+				if cell.wantErr || cell.wantRuntimeErr {
+					continue
+				}
+
+				if got.String() != cell.want {
+					t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_Run_importChanged(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.nk": "(import ./b.nk)\n(let get (fn [] b.value))\n",
+		"b.nk": "(let value 1)",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	s := NewSession()
+	for i, value := range []string{"1", "2"} {
+		if err := os.WriteFile(filepath.Join(dir, "b.nk"), []byte("(let value "+value+")"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		code := "(do (import " + dir + "/a.nk) (a.get))"
+		got, errs, err := s.Run([]byte(code))
+		if len(errs) > 0 || err != nil {
+			t.Fatalf("run %d Session.Run(%s) errs %v, err %v", i, code, errs, err)
+		}
+		if got.String() != value {
+			t.Errorf("run %d Session.Run(%s) = %s, want %s", i, code, got.String(), value)
+		}
+	}
+}
+
+func TestSession_Run_importErrorLine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.nk")
+	if err := os.WriteFile(path, []byte("(let a 1)\n\n(let b (+ a \"one\"))\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	s := NewSession()
+	_, errs, _ := s.Run([]byte("(import " + path + ")"))
+	if len(errs) == 0 {
+		t.Fatalf("Session.Run() expected an error")
+	}
+
+	want := path + ":3: "
+	if !strings.HasPrefix(errs[0].Error(), want) {
+		t.Errorf("Session.Run() error = %q, want prefix %q", errs[0].Error(), want)
+	}
+}
+
+func TestSession_Run_shadowBuiltin(t *testing.T) {
+	tests := []struct {
+		name         string
+		cells        []cell
+		wantWarnings []string
+	}{
+		{
+			name: "top level let",
+			cells: []cell{
+				{code: "(let cd 5)", want: "<nil>"},
+				{code: "(+ cd 1)", want: "6"},
+			},
+			wantWarnings: []string{"let 'cd' shadows the builtin 'cd'"},
+		},
+		{
+			name: "top level operator",
+			cells: []cell{
+				{code: "(let + 5)", want: "<nil>"},
+				{code: "(math.add + 1)", want: "6"},
+			},
+			wantWarnings: []string{"let '+' shadows the builtin '+'"},
+		},
+		{
+			name: "rolled back let",
+			cells: []cell{
+				{code: "(let ls (raise \"boom\"))", wantRuntimeErr: true},
+				// the builtin is restored so it is shadowed again
+				{code: "(let ls 2)", want: "<nil>"},
+			},
+			wantWarnings: []string{"let 'ls' shadows the builtin 'ls'", "let 'ls' shadows the builtin 'ls'"},
+		},
+		{
+			name: "impls can not be rebound",
+			cells: []cell{
+				{code: "(impl short (fn [a str] a))", want: "<nil>"},
+				{code: "(let short 1)", wantErr: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession()
+			var warnings []string
+			for i, cell := range tt.cells {
+				got, errs, err := s.Run([]byte(cell.code))
+				if (len(errs) > 0) != cell.wantErr {
+					t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+				}
+				if (err != nil) != cell.wantRuntimeErr {
+					t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+				}
+				for _, warning := range s.Warnings {
+					warnings = append(warnings, warning.Error())
+				}
+				if cell.wantErr || cell.wantRuntimeErr {
+					continue
+				}
+
+				if got.String() != cell.want {
+					t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+				}
+			}
+
+			if !reflect.DeepEqual(warnings, tt.wantWarnings) {
+				t.Errorf("Session.Warnings = %v, want %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestSession_Run_trace(t *testing.T) {
+	s := NewSession()
+	_, errs, _ := s.Run([]byte("(let fails (fn [a int] (raise \"boom\")))"))
+	if len(errs) > 0 {
+		t.Fatalf("Session.Run() errs %v", errs)
+	}
+
+	_, _, err := s.Run([]byte("(+ 1\n  (fails 2))"))
+	want := "boom\n" +
+		"  at raise (cell 1:1:25)\n" +
+		"  at fails (cell 2:2:4)"
+	if err == nil || err.Error() != want {
+		t.Errorf("Session.Run() err = %v, want %q", err, want)
+	}
+}
+
+func TestSession_Run_unicode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "résumé.txt"), []byte("héllo"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cells := []cell{
+		{code: `(let café "☕")`, want: "<nil>"},
+		{code: `café`, want: "☕"},
+		{code: `(let 日本 {.名前 "東京"})`, want: "<nil>"},
+		{code: `[日本 .名前]`, want: "東京"},
+		{code: `'über`, want: "'über"},
+		{code: fmt.Sprintf("(fs.read %s)", filepath.Join(dir, "résumé.txt")), want: "héllo"},
+		{code: fmt.Sprintf("(path.base %s)", filepath.Join(dir, "résumé.txt")), want: "résumé.txt"},
+		{code: "(let bad \"\xff\")", wantErr: true},
+		{code: "\xe6\x97", wantErr: true},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+
+	// trace columns count characters rather than bytes
+	_, _, err := s.Run([]byte(`(do "héllo" (raise "boom"))`))
+	want := "boom\n  at raise (cell 8:1:14)"
+	if err == nil || err.Error() != want {
+		t.Errorf("Session.Run() err = %v, want %q", err, want)
+	}
+}
+
+func TestSession_Run_files(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	copied := filepath.Join(dir, "copy", "notes.txt")
+
+	// nook strings can not hold new lines yet so the file is created up front
+	if err := os.WriteFile(file, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cells := []cell{
+		{code: fmt.Sprintf("(fs.read_lines %s)", file), want: "[\"one\" \"two\"]"},
+		{code: fmt.Sprintf("(fs.append %s \"three\")", file), want: "<nil>"},
+		{code: fmt.Sprintf("(fs.read_lines %s)", file), want: "[\"one\" \"two\" \"three\"]"},
+		{code: fmt.Sprintf("[(fs.stat %s) .size]", file), want: "13B"},
+		{code: fmt.Sprintf("(fs.write %s \"hello\")", filepath.Join(dir, "hello.txt")), want: "<nil>"},
+		{code: fmt.Sprintf("(fs.read %s)", filepath.Join(dir, "hello.txt")), want: "hello"},
+		{code: fmt.Sprintf("[(fs.stat %s) .is_dir]", dir), want: "true"},
+		{code: fmt.Sprintf("(fs.mkdir %s)", filepath.Join(dir, "copy", "nested")), want: "<nil>"},
+		{code: fmt.Sprintf("(fs.cp %s %s)", file, copied), want: "<nil>"},
+		{code: fmt.Sprintf("(fs.read %s)", copied), want: "one\ntwo\nthree"},
+		{code: fmt.Sprintf("(fs.cp %s %s)", filepath.Join(dir, "copy"), filepath.Join(dir, "backup")), want: "<nil>"},
+		{code: fmt.Sprintf("(exists %s)", filepath.Join(dir, "backup", "nested")), want: "true"},
+		{code: fmt.Sprintf("(fs.mv %s %s)", copied, filepath.Join(dir, "moved.txt")), want: "<nil>"},
+		{code: fmt.Sprintf("(exists %s)", copied), want: "false"},
+		{code: fmt.Sprintf("(fs.cp %s %s)", file, filepath.Join(dir, "copy")), want: "<nil>"},
+		{code: fmt.Sprintf("(fs.read %s)", copied), want: "one\ntwo\nthree"},
+		{code: fmt.Sprintf("(fs.cp %s %s/)", file, filepath.Join(dir, "missing")), wantRuntimeErr: true},
+		{code: fmt.Sprintf("(exists %s)", filepath.Join(dir, "missing")), want: "false"},
+		{code: fmt.Sprintf("(fs.rm %s)", filepath.Join(dir, "backup")), want: "<nil>"},
+		{code: fmt.Sprintf("(exists %s)", filepath.Join(dir, "backup")), want: "false"},
+		{code: fmt.Sprintf("(fs.rm %s)", filepath.Join(dir, "missing")), wantRuntimeErr: true},
+		{code: fmt.Sprintf("(try (fs.read %s) (fn [e error] \"default\"))", filepath.Join(dir, "missing")), want: "default"},
+		{code: fmt.Sprintf("(fs.touch %s)", filepath.Join(dir, "empty")), want: "<nil>"},
+		{code: fmt.Sprintf("(fs.read %s)", filepath.Join(dir, "empty")), want: ""},
+		{code: "(path.is_dir (fs.tmpdir))", want: "true"},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if len(errs) > 0 {
+			t.Fatalf("cell %d Session.Run(%s) errs %v", i, cell.code, errs)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_json(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "repo.json")
+	data := `{"name": "nook", "stars": 10, "owner": {"login": "bjatkin"}, "topics": ["shell", "lisp"], "extra": 1}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cells := []cell{
+		{code: fmt.Sprintf("(let repo (json.parse (fs.read %s)))", file), want: "<nil>"},
+		{code: "[repo .stars]", want: "10"},
+		{code: "[[repo .owner] .login]", want: "bjatkin"},
+		{code: "(let typed {<.name str .stars int .topics [str]> repo})", want: "<nil>"},
+		{code: "typed", want: "{.name \"nook\" .stars 10 .topics [\"shell\" \"lisp\"]}"},
+		{code: "(str.join [typed .topics] \",\")", want: "shell,lisp"},
+		{code: "(let login (fn [r <.owner <.login str>>] str [[r .owner] .login]))", want: "<nil>"},
+		{code: "(login {<.owner <.login str>> repo})", want: "bjatkin"},
+		{code: "[typed .extra]", wantErr: true},
+		{code: "{<.name int> repo}", wantRuntimeErr: true},
+		{code: "{<.missing str> repo}", wantRuntimeErr: true},
+		{code: "(err.message (try {<.topics [int]> repo}))", want: "can not convert 'str' to 'int' at '.topics[0]'"},
+		{code: "{<int float> (json.parse \"[1, 2]\")}", want: "{1 2}"},
+		{code: "(let xs {[int] (json.parse \"[1, 2, 3]\")})", want: "<nil>"},
+		{code: "(+ [xs 2] 1)", want: "4"},
+		{code: "{[int] (json.parse \"[1, 2, \\\"x\\\"]\")}", wantRuntimeErr: true},
+		{code: "{[int] 1 [repo .stars]}", want: "[1 10]"},
+		{code: "{[int] 1 [repo .name]}", wantRuntimeErr: true},
+		{code: "(json.stringify {.a {[_] 1 2} .b {true {none}}})", want: "{\"a\":[1,2],\"b\":[true,null]}"},
+		{code: "(json.parse \"[1,\")", wantRuntimeErr: true},
+		{code: "(try (json.parse \"[1,\") (fn [e error] (err.message e)))", want: "invalid json at line 1 column 4: unexpected end of json"},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_csv(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"people.csv": "name,age\nann,30\n\"bo, jr\",4\n",
+		"people.tsv": "ann\t30\nbo\t4\n",
+		"semi.csv":   "name;age\nann;30\n",
+		"bad.csv":    "name,age\nann\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	cells := []cell{
+		{code: fmt.Sprintf("(let people (csv.parse (fs.read %s)))", file("people.csv")), want: "<nil>"},
+		{code: "people", want: "[{.age \"30\" .name \"ann\"} {.age \"4\" .name \"bo, jr\"}]"},
+		{code: "(map people (fn [p] [p .name]))", want: "[\"ann\" \"bo, jr\"]"},
+		{code: fmt.Sprintf("(csv.parse (fs.read %s) false)", file("people.csv")), want: "[{\"name\" \"age\"} {\"ann\" \"30\"} {\"bo, jr\" \"4\"}]"},
+		{code: fmt.Sprintf("(tsv.parse (fs.read %s) false)", file("people.tsv")), want: "[{\"ann\" \"30\"} {\"bo\" \"4\"}]"},
+		{code: fmt.Sprintf("(csv.parse (fs.read %s) true \";\")", file("semi.csv")), want: "[{.age \"30\" .name \"ann\"}]"},
+		{code: fmt.Sprintf("(csv.parse (fs.read %s))", file("bad.csv")), wantRuntimeErr: true},
+		{code: "(csv.parse \"a\" true \";;\")", wantRuntimeErr: true},
+		{code: "(csv.write people)", want: "name,age\nann,30\n\"bo, jr\",4\n"},
+		{code: "(csv.write people \";\")", want: "name;age\nann;30\nbo, jr;4\n"},
+		{code: "(csv.write (filter people (fn [p] (== [p .name] \"ann\"))))", want: "name,age\nann,30\n"},
+		{code: "(csv.write {[_] {1 true} {2 false}})", want: "1,true\n2,false\n"},
+		{code: "(tsv.write {[_] {1 \"a\"}})", want: "1\ta\n"},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if len(errs) > 0 {
+			t.Fatalf("cell %d Session.Run(%s) errs %v", i, cell.code, errs)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_from(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ps.txt":     "USER   PID COMMAND\nroot     1 init\nann     42 nook -i\n",
+		"os.txt":     "NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\n",
+		"status.txt": "# branch.head main\n# branch.ab +1 -0\n1 .M N... 100644 100644 100644 3a 3a main.go\n? notes.txt\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	cells := []cell{
+		{code: fmt.Sprintf("(let ps (from.table (fs.read %s)))", file("ps.txt")), want: "<nil>"},
+		{code: "(map ps (fn [p] [p .command]))", want: "[\"init\" \"nook -i\"]"},
+		{code: fmt.Sprintf("[(from.kv (fs.read %s)) .version_id]", file("os.txt")), want: "22.04"},
+		{code: fmt.Sprintf("(let status (from.git_status (fs.read %s)))", file("status.txt")), want: "<nil>"},
+		{code: "[status .branch]", want: "main"},
+		{code: "[status .ahead]", want: "1"},
+		{code: "(map [status .files] (fn [f] [f .path]))", want: "[\"main.go\" \"notes.txt\"]"},
+		{code: "(path.ext [[[status .files] 0] .path])", want: ".go"},
+		{code: "(map [status .files] (fn [f] [f .kind]))", want: "[\"changed\" \"untracked\"]"},
+		{code: "[status .missing]", wantErr: true},
+		{code: "(from.git_status \"M main.go\")", wantRuntimeErr: true},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_regex(t *testing.T) {
+	cells := []cell{
+		{code: `(let log "ann 42 ok, bo 7 failed")`, want: "<nil>"},
+		{code: `#/a\/b/`, want: `#/a\/b/`},
+		{code: `(regex.match log #/\d+/)`, want: "true"},
+		{code: `(regex.match log #/^bo/)`, want: "false"},
+		{code: `(regex.find log #/\d+/)`, want: "42"},
+		{code: `(regex.find_all log #/\d+/)`, want: `["42" "7"]`},
+		{code: `(regex.captures log #/(\w+) (\d+)/)`, want: `{"ann" "42"}`},
+		{code: `(regex.captures_all log #/(?P<name>\w+) (?P<id>\d+)/)`, want: `[{.id "42" .name "ann"} {.id "7" .name "bo"}]`},
+		{code: `(map (regex.captures_all log #/(?P<name>\w+) \d+/) (fn [m] [m .name]))`, want: `["ann" "bo"]`},
+		{code: `(regex.replace log #/(\w+) (\d+)/ "$2=$1")`, want: "42=ann ok, 7=bo failed"},
+		{code: `(regex.split log #/,\s*/)`, want: `["ann 42 ok" "bo 7 failed"]`},
+		{code: `(let re (fn [p regex] regex p))`, want: "<nil>"},
+		{code: `(re #/x/)`, want: `#/x/`},
+		{code: `(regex.match "abc" {regex "b+"})`, want: "true"},
+		{code: `(err.message (try {regex "a("}))`, want: "invalid regex 'a(': missing closing ) 'a('"},
+		{code: `(regex.find log #/z/)`, wantRuntimeErr: true},
+		{code: `(regex.match log "a")`, wantErr: true},
+		{code: `#/a(/`, wantErr: true},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_strings(t *testing.T) {
+	cells := []cell{
+		{code: `"a\tb"`, want: "a\tb"},
+		{code: `"say \"hi\" \u{1F600}"`, want: "say \"hi\" \U0001F600"},
+		{code: "`C:\\new ${x}`", want: "C:\\new ${x}"},
+		{code: "\"one\ntwo\"", want: "one\ntwo"},
+		{code: `(let name "nook")`, want: "<nil>"},
+		{code: `(let n 3)`, want: "<nil>"},
+		{code: `"hello ${name}!"`, want: "hello nook!"},
+		{code: `"${n} + 1 = ${(+ n 1)}"`, want: "3 + 1 = 4"},
+		{code: `"${'ok} ${./a/b} ${1.5KB} ${#/\d+/} ${true}"`, want: "ok ./a/b 1.5KB \\d+ true"},
+		{code: `"${(str.join {[_] "a" "b"} "}")}"`, want: "a}b"},
+		{code: `(let greet (fn [who str] str "hi ${who}"))`, want: "<nil>"},
+		{code: `(greet "bo")`, want: "hi bo"},
+		{code: `"\$${n}"`, want: "$3"},
+		{code: `"${{[_] 1 2}}"`, wantErr: true},
+		{code: `"${missing}"`, wantErr: true},
+		{code: `"\q"`, wantErr: true},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_heredoc(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(file, []byte("b\na\n"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cells := []cell{
+		{code: "(let query <<SQL\n    select name\n      from users;\n    SQL)", want: "<nil>"},
+		{code: "query", want: "select name\n  from users;\n"},
+		{code: "(str.len <<EOF\nEOF)", want: "0"},
+		{code: "($cat < query)", want: "select name\n  from users;\n"},
+		{code: "($sort < <<EOF\n  b\n  c\n  a\n  EOF)", want: "a\nb\nc\n"},
+		{code: fmt.Sprintf("($sort < %s)", file), want: "a\nb\n"},
+		{code: `($cat < "${(+ 1 2)}")`, want: "3"},
+		{code: "($wc -l < 5)", wantErr: true},
+		{code: "($cat < query query)", wantErr: true},
+		{code: "<<EOF\ntext", wantErr: true},
+		{code: fmt.Sprintf("($cat < %s)", filepath.Join(filepath.Dir(file), "missing")), wantRuntimeErr: true},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %q, want %q", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_time(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	cells := []cell{
+		{code: "5s", want: "5s"},
+		{code: "(+ 1h30m 250ms)", want: "1h30m0.25s"},
+		{code: "(- 2h 30m)", want: "1h30m"},
+		{code: "(* 90s 2)", want: "3m"},
+		{code: "(/ 1h 15m)", want: "4"},
+		{code: "(< 250ms 1s)", want: "true"},
+		{code: "(== 60s 1m)", want: "true"},
+		{code: "(+ 1.5GiB 512MiB)", want: "2GiB"},
+		{code: "(> 10KB 10KiB)", want: "false"},
+		{code: "(size.bytes 1.5KB)", want: "1500"},
+		{code: "1.5B", wantErr: true},
+		{code: "(let start (time.parse \"2024-03-09T14:30:00Z\"))", want: "<nil>"},
+		{code: "(+ start 36h)", want: "2024-03-11T02:30:00Z"},
+		{code: "(- (+ start 2h) start)", want: "2h"},
+		{code: "(time.format (- start 1h) \"datetime\")", want: "2024-03-09 13:30:00"},
+		{code: "(time.format (time.in start \"America/New_York\") \"kitchen\")", want: "9:30AM"},
+		{code: "(== (time.in start \"Asia/Tokyo\") start)", want: "true"},
+		{code: "(< start (time.now))", want: "true"},
+		{code: "(time.unix (time.from_unix 1700000000))", want: "1700000000"},
+		{code: "(let timeout (fn [d duration] duration (* d 2)))", want: "<nil>"},
+		{code: "(timeout 5s)", want: "10s"},
+		{code: "(timeout 5)", wantErr: true},
+		{code: "(+ 5s 1KB)", wantErr: true},
+		{code: "(duration.parse \"1m30s\")", want: "1m30s"},
+		{code: "(duration.parse \"soon\")", wantRuntimeErr: true},
+		{code: fmt.Sprintf("(map (ls %s) (fn [f] [f .name]))", dir), want: "[\"notes.txt\"]"},
+		{code: fmt.Sprintf("[[(ls %s) 0] .size]", dir), want: "5B"},
+		{code: fmt.Sprintf("(< (time.since [(fs.stat %s) .mod_time]) 1h)", filepath.Join(dir, "notes.txt")), want: "true"},
+	}
+
+	s := NewSession()
+	for i, cell := range cells {
+		got, errs, err := s.Run([]byte(cell.code))
+		if (len(errs) > 0) != cell.wantErr {
+			t.Fatalf("cell %d Session.Run(%s) errs %v, wantErr %v", i, cell.code, errs, cell.wantErr)
+		}
+		if (err != nil) != cell.wantRuntimeErr {
+			t.Fatalf("cell %d Session.Run(%s) err %v, wantRuntimeErr %v", i, cell.code, err, cell.wantRuntimeErr)
+		}
+		if cell.wantErr || cell.wantRuntimeErr {
+			continue
+		}
+
+		if got.String() != cell.want {
+			t.Errorf("cell %d Session.Run(%s) = %s, want %s", i, cell.code, got.String(), cell.want)
+		}
+	}
+}
+
+func TestSession_Run_homeDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	s := NewSession()
+	got, errs, err := s.Run([]byte("(path.expand ~/notes.txt)"))
+	if len(errs) > 0 || err != nil {
+		t.Fatalf("Session.Run() errs %v, err %v", errs, err)
+	}
+
+	want := filepath.Join(home, "notes.txt")
+	if got.String() != want {
+		t.Errorf("Session.Run() = %s, want %s", got.String(), want)
+	}
+}
+
+func TestSession_Run_maxDepth(t *testing.T) {
+	s := NewSession()
+	if err := s.SetMaxDepth(0); err == nil {
+		t.Errorf("Session.SetMaxDepth(0) expected an error")
+	}
+	if err := s.SetMaxDepth(5); err != nil {
+		t.Fatalf("Session.SetMaxDepth(5) err %v", err)
+	}
+
+	_, errs, _ := s.Run([]byte("(let deep (fn [n int] int (match n (0 0) (else (+ 1 (deep (- n 1)))))))"))
+	if len(errs) > 0 {
+		t.Fatalf("Session.Run() errs %v", errs)
+	}
+
+	_, _, err := s.Run([]byte("(deep 4)"))
+	if err != nil {
+		t.Fatalf("Session.Run() err %v", err)
+	}
+
+	_, _, err = s.Run([]byte("(deep 5)"))
+	want := "stack overflow, the max call depth of 5 was exceeded"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Session.Run() err = %v, want prefix %q", err, want)
+	}
+}
@@ -2,6 +2,8 @@ package symbol
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/bjatkin/nook/script/ast"
 	"github.com/bjatkin/nook/script/builtin"
@@ -11,9 +13,10 @@ import (
 type entryKind int
 
 const (
-	valueKind = entryKind(0)
+	valueKind = entryKind(iota)
 	implKind
 	builtinKind
+	namespaceKind
 )
 
 type Entry interface {
@@ -40,14 +43,17 @@ type ImplEntry struct {
 
 func (e *ImplEntry) kind() entryKind { return implKind }
 
-func (e *ImplEntry) Match(args []ast.TypeExpr) (*ImplOverload, bool) {
+// Candidates returns every overload of the impl as a call target
+func (e *ImplEntry) Candidates() []ast.Overload {
+	candidates := []ast.Overload{}
 	for _, overload := range e.Overloads {
-		if types.MatchFunc(args, overload.Type) {
-			return &overload, true
-		}
+		candidates = append(candidates, ast.Overload{
+			Type: overload.Type,
+			Func: overload.Decl.Func,
+		})
 	}
 
-	return nil, false
+	return candidates
 }
 
 type BuiltinOverload struct {
@@ -62,19 +68,59 @@ type BuiltinEntry struct {
 
 func (e *BuiltinEntry) kind() entryKind { return builtinKind }
 
-func (e *BuiltinEntry) Match(args []ast.TypeExpr) (*BuiltinOverload, bool) {
+// Candidates returns every overload of the builtin as a call target
+func (e *BuiltinEntry) Candidates() []ast.Overload {
+	candidates := []ast.Overload{}
 	for _, overload := range e.Overloads {
-		if types.MatchFunc(args, overload.Type) {
-			return &overload, true
+		candidates = append(candidates, ast.Overload{
+			Type: overload.Type,
+			Func: overload.Decl,
+		})
+	}
+
+	return candidates
+}
+
+// NamespaceEntry is a named table of symbols declared with 'ns' or 'import'
+type NamespaceEntry struct {
+	Name  string
+	Table *Table
+
+	// Hash is the content hash of the imported file, it is empty for namespaces declared with 'ns'
+	Hash string
+}
+
+func (e *NamespaceEntry) kind() entryKind { return namespaceKind }
+
+// IsQualified reports whether a name refers to a symbol inside of a namespace (e.g. vec.add)
+func IsQualified(name string) bool {
+	return strings.Contains(name, ".")
+}
+
+// IsPrivate reports whether a qualified name refers to a private member of a namespace.
+// Members are private if their name starts with an '_'.
+func IsPrivate(name string) bool {
+	if !IsQualified(name) {
+		return false
+	}
+
+	segments := strings.Split(name, ".")
+	for _, segment := range segments[1:] {
+		if strings.HasPrefix(segment, "_") {
+			return true
 		}
 	}
 
-	return nil, false
+	return false
 }
 
 type Table struct {
 	parent   *Table
 	symboles map[string]Entry
+
+	// journal holds the entries that have been replaced since Begin was called
+	// so they can be restored by Rollback. A nil entry means the symbol did not exist.
+	journal map[string]Entry
 }
 
 func NewTable() *Table {
@@ -83,8 +129,63 @@ func NewTable() *Table {
 	}
 }
 
+// Begin starts a transaction on the table. All changes made to the table after
+// Begin is called can be undone by calling Rollback, or kept by calling Commit.
+// Only changes to this table are tracked, not changes to child scopes.
+func (t *Table) Begin() {
+	t.journal = map[string]Entry{}
+}
+
+// Commit keeps all the changes made to the table since Begin was called
+func (t *Table) Commit() {
+	t.journal = nil
+}
+
+// Rollback undoes all the changes made to the table since Begin was called
+func (t *Table) Rollback() {
+	for name, entry := range t.journal {
+		if entry == nil {
+			delete(t.symboles, name)
+			continue
+		}
+
+		t.symboles[name] = entry
+	}
+
+	t.journal = nil
+}
+
+// record saves the current entry for name in the journal so it can be restored later.
+// Only the first change to a symbol is recorded since that is the state Rollback restores.
+func (t *Table) record(name string) {
+	if t.journal == nil {
+		return
+	}
+	if _, ok := t.journal[name]; ok {
+		return
+	}
+
+	// overloads are appended in place so the entries need to be copied
+	switch entry := t.symboles[name].(type) {
+	case *ImplEntry:
+		t.journal[name] = &ImplEntry{
+			Name:      entry.Name,
+			Overloads: slices.Clone(entry.Overloads),
+		}
+	case *BuiltinEntry:
+		t.journal[name] = &BuiltinEntry{
+			Name:      entry.Name,
+			Overloads: slices.Clone(entry.Overloads),
+		}
+	default:
+		t.journal[name] = entry
+	}
+}
+
 func (t *Table) AddBuiltin(builtin builtin.Builtin) error {
 	name := builtin.Name
+	t.record(name)
+
 	entry, ok := t.symboles[name]
 	if !ok {
 		t.addNewBuiltin(builtin)
@@ -99,7 +200,8 @@ func (t *Table) AddBuiltin(builtin builtin.Builtin) error {
 	builtinEntry.Overloads = append(builtinEntry.Overloads, BuiltinOverload{
 		Type: builtin.Type,
 		Decl: &ast.Builtin{
-			Fn: builtin.Fn,
+			Type: builtin.Type,
+			Fn:   builtin.Fn,
 		},
 	})
 
@@ -114,7 +216,8 @@ func (t *Table) addNewBuiltin(builtin builtin.Builtin) {
 		Overloads: []BuiltinOverload{{
 			Type: builtin.Type,
 			Decl: &ast.Builtin{
-				Fn: builtin.Fn,
+				Type: builtin.Type,
+				Fn:   builtin.Fn,
 			},
 		}},
 	}
@@ -124,6 +227,8 @@ func (t *Table) addNewBuiltin(builtin builtin.Builtin) {
 
 func (t *Table) AddImpl(impl *ast.Impl) error {
 	name := impl.Identifier.Name
+	t.record(name)
+
 	entry, ok := t.symboles[name]
 	if !ok {
 		t.addNewImpl(impl)
@@ -135,6 +240,17 @@ func (t *Table) AddImpl(impl *ast.Impl) error {
 		return fmt.Errorf("entry already exists and is not an impl '%v'", entry)
 	}
 
+	// overloads with the same signature would always be ambiguous
+	for _, overload := range implEntry.Overloads {
+		if types.SameSignature(overload.Type, impl.Func.Type) {
+			return fmt.Errorf(
+				"impl '%s' already has an overload with the signature %s",
+				name,
+				types.Name(overload.Type),
+			)
+		}
+	}
+
 	implEntry.Overloads = append(implEntry.Overloads, ImplOverload{
 		Type: impl.Func.Type,
 		Decl: impl,
@@ -159,15 +275,21 @@ func (t *Table) addNewImpl(impl *ast.Impl) {
 
 func (t *Table) AddLet(let *ast.Let, letType ast.TypeExpr) error {
 	name := let.Identifier.Name
+	t.record(name)
+
 	entry, ok := t.symboles[name]
 	if !ok {
 		t.addNewLet(let, letType)
 		return nil
 	}
 
-	_, ok = entry.(*ValueEntry)
-	if !ok {
-		return fmt.Errorf("entry alread exists and is not a value '%v'", entry)
+	// a let can shadow a builtin in any scope, the linter warns about it instead
+	switch entry.(type) {
+	case *ValueEntry, *BuiltinEntry:
+	case *ImplEntry:
+		return fmt.Errorf("'%s' is an impl and can not be rebound with let", name)
+	default:
+		return fmt.Errorf("'%s' is a namespace and can not be rebound with let", name)
 	}
 
 	t.symboles[name] = &ValueEntry{
@@ -187,20 +309,135 @@ func (t *Table) addNewLet(let *ast.Let, letType ast.TypeExpr) {
 	}
 }
 
-func (t *Table) Lookup(name string) (Entry, bool) {
-	valueEntry, ok := t.lookupValueInScope(name)
-	if ok {
-		return valueEntry, ok
+// AddParam binds a function paramater in the current scope. Paramaters shadow
+// any existing entry with the same name.
+func (t *Table) AddParam(param ast.Param) {
+	name := param.Identifier.Name
+	t.record(name)
+
+	t.symboles[name] = &ValueEntry{
+		Name: name,
+		Type: param.Type,
 	}
+}
 
-	implEntry, ok := t.lookupImplInScope(name)
+// AddNamespace binds a namespace table in the current scope
+func (t *Table) AddNamespace(name string, namespace *Table) error {
+	t.record(name)
+
+	entry, ok := t.symboles[name]
 	if ok {
-		return implEntry, ok
+		if _, ok := entry.(*NamespaceEntry); !ok {
+			return fmt.Errorf("entry already exists and is not a namespace '%v'", entry)
+		}
 	}
 
-	builtinEntry, ok := t.lookupBuiltinInScope(name)
-	if ok {
-		return builtinEntry, ok
+	t.symboles[name] = &NamespaceEntry{
+		Name:  name,
+		Table: namespace,
+	}
+	return nil
+}
+
+// AddModule binds the namespace of an imported file in the current scope,
+// replacing any namespace that already has the same name
+func (t *Table) AddModule(name, hash string, namespace *Table) error {
+	err := t.AddNamespace(name, namespace)
+	if err != nil {
+		return err
+	}
+
+	t.symboles[name].(*NamespaceEntry).Hash = hash
+	return nil
+}
+
+// LookupModule finds the namespace of an imported file in the current scope
+func (t *Table) LookupModule(name string) (*NamespaceEntry, bool) {
+	entry, ok := t.symboles[name]
+	if !ok {
+		return nil, false
+	}
+
+	namespaceEntry, ok := entry.(*NamespaceEntry)
+	if !ok || namespaceEntry.Hash == "" {
+		return nil, false
+	}
+
+	return namespaceEntry, true
+}
+
+// LookupNamespace finds the table for a namespace. The name may be qualified to find nested namespaces.
+func (t *Table) LookupNamespace(name string) (*Table, bool) {
+	if IsQualified(name) {
+		namespace, member, ok := t.resolveQualified(name)
+		if !ok {
+			return nil, false
+		}
+
+		return namespace.lookupNamespaceInScope(member)
+	}
+
+	if entry, ok := t.lookupNamespaceInScope(name); ok {
+		return entry, true
+	}
+
+	if t.parent == nil {
+		return nil, false
+	}
+
+	return t.parent.LookupNamespace(name)
+}
+
+func (t *Table) lookupNamespaceInScope(name string) (*Table, bool) {
+	entry, ok := t.symboles[name]
+	if !ok {
+		return nil, false
+	}
+
+	namespaceEntry, ok := entry.(*NamespaceEntry)
+	if !ok {
+		return nil, false
+	}
+
+	return namespaceEntry.Table, true
+}
+
+// resolveQualified finds the namespace table that contains the final segment of a qualified name.
+// Private members can not be resolved.
+func (t *Table) resolveQualified(name string) (*Table, string, bool) {
+	if IsPrivate(name) {
+		return nil, "", false
+	}
+
+	segments := strings.Split(name, ".")
+	namespace, ok := t.LookupNamespace(segments[0])
+	if !ok {
+		return nil, "", false
+	}
+
+	for _, segment := range segments[1 : len(segments)-1] {
+		namespace, ok = namespace.lookupNamespaceInScope(segment)
+		if !ok {
+			return nil, "", false
+		}
+	}
+
+	return namespace, segments[len(segments)-1], true
+}
+
+func (t *Table) Lookup(name string) (Entry, bool) {
+	if IsQualified(name) {
+		namespace, member, ok := t.resolveQualified(name)
+		if !ok {
+			return nil, false
+		}
+
+		entry, ok := namespace.symboles[member]
+		return entry, ok
+	}
+
+	if entry, ok := t.symboles[name]; ok {
+		return entry, true
 	}
 
 	if t.parent == nil {
@@ -211,6 +448,15 @@ func (t *Table) Lookup(name string) (Entry, bool) {
 }
 
 func (t *Table) LookupValue(name string) (*ValueEntry, bool) {
+	if IsQualified(name) {
+		namespace, member, ok := t.resolveQualified(name)
+		if !ok {
+			return nil, false
+		}
+
+		return namespace.lookupValueInScope(member)
+	}
+
 	if entry, ok := t.lookupValueInScope(name); ok {
 		return entry, true
 	}
@@ -237,6 +483,15 @@ func (t *Table) lookupValueInScope(name string) (*ValueEntry, bool) {
 }
 
 func (t *Table) LookupImpl(name string) (*ImplEntry, bool) {
+	if IsQualified(name) {
+		namespace, member, ok := t.resolveQualified(name)
+		if !ok {
+			return nil, false
+		}
+
+		return namespace.lookupImplInScope(member)
+	}
+
 	if entry, ok := t.lookupImplInScope(name); ok {
 		return entry, true
 	}
@@ -263,6 +518,15 @@ func (t *Table) lookupImplInScope(name string) (*ImplEntry, bool) {
 }
 
 func (t *Table) LookupBuiltin(name string) (*BuiltinEntry, bool) {
+	if IsQualified(name) {
+		namespace, member, ok := t.resolveQualified(name)
+		if !ok {
+			return nil, false
+		}
+
+		return namespace.lookupBuiltinInScope(member)
+	}
+
 	if entry, ok := t.lookupBuiltinInScope(name); ok {
 		return entry, true
 	}
@@ -0,0 +1,71 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// SizeUnits are the units that can be used in size literals and the number of bytes in each.
+// Units with an 'i' are powers of 1024.
+var SizeUnits = map[string]int64{
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+}
+
+// SizeUnitNames are the names of the size units, longest first so a unit is never
+// matched when a longer unit that starts with it was written (e.g. KiB and not K)
+var SizeUnitNames = func() []string {
+	names := []string{}
+	for name := range SizeUnits {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+
+	return names
+}()
+
+// ParseSize parses a size written as a number followed by a unit (e.g. 10KB or 1.5GiB) into a
+// number of bytes. The size must be a whole number of bytes, so 1.5B is not a valid size.
+func ParseSize(text string) (int64, error) {
+	i := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '_'
+	})
+	if i <= 0 {
+		return 0, fmt.Errorf("invalid size '%s'", text)
+	}
+
+	unit, ok := SizeUnits[text[i:]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit '%s'", text[i:])
+	}
+
+	// the number is parsed exactly so fractional sizes like 1.1KB are not rounded
+	number, ok := new(big.Rat).SetString(strings.ReplaceAll(text[:i], "_", ""))
+	if !ok {
+		return 0, fmt.Errorf("invalid size '%s'", text)
+	}
+
+	bytes := number.Mul(number, new(big.Rat).SetInt64(unit))
+	if !bytes.IsInt() {
+		return 0, fmt.Errorf("size '%s' is not a whole number of bytes", text)
+	}
+	if !bytes.Num().IsInt64() {
+		return 0, fmt.Errorf("size '%s' is too large", text)
+	}
+
+	return bytes.Num().Int64(), nil
+}
@@ -13,6 +13,22 @@ const (
 
 	// Keywords and Symbols
 	Let
+	Fn
+	Impl
+	If
+	Else
+	Match
+	Do
+	Ns
+	Import
+	Macro
+	Quote
+	Quasiquote
+	Unquote
+	Splice
+	Try
+	And
+	Or
 	Nil
 	Plus
 	Minus
@@ -23,6 +39,7 @@ const (
 	GreaterEqual
 	LessEqual
 	Equal
+	NotEqual
 	OpenParen
 	CloseParen
 	OpenCurly
@@ -32,13 +49,20 @@ const (
 
 	// Type Keywords
 	IntType
+	BigIntType
+	NumberType
 	FloatType
 	BoolType
 	StringType
 	PathType
 	FlagType
+	RegexType
+	DurationType
+	TimeType
+	SizeType
 	AtomType
 	CommandType
+	ErrorType
 	DictType
 	TupleType
 	SliceType
@@ -52,8 +76,13 @@ const (
 	String
 	Path
 	Flag
+	Regex
+	Heredoc
+	Duration
+	Size
 	Atom
 	Command
+	Property
 )
 
 func (k Kind) String() string {
@@ -68,6 +97,38 @@ func (k Kind) String() string {
 		return "Comment"
 	case Let:
 		return "Let"
+	case Fn:
+		return "Fn"
+	case Impl:
+		return "Impl"
+	case If:
+		return "If"
+	case Else:
+		return "Else"
+	case Match:
+		return "Match"
+	case Do:
+		return "Do"
+	case Ns:
+		return "Ns"
+	case Import:
+		return "Import"
+	case Macro:
+		return "Macro"
+	case Quote:
+		return "Quote"
+	case Quasiquote:
+		return "Quasiquote"
+	case Unquote:
+		return "Unquote"
+	case Splice:
+		return "Splice"
+	case Try:
+		return "Try"
+	case And:
+		return "And"
+	case Or:
+		return "Or"
 	case Nil:
 		return "Nil"
 	case Plus:
@@ -88,6 +149,8 @@ func (k Kind) String() string {
 		return "LessEqual"
 	case Equal:
 		return "Equal"
+	case NotEqual:
+		return "NotEqual"
 	case OpenParen:
 		return "OpenParen"
 	case CloseParen:
@@ -102,6 +165,10 @@ func (k Kind) String() string {
 		return "CloseSquare"
 	case IntType:
 		return "IntType"
+	case BigIntType:
+		return "BigIntType"
+	case NumberType:
+		return "NumberType"
 	case FloatType:
 		return "FloatType"
 	case BoolType:
@@ -112,10 +179,20 @@ func (k Kind) String() string {
 		return "PathType"
 	case FlagType:
 		return "FlagType"
+	case RegexType:
+		return "RegexType"
+	case DurationType:
+		return "DurationType"
+	case TimeType:
+		return "TimeType"
+	case SizeType:
+		return "SizeType"
 	case AtomType:
 		return "AtomType"
 	case CommandType:
 		return "CommandType"
+	case ErrorType:
+		return "ErrorType"
 	case DictType:
 		return "DictType"
 	case TupleType:
@@ -138,10 +215,20 @@ func (k Kind) String() string {
 		return "Path"
 	case Flag:
 		return "Flag"
+	case Regex:
+		return "Regex"
+	case Heredoc:
+		return "Heredoc"
+	case Duration:
+		return "Duration"
+	case Size:
+		return "Size"
 	case Atom:
 		return "Atom"
 	case Command:
 		return "Command"
+	case Property:
+		return "Property"
 	case Whitespace:
 		return "Whitespace"
 	default:
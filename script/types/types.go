@@ -1,6 +1,9 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/bjatkin/nook/script/ast"
 )
 
@@ -11,13 +14,28 @@ func Match(got, want ast.TypeExpr) bool {
 		return true
 	}
 
+	if _, ok := want.(*ast.NumberType); ok && IsNumber(got) {
+		return true
+	}
+
+	// type variables can match any type, Bind checks that they are used consistently
+	if _, ok := want.(*ast.TypeVar); ok {
+		return true
+	}
+
 	switch got := got.(type) {
-	case *ast.TraitType:
+	case *ast.TraitType, *ast.TypeVar:
 		// TODO: again all traits are empty for now
 		return true
+	case *ast.NumberType:
+		// the number could be any kind of number until runtime
+		return IsNumber(want)
 	case *ast.IntType:
 		_, ok := want.(*ast.IntType)
 		return ok
+	case *ast.BigIntType:
+		_, ok := want.(*ast.BigIntType)
+		return ok
 	case *ast.FloatType:
 		_, ok := want.(*ast.FloatType)
 		return ok
@@ -36,9 +54,58 @@ func Match(got, want ast.TypeExpr) bool {
 	case *ast.FlagType:
 		_, ok := want.(*ast.FlagType)
 		return ok
+	case *ast.RegexType:
+		_, ok := want.(*ast.RegexType)
+		return ok
+	case *ast.DurationType:
+		_, ok := want.(*ast.DurationType)
+		return ok
+	case *ast.TimeType:
+		_, ok := want.(*ast.TimeType)
+		return ok
+	case *ast.SizeType:
+		_, ok := want.(*ast.SizeType)
+		return ok
 	case *ast.NoneType:
 		_, ok := want.(*ast.NoneType)
 		return ok
+	case *ast.ErrorType:
+		_, ok := want.(*ast.ErrorType)
+		return ok
+	case *ast.TupleType:
+		want, ok := want.(*ast.TupleType)
+		if !ok || len(got.Types) != len(want.Types) {
+			return false
+		}
+
+		for i := range got.Types {
+			if !Match(got.Types[i], want.Types[i]) {
+				return false
+			}
+		}
+
+		return true
+	case *ast.SliceType:
+		want, ok := want.(*ast.SliceType)
+		if !ok {
+			return false
+		}
+
+		return Match(Elem(got), Elem(want))
+	case *ast.DictType:
+		want, ok := want.(*ast.DictType)
+		if !ok || len(got.Fields) != len(want.Fields) {
+			return false
+		}
+
+		for _, wantField := range want.Fields {
+			gotField, ok := Field(got, wantField.Name)
+			if !ok || !Match(gotField, wantField.Type) {
+				return false
+			}
+		}
+
+		return true
 	case *ast.FuncType:
 		want, ok := want.(*ast.FuncType)
 		if !ok {
@@ -69,8 +136,8 @@ func Match(got, want ast.TypeExpr) bool {
 }
 
 func MatchArity(args []ast.TypeExpr, funcType *ast.FuncType) bool {
-	if len(funcType.Params.Params) == 0 && len(args) == 0 {
-		return true
+	if len(funcType.Params.Params) == 0 {
+		return len(args) == 0
 	}
 
 	params := funcType.Params.Params
@@ -125,3 +192,442 @@ func MatchFunc(args []ast.TypeExpr, funcType *ast.FuncType) bool {
 
 	return true
 }
+
+// Unify finds a common type for two types. If either type is a trait the result
+// is a trait, otherwise both types must match each other exactly.
+func Unify(a, b ast.TypeExpr) (ast.TypeExpr, bool) {
+	if _, ok := a.(*ast.TraitType); ok {
+		return a, true
+	}
+	if _, ok := b.(*ast.TraitType); ok {
+		return b, true
+	}
+
+	if Match(a, b) && Match(b, a) {
+		return a, true
+	}
+
+	return nil, false
+}
+
+// Name returns the name of a type as it would be written in nook script
+func Name(typeExpr ast.TypeExpr) string {
+	switch typeExpr := typeExpr.(type) {
+	case *ast.IntType:
+		return "int"
+	case *ast.BigIntType:
+		return "bigint"
+	case *ast.NumberType:
+		return "number"
+	case *ast.FloatType:
+		return "float"
+	case *ast.BoolType:
+		return "bool"
+	case *ast.AtomType:
+		return "atom"
+	case *ast.StringType:
+		return "str"
+	case *ast.PathType:
+		return "path"
+	case *ast.FlagType:
+		return "flag"
+	case *ast.RegexType:
+		return "regex"
+	case *ast.DurationType:
+		return "duration"
+	case *ast.TimeType:
+		return "time"
+	case *ast.SizeType:
+		return "size"
+	case *ast.NoneType:
+		return "none"
+	case *ast.ErrorType:
+		return "error"
+	case *ast.CommandType:
+		return "cmd"
+	case *ast.TraitType:
+		// TODO: this should be more specific than just an any
+		return "any"
+	case *ast.TypeVar:
+		return typeExpr.Name
+	case *ast.VariadicType:
+		return Name(typeExpr.Type) + "..."
+	case *ast.TupleType:
+		items := []string{}
+		for _, item := range typeExpr.Types {
+			items = append(items, Name(item))
+		}
+		return "<" + strings.Join(items, " ") + ">"
+	case *ast.SliceType:
+		return "[" + Name(Elem(typeExpr)) + "]"
+	case *ast.DictType:
+		fields := []string{}
+		for _, field := range typeExpr.Fields {
+			fields = append(fields, "."+field.Name+" "+Name(field.Type))
+		}
+		return "<" + strings.Join(fields, " ") + ">"
+	case *ast.FuncType:
+		params := []string{}
+		if typeExpr.Params != nil {
+			for _, param := range typeExpr.Params.Params {
+				params = append(params, Name(param.Type))
+			}
+		}
+		return "<fn [" + strings.Join(params, " ") + "] " + Name(typeExpr.Return) + ">"
+	default:
+		return "unknown"
+	}
+}
+
+// Field returns the type of a field in a dict type
+func Field(dict *ast.DictType, name string) (ast.TypeExpr, bool) {
+	for _, field := range dict.Fields {
+		if field.Name == name {
+			return field.Type, true
+		}
+	}
+
+	return nil, false
+}
+
+// Elem returns the element type of a slice, slices whose element type is not known hold any value
+func Elem(slice *ast.SliceType) ast.TypeExpr {
+	if slice.Elem == nil {
+		return &ast.TraitType{}
+	}
+
+	return slice.Elem
+}
+
+// IsGeneric reports whether a type contains any type variables
+func IsGeneric(typeExpr ast.TypeExpr) bool {
+	switch typeExpr := typeExpr.(type) {
+	case *ast.TypeVar:
+		return true
+	case *ast.SliceType:
+		return typeExpr.Elem != nil && IsGeneric(typeExpr.Elem)
+	case *ast.VariadicType:
+		return IsGeneric(typeExpr.Type)
+	case *ast.TupleType:
+		for _, item := range typeExpr.Types {
+			if IsGeneric(item) {
+				return true
+			}
+		}
+	case *ast.FuncType:
+		if typeExpr.Params == nil {
+			return IsGeneric(typeExpr.Return)
+		}
+		for _, param := range typeExpr.Params.Params {
+			if IsGeneric(param.Type) {
+				return true
+			}
+		}
+		return IsGeneric(typeExpr.Return)
+	}
+
+	return false
+}
+
+// Bind finds the type of each type variable in a generic function from the types of the
+// arguments it is called with. Every use of a type variable must have the same type.
+func Bind(funcType *ast.FuncType, args []ast.TypeExpr) (map[string]ast.TypeExpr, error) {
+	bindings := map[string]ast.TypeExpr{}
+	for i, arg := range args {
+		err := bind(ParamType(funcType, i), arg, bindings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bindings, nil
+}
+
+// Binds reports whether any of the overloads can be called with the arguments. Generic overloads
+// only match if their type variables can be bound, e.g. (T T) does not match an int and a str.
+func Binds(args []ast.TypeExpr, overloads []*ast.FuncType, matches []int) bool {
+	for _, i := range matches {
+		if !IsGeneric(overloads[i]) {
+			return true
+		}
+		if _, err := Bind(overloads[i], args); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func bind(param, arg ast.TypeExpr, bindings map[string]ast.TypeExpr) error {
+	switch param := param.(type) {
+	case *ast.TypeVar:
+		bound, ok := bindings[param.Name]
+		switch {
+		case !ok || IsTrait(bound):
+			bindings[param.Name] = arg
+		case IsTrait(arg):
+			// an unknown type does not tell us anything new about the variable
+		case !Match(arg, bound) || !Match(bound, arg):
+			return fmt.Errorf("expected '%s' but got '%s' for %s", Name(bound), Name(arg), param.Name)
+		}
+	case *ast.SliceType:
+		if arg, ok := arg.(*ast.SliceType); ok {
+			return bind(Elem(param), Elem(arg), bindings)
+		}
+	case *ast.TupleType:
+		arg, ok := arg.(*ast.TupleType)
+		if !ok || len(arg.Types) != len(param.Types) {
+			return nil
+		}
+
+		for i := range param.Types {
+			if err := bind(param.Types[i], arg.Types[i], bindings); err != nil {
+				return err
+			}
+		}
+	case *ast.FuncType:
+		arg, ok := arg.(*ast.FuncType)
+		if !ok || len(arg.Params.Params) != len(param.Params.Params) {
+			return nil
+		}
+
+		for i := range param.Params.Params {
+			if err := bind(param.Params.Params[i].Type, arg.Params.Params[i].Type, bindings); err != nil {
+				return err
+			}
+		}
+		return bind(param.Return, arg.Return, bindings)
+	}
+
+	return nil
+}
+
+// Substitute replaces the type variables in a type with the types they are bound to.
+// Type variables that were never bound can hold any value.
+func Substitute(typeExpr ast.TypeExpr, bindings map[string]ast.TypeExpr) ast.TypeExpr {
+	switch typeExpr := typeExpr.(type) {
+	case *ast.TypeVar:
+		if bound, ok := bindings[typeExpr.Name]; ok {
+			return bound
+		}
+		return &ast.TraitType{}
+	case *ast.SliceType:
+		return &ast.SliceType{Tok: typeExpr.Tok, Elem: Substitute(Elem(typeExpr), bindings)}
+	case *ast.VariadicType:
+		return &ast.VariadicType{Type: Substitute(typeExpr.Type, bindings)}
+	case *ast.TupleType:
+		tupleType := &ast.TupleType{}
+		for _, item := range typeExpr.Types {
+			tupleType.Types = append(tupleType.Types, Substitute(item, bindings))
+		}
+		return tupleType
+	case *ast.FuncType:
+		params := &ast.ParamList{}
+		for _, param := range typeExpr.Params.Params {
+			params.Params = append(params.Params, ast.Param{
+				Identifier: param.Identifier,
+				Type:       Substitute(param.Type, bindings),
+			})
+		}
+		return &ast.FuncType{Params: params, Return: Substitute(typeExpr.Return, bindings)}
+	default:
+		return typeExpr
+	}
+}
+
+// IsTrait reports whether a type is a trait, meaning the concrete type is not known until runtime
+func IsTrait(typeExpr ast.TypeExpr) bool {
+	switch typeExpr.(type) {
+	case *ast.TraitType, *ast.NumberType:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsNumber reports whether a type is one of the number types
+func IsNumber(typeExpr ast.TypeExpr) bool {
+	switch typeExpr.(type) {
+	case *ast.IntType, *ast.BigIntType, *ast.FloatType, *ast.NumberType:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsStringer reports whether values of a type can be converted to a string, stringers
+// are the types that can be interpolated into a string (e.g. "${1}" or "${./file.txt}")
+func IsStringer(typeExpr ast.TypeExpr) bool {
+	switch typeExpr.(type) {
+	case *ast.StringType, *ast.IntType, *ast.BigIntType, *ast.FloatType, *ast.NumberType,
+		*ast.BoolType, *ast.AtomType, *ast.PathType, *ast.FlagType, *ast.RegexType,
+		*ast.DurationType, *ast.TimeType, *ast.SizeType, *ast.NoneType, *ast.TraitType:
+		return true
+	default:
+		return false
+	}
+}
+
+// Promote converts int arguments to floats so mixed int and float calls can use the float
+// overload of a function. It reports false if there were no ints to promote.
+func Promote(args []ast.TypeExpr) ([]ast.TypeExpr, bool) {
+	promoted := []ast.TypeExpr{}
+	ok := false
+	for _, arg := range args {
+		if _, isInt := arg.(*ast.IntType); isInt {
+			promoted = append(promoted, &ast.FloatType{})
+			ok = true
+			continue
+		}
+
+		promoted = append(promoted, arg)
+	}
+
+	return promoted, ok
+}
+
+// Convertible reports whether a value of the from type can be converted to the to type
+// with an explicit conversion (e.g. {float 1})
+func Convertible(from, to ast.TypeExpr) bool {
+	if Match(from, to) {
+		return true
+	}
+
+	switch from.(type) {
+	case *ast.IntType:
+		switch to.(type) {
+		case *ast.FloatType, *ast.BigIntType:
+			return true
+		}
+	case *ast.StringType:
+		switch to.(type) {
+		case *ast.PathType, *ast.FlagType, *ast.RegexType:
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve finds the overloads that should be used for a call with the given arguments.
+// If all the argument types are known only the most specific overloads are returned, so
+// more than one overload means the call is ambiguous. If any argument type is a trait
+// every matching overload is returned since the best match can only be found at runtime.
+func Resolve(args []ast.TypeExpr, overloads []*ast.FuncType) []int {
+	matches := []int{}
+	for i, overload := range overloads {
+		if MatchFunc(args, overload) {
+			matches = append(matches, i)
+		}
+	}
+
+	for _, arg := range args {
+		if IsTrait(arg) {
+			return matches
+		}
+	}
+
+	best := []int{}
+	for _, i := range matches {
+		dominated := false
+		for _, j := range matches {
+			if i != j && moreSpecific(overloads[j], overloads[i], len(args)) {
+				dominated = true
+				break
+			}
+		}
+
+		if !dominated {
+			best = append(best, i)
+		}
+	}
+
+	return best
+}
+
+// SameSignature reports whether two functions accept exactly the same arguments
+func SameSignature(a, b *ast.FuncType) bool {
+	aParams := a.Params.Params
+	bParams := b.Params.Params
+	if len(aParams) != len(bParams) {
+		return false
+	}
+
+	for i := range aParams {
+		if !covers(aParams[i].Type, bParams[i].Type) || !covers(bParams[i].Type, aParams[i].Type) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// moreSpecific reports whether a is strictly more specific than b for a call with argc arguments.
+// If both functions are equally specific a non-variadic function is more specific than a variadic one.
+func moreSpecific(a, b *ast.FuncType, argc int) bool {
+	aNarrower := true
+	bNarrower := true
+	for i := 0; i < argc; i++ {
+		aParam := ParamType(a, i)
+		bParam := ParamType(b, i)
+		if !covers(bParam, aParam) {
+			aNarrower = false
+		}
+		if !covers(aParam, bParam) {
+			bNarrower = false
+		}
+	}
+
+	if !aNarrower {
+		return false
+	}
+	if !bNarrower {
+		return true
+	}
+
+	return !isVariadic(a) && isVariadic(b)
+}
+
+// ParamType returns the type of the paramater that the i'th argument will be bound to
+func ParamType(funcType *ast.FuncType, i int) ast.TypeExpr {
+	params := funcType.Params.Params
+	last := params[len(params)-1].Type
+	if variadic, ok := last.(*ast.VariadicType); ok && i >= len(params)-1 {
+		return variadic.Type
+	}
+
+	return params[i].Type
+}
+
+func isVariadic(funcType *ast.FuncType) bool {
+	params := funcType.Params.Params
+	if len(params) == 0 {
+		return false
+	}
+
+	_, ok := params[len(params)-1].Type.(*ast.VariadicType)
+	return ok
+}
+
+// covers reports whether every value of the got type is also a value of the want type
+func covers(want, got ast.TypeExpr) bool {
+	_, wantVar := want.(*ast.TypeVar)
+	_, gotVar := got.(*ast.TypeVar)
+	if IsTrait(want) || wantVar {
+		return true
+	}
+	if IsTrait(got) || gotVar {
+		return false
+	}
+
+	wantVariadic, wantOk := want.(*ast.VariadicType)
+	gotVariadic, gotOk := got.(*ast.VariadicType)
+	if wantOk != gotOk {
+		return false
+	}
+	if wantOk {
+		return covers(wantVariadic.Type, gotVariadic.Type)
+	}
+
+	return Match(got, want)
+}
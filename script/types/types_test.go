@@ -0,0 +1,166 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bjatkin/nook/script/ast"
+)
+
+func funcType(params ...ast.TypeExpr) *ast.FuncType {
+	paramList := &ast.ParamList{}
+	for _, param := range params {
+		paramList.Params = append(paramList.Params, ast.Param{Type: param})
+	}
+
+	return &ast.FuncType{Params: paramList, Return: &ast.TraitType{}}
+}
+
+func TestResolve(t *testing.T) {
+	type args struct {
+		args      []ast.TypeExpr
+		overloads []*ast.FuncType
+	}
+	tests := []struct {
+		name string
+		args args
+		want []int
+	}{
+		{
+			name: "no match",
+			args: args{
+				args:      []ast.TypeExpr{&ast.StringType{}},
+				overloads: []*ast.FuncType{funcType(&ast.IntType{})},
+			},
+			want: []int{},
+		},
+		{
+			name: "typed overload is more specific than untyped",
+			args: args{
+				args: []ast.TypeExpr{&ast.IntType{}, &ast.IntType{}},
+				overloads: []*ast.FuncType{
+					funcType(&ast.TraitType{}, &ast.TraitType{}),
+					funcType(&ast.IntType{}, &ast.IntType{}),
+				},
+			},
+			want: []int{1},
+		},
+		{
+			name: "declaration order does not matter",
+			args: args{
+				args: []ast.TypeExpr{&ast.IntType{}, &ast.IntType{}},
+				overloads: []*ast.FuncType{
+					funcType(&ast.IntType{}, &ast.IntType{}),
+					funcType(&ast.TraitType{}, &ast.TraitType{}),
+				},
+			},
+			want: []int{0},
+		},
+		{
+			name: "equally specific overloads are ambiguous",
+			args: args{
+				args: []ast.TypeExpr{&ast.IntType{}, &ast.IntType{}},
+				overloads: []*ast.FuncType{
+					funcType(&ast.IntType{}, &ast.TraitType{}),
+					funcType(&ast.TraitType{}, &ast.IntType{}),
+				},
+			},
+			want: []int{0, 1},
+		},
+		{
+			name: "fixed arity is more specific than variadic",
+			args: args{
+				args: []ast.TypeExpr{&ast.IntType{}, &ast.IntType{}},
+				overloads: []*ast.FuncType{
+					funcType(&ast.VariadicType{Type: &ast.IntType{}}),
+					funcType(&ast.IntType{}, &ast.IntType{}),
+				},
+			},
+			want: []int{1},
+		},
+		{
+			name: "unknown arguments return every match",
+			args: args{
+				args: []ast.TypeExpr{&ast.TraitType{}},
+				overloads: []*ast.FuncType{
+					funcType(&ast.IntType{}),
+					funcType(&ast.FloatType{}),
+					funcType(&ast.IntType{}, &ast.IntType{}),
+				},
+			},
+			want: []int{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(tt.args.args, tt.args.overloads); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	tVar := &ast.TypeVar{Name: "T"}
+	mapType := &ast.FuncType{
+		Params: &ast.ParamList{Params: []ast.Param{
+			{Type: &ast.SliceType{Elem: tVar}},
+			{Type: funcType(tVar)},
+		}},
+	}
+
+	type args struct {
+		funcType *ast.FuncType
+		args     []ast.TypeExpr
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]ast.TypeExpr
+		wantErr bool
+	}{
+		{
+			name: "bound from the slice",
+			args: args{
+				funcType: mapType,
+				args:     []ast.TypeExpr{&ast.SliceType{Elem: &ast.IntType{}}, funcType(&ast.IntType{})},
+			},
+			want: map[string]ast.TypeExpr{"T": &ast.IntType{}},
+		},
+		{
+			name: "unknown types do not change the binding",
+			args: args{
+				funcType: mapType,
+				args:     []ast.TypeExpr{&ast.SliceType{Elem: &ast.IntType{}}, funcType(&ast.TraitType{})},
+			},
+			want: map[string]ast.TypeExpr{"T": &ast.IntType{}},
+		},
+		{
+			name: "unknown types are replaced",
+			args: args{
+				funcType: mapType,
+				args:     []ast.TypeExpr{&ast.SliceType{}, funcType(&ast.StringType{})},
+			},
+			want: map[string]ast.TypeExpr{"T": &ast.StringType{}},
+		},
+		{
+			name: "conflicting types",
+			args: args{
+				funcType: mapType,
+				args:     []ast.TypeExpr{&ast.SliceType{Elem: &ast.IntType{}}, funcType(&ast.StringType{})},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Bind(tt.args.funcType, tt.args.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Bind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
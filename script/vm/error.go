@@ -0,0 +1,17 @@
+package vm
+
+import (
+	"errors"
+
+	"github.com/bjatkin/nook/script/builtin"
+)
+
+// toError converts a go error into a nook script error value
+func toError(err error) *builtin.Error {
+	var nookErr *builtin.Error
+	if errors.As(err, &nookErr) {
+		return nookErr
+	}
+
+	return &builtin.Error{Message: err.Error()}
+}
@@ -1,6 +1,18 @@
 package vm
 
-import "fmt"
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/builtin"
+	"github.com/bjatkin/nook/script/types"
+)
 
 type Kind int64
 
@@ -15,6 +27,16 @@ const (
 	Flag
 	None
 	Func
+	Namespace
+	Err
+	Tuple
+	Dict
+	BigInt
+	Slice
+	Regex
+	Duration
+	Time
+	Size
 )
 
 func (r Kind) String() string {
@@ -37,6 +59,26 @@ func (r Kind) String() string {
 		return "none"
 	case Func:
 		return "fn"
+	case Namespace:
+		return "ns"
+	case Err:
+		return "error"
+	case Tuple:
+		return "tuple"
+	case Dict:
+		return "dict"
+	case BigInt:
+		return "bigint"
+	case Slice:
+		return "slice"
+	case Regex:
+		return "regex"
+	case Duration:
+		return "duration"
+	case Time:
+		return "time"
+	case Size:
+		return "size"
 	default:
 		return "untyped"
 	}
@@ -44,13 +86,144 @@ func (r Kind) String() string {
 
 var NoneValue = Value{value: nil, kind: None}
 
+// Closure is a function value along with the scope it was declared in
+type Closure struct {
+	Func  *ast.Func
+	scope *scope
+
+	// path is the name of the source the function was declared in
+	path string
+}
+
+func (c *Closure) String() string {
+	params := []string{}
+	for _, param := range c.Func.Type.Params.Params {
+		params = append(params, param.Identifier.Name)
+	}
+
+	return "<fn [" + strings.Join(params, " ") + "]>"
+}
+
 type Value struct {
 	value any
 	kind  Kind
+
+	// names is the order of a dict's fields, dicts without names use sorted order
+	names []string
 }
 
 func (v *Value) String() string {
-	return fmt.Sprint(v.value)
+	switch v.kind {
+	case Tuple:
+		items := []string{}
+		for _, item := range v.value.([]Value) {
+			items = append(items, item.literal())
+		}
+		return "{" + strings.Join(items, " ") + "}"
+	case Slice:
+		items := []string{}
+		for _, item := range v.value.([]Value) {
+			items = append(items, item.literal())
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	case Dict:
+		fields := v.value.(map[string]Value)
+		items := []string{}
+		for _, name := range sortedKeys(fields) {
+			field := fields[name]
+			items = append(items, "."+name+" "+field.literal())
+		}
+		return "{" + strings.Join(items, " ") + "}"
+	case Duration:
+		return builtin.FormatDuration(v.value.(time.Duration))
+	case Time:
+		return v.value.(time.Time).Format(time.RFC3339)
+	case Regex:
+		pattern := v.value.(*regexp.Regexp).String()
+		return "#/" + strings.ReplaceAll(pattern, "/", `\/`) + "/"
+	default:
+		return fmt.Sprint(v.value)
+	}
+}
+
+// literal formats the value the way it would be written in nook script,
+// it is used to show the values inside of tuples and dicts
+func (v *Value) literal() string {
+	if v.kind == String {
+		return strconv.Quote(v.Str())
+	}
+
+	return v.String()
+}
+
+// Table returns the header and rows of a value that can be shown as a table. Slices of dicts
+// that all have the same fields use the field names as the header, and slices of tuples that
+// all have the same length have no header. It reports false for any other value.
+func (v *Value) Table() ([]string, [][]string, bool) {
+	if v.kind != Slice {
+		return nil, nil, false
+	}
+
+	items := v.value.([]Value)
+	if len(items) == 0 {
+		return nil, nil, false
+	}
+
+	// names are the sorted field names of the first row, every row must have the same fields
+	var header, names []string
+	rows := [][]string{}
+	for i, item := range items {
+		row := []string{}
+		switch item.kind {
+		case Dict:
+			fields := item.value.(map[string]Value)
+			if i == 0 {
+				header, names = item.fieldNames(), sortedKeys(fields)
+			}
+			if header == nil || !slices.Equal(sortedKeys(fields), names) {
+				return nil, nil, false
+			}
+
+			for _, name := range header {
+				field := fields[name]
+				row = append(row, field.String())
+			}
+		case Tuple:
+			for _, field := range item.value.([]Value) {
+				row = append(row, field.String())
+			}
+			if header != nil || (i > 0 && len(row) != len(rows[0])) {
+				return nil, nil, false
+			}
+		default:
+			return nil, nil, false
+		}
+
+		rows = append(rows, row)
+	}
+
+	return header, rows, true
+}
+
+// fieldNames returns the field names of a dict in the order they were parsed in, or in sorted order
+// if the dict does not keep its order
+func (v *Value) fieldNames() []string {
+	if v.names != nil {
+		return v.names
+	}
+
+	return sortedKeys(v.value.(map[string]Value))
+}
+
+// sortedKeys returns the field names of a dict in a stable order
+func sortedKeys(fields map[string]Value) []string {
+	keys := []string{}
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
 }
 
 func (v *Value) Value() any {
@@ -72,3 +245,394 @@ func (v *Value) Float() float64 {
 func (v *Value) Kind() Kind {
 	return v.kind
 }
+
+// Equal reports whether two values have the same kind and value.
+// Tuples and dicts are equal if all of their items are equal.
+func (v *Value) Equal(other Value) bool {
+	if v.kind != other.kind {
+		return false
+	}
+
+	switch v.kind {
+	case Tuple, Slice:
+		items, otherItems := v.value.([]Value), other.value.([]Value)
+		return slices.EqualFunc(items, otherItems, func(a, b Value) bool { return a.Equal(b) })
+	case Dict:
+		fields, otherFields := v.value.(map[string]Value), other.value.(map[string]Value)
+		if len(fields) != len(otherFields) {
+			return false
+		}
+
+		for name, field := range fields {
+			otherField, ok := otherFields[name]
+			if !ok || !field.Equal(otherField) {
+				return false
+			}
+		}
+
+		return true
+	case BigInt:
+		return v.value.(*big.Int).Cmp(other.value.(*big.Int)) == 0
+	case Regex:
+		return v.value.(*regexp.Regexp).String() == other.value.(*regexp.Regexp).String()
+	case Time:
+		return v.value.(time.Time).Equal(other.value.(time.Time))
+	default:
+		return v.value == other.value
+	}
+}
+
+// Type returns the nook script type of the value
+func (v *Value) Type() ast.TypeExpr {
+	switch v.kind {
+	case Int:
+		return &ast.IntType{}
+	case BigInt:
+		return &ast.BigIntType{}
+	case Float:
+		return &ast.FloatType{}
+	case Bool:
+		return &ast.BoolType{}
+	case Atom:
+		return &ast.AtomType{}
+	case String:
+		return &ast.StringType{}
+	case Path:
+		return &ast.PathType{}
+	case Flag:
+		return &ast.FlagType{}
+	case Regex:
+		return &ast.RegexType{}
+	case Duration:
+		return &ast.DurationType{}
+	case Time:
+		return &ast.TimeType{}
+	case Size:
+		return &ast.SizeType{}
+	case None:
+		return &ast.NoneType{}
+	case Err:
+		return &ast.ErrorType{}
+	case Func:
+		return v.value.(*Closure).Func.Type
+	case Tuple:
+		tupleType := &ast.TupleType{}
+		for _, item := range v.value.([]Value) {
+			tupleType.Types = append(tupleType.Types, item.Type())
+		}
+		return tupleType
+	case Slice:
+		// the element type is only known if all the items have the same type
+		var elem ast.TypeExpr
+		for _, item := range v.value.([]Value) {
+			itemType := item.Type()
+			if elem != nil && !types.Match(itemType, elem) {
+				return &ast.SliceType{}
+			}
+			elem = itemType
+		}
+		return &ast.SliceType{Elem: elem}
+	case Dict:
+		fields := v.value.(map[string]Value)
+		dictType := &ast.DictType{}
+		for _, name := range sortedKeys(fields) {
+			field := fields[name]
+			dictType.Fields = append(dictType.Fields, ast.FieldType{Name: name, Type: field.Type()})
+		}
+		return dictType
+	default:
+		return &ast.TraitType{}
+	}
+}
+
+// toGo converts a value into the go value that is passed to builtin functions.
+// Slices and tuples become []any, dicts become a builtin.Dict and functions become
+// a *builtin.Func that runs the function in the vm. The type is the type of the
+// builtin's paramater, it is used to convert the arguments of functions back into values.
+func (vm *VM) toGo(value Value, typeExpr ast.TypeExpr) any {
+	switch value.kind {
+	case Tuple, Slice:
+		tupleType, _ := typeExpr.(*ast.TupleType)
+		var elem ast.TypeExpr = &ast.TraitType{}
+		if sliceType, ok := typeExpr.(*ast.SliceType); ok {
+			elem = types.Elem(sliceType)
+		}
+
+		items := []any{}
+		for i, item := range value.value.([]Value) {
+			itemType := elem
+			if tupleType != nil && i < len(tupleType.Types) {
+				itemType = tupleType.Types[i]
+			}
+			items = append(items, vm.toGo(item, itemType))
+		}
+		return items
+	case Dict:
+		dictType, _ := typeExpr.(*ast.DictType)
+		fields := map[string]any{}
+		for name, field := range value.value.(map[string]Value) {
+			var fieldType ast.TypeExpr = &ast.TraitType{}
+			if dictType != nil {
+				if t, ok := types.Field(dictType, name); ok {
+					fieldType = t
+				}
+			}
+			fields[name] = vm.toGo(field, fieldType)
+		}
+		return builtin.Dict{Names: value.fieldNames(), Fields: fields}
+	case Func:
+		closure := value.value.(*Closure)
+		funcType, _ := typeExpr.(*ast.FuncType)
+		return &builtin.Func{
+			Value: closure,
+			Call: func(args ...any) (any, error) {
+				return vm.callBack(closure, funcType, args)
+			},
+		}
+	default:
+		return value.value
+	}
+}
+
+// callBack calls a closure that was passed to a builtin. The arguments are converted using the
+// types in the closures signature, or in the builtin's signature if the closure's param is untyped.
+func (vm *VM) callBack(closure *Closure, funcType *ast.FuncType, args []any) (any, error) {
+	params := closure.Func.Type.Params.Params
+	values := []Value{}
+	for i, arg := range args {
+		var paramType ast.TypeExpr = &ast.TraitType{}
+		if i < len(params) && params[i].Type != nil {
+			paramType = params[i].Type
+		}
+		if funcType != nil && i < len(funcType.Params.Params) && types.IsTrait(paramType) {
+			paramType = funcType.Params.Params[i].Type
+		}
+
+		value, err := fromGo(arg, paramType)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	ret, err := vm.callClosure(closure, values)
+	if err != nil {
+		return nil, err
+	}
+
+	var returnType ast.TypeExpr = &ast.TraitType{}
+	if funcType != nil {
+		returnType = funcType.Return
+	}
+
+	return vm.toGo(ret, returnType), nil
+}
+
+// fromGo converts a value returned by a builtin function back into a nook value.
+// The type is used to tell strings, paths, atoms and flags apart, and whether
+// a []any is a slice or a tuple. If the type is unknown the go type is used instead.
+func fromGo(raw any, typeExpr ast.TypeExpr) (Value, error) {
+	switch raw := raw.(type) {
+	case nil:
+		return NoneValue, nil
+	case int64:
+		return Value{value: raw, kind: Int}, nil
+	case float64:
+		return Value{value: raw, kind: Float}, nil
+	case bool:
+		return Value{value: raw, kind: Bool}, nil
+	case *big.Int:
+		return Value{value: raw, kind: BigInt}, nil
+	case *builtin.Error:
+		return Value{value: raw, kind: Err}, nil
+	case *builtin.Func:
+		return Value{value: raw.Value, kind: Func}, nil
+	case *regexp.Regexp:
+		return Value{value: raw, kind: Regex}, nil
+	case time.Duration:
+		return Value{value: raw, kind: Duration}, nil
+	case time.Time:
+		return Value{value: raw, kind: Time}, nil
+	case builtin.Size:
+		return Value{value: raw, kind: Size}, nil
+	case string:
+		switch typeExpr.(type) {
+		case *ast.PathType:
+			return Value{value: raw, kind: Path}, nil
+		case *ast.AtomType:
+			return Value{value: raw, kind: Atom}, nil
+		case *ast.FlagType:
+			return Value{value: raw, kind: Flag}, nil
+		default:
+			return Value{value: raw, kind: String}, nil
+		}
+	case builtin.Tuple:
+		value, err := fromGo([]any(raw), typeExpr)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{value: value.value, kind: Tuple}, nil
+	case []any:
+		tupleType, isTuple := typeExpr.(*ast.TupleType)
+		var elem ast.TypeExpr = &ast.TraitType{}
+		if sliceType, ok := typeExpr.(*ast.SliceType); ok {
+			elem = types.Elem(sliceType)
+		}
+
+		items := []Value{}
+		for i, item := range raw {
+			itemType := elem
+			if isTuple && i < len(tupleType.Types) {
+				itemType = tupleType.Types[i]
+			}
+
+			value, err := fromGo(item, itemType)
+			if err != nil {
+				return Value{}, err
+			}
+			items = append(items, value)
+		}
+
+		if isTuple {
+			return Value{value: items, kind: Tuple}, nil
+		}
+		return Value{value: items, kind: Slice}, nil
+	case builtin.Dict:
+		value, err := fromGo(raw.Fields, typeExpr)
+		if err != nil {
+			return Value{}, err
+		}
+		value.names = raw.Names
+		return value, nil
+	case map[string]any:
+		dictType, _ := typeExpr.(*ast.DictType)
+		fields := map[string]Value{}
+		for name, field := range raw {
+			var fieldType ast.TypeExpr = &ast.TraitType{}
+			if dictType != nil {
+				if t, ok := types.Field(dictType, name); ok {
+					fieldType = t
+				}
+			}
+
+			value, err := fromGo(field, fieldType)
+			if err != nil {
+				return Value{}, err
+			}
+			fields[name] = value
+		}
+
+		return Value{value: fields, kind: Dict}, nil
+	default:
+		return Value{}, fmt.Errorf("failed to convert return to return type")
+	}
+}
+
+// convert changes the type of a value, the checker has already made sure the conversion is valid.
+// Values that are only known at runtime, like parsed json, are checked item by item.
+func convert(value Value, to ast.TypeExpr) (Value, error) {
+	return convertAt(value, to, "")
+}
+
+// convertAt converts a value that is at a location inside of an outer value (e.g. .users[0].name),
+// the location is used to show where a nested conversion failed
+func convertAt(value Value, to ast.TypeExpr, at string) (Value, error) {
+	switch to := to.(type) {
+	case *ast.FloatType:
+		switch value.kind {
+		case Int:
+			return Value{value: float64(value.Int()), kind: Float}, nil
+		case BigInt:
+			f, _ := new(big.Float).SetInt(value.value.(*big.Int)).Float64()
+			return Value{value: f, kind: Float}, nil
+		}
+	case *ast.BigIntType:
+		if value.kind == Int {
+			return Value{value: big.NewInt(value.Int()), kind: BigInt}, nil
+		}
+	case *ast.PathType:
+		if value.kind == String {
+			return Value{value: value.value, kind: Path}, nil
+		}
+	case *ast.FlagType:
+		if value.kind == String {
+			return Value{value: value.value, kind: Flag}, nil
+		}
+	case *ast.RegexType:
+		if value.kind == String {
+			re, err := builtin.CompileRegex(value.Str())
+			if err != nil {
+				return Value{}, err
+			}
+			return Value{value: re, kind: Regex}, nil
+		}
+	case *ast.TraitType:
+		return value, nil
+	case *ast.SliceType:
+		if value.kind != Slice && value.kind != Tuple {
+			break
+		}
+
+		items := []Value{}
+		for i, item := range value.value.([]Value) {
+			converted, err := convertAt(item, types.Elem(to), fmt.Sprintf("%s[%d]", at, i))
+			if err != nil {
+				return Value{}, err
+			}
+			items = append(items, converted)
+		}
+		return Value{value: items, kind: Slice}, nil
+	case *ast.TupleType:
+		if value.kind != Slice && value.kind != Tuple || len(value.value.([]Value)) != len(to.Types) {
+			break
+		}
+
+		items := []Value{}
+		for i, item := range value.value.([]Value) {
+			converted, err := convertAt(item, to.Types[i], fmt.Sprintf("%s[%d]", at, i))
+			if err != nil {
+				return Value{}, err
+			}
+			items = append(items, converted)
+		}
+		return Value{value: items, kind: Tuple}, nil
+	case *ast.DictType:
+		if value.kind != Dict {
+			break
+		}
+
+		// only the fields in the type are kept, any extra fields are dropped
+		fields := value.value.(map[string]Value)
+		converted := map[string]Value{}
+		for _, field := range to.Fields {
+			fieldAt := at + "." + field.Name
+			fieldValue, ok := fields[field.Name]
+			if !ok {
+				return Value{}, fmt.Errorf("missing field '%s'", fieldAt)
+			}
+
+			var err error
+			converted[field.Name], err = convertAt(fieldValue, field.Type, fieldAt)
+			if err != nil {
+				return Value{}, err
+			}
+		}
+		// the fields that are kept stay in the same order
+		var names []string
+		for _, name := range value.names {
+			if _, ok := converted[name]; ok {
+				names = append(names, name)
+			}
+		}
+		return Value{value: converted, kind: Dict, names: names}, nil
+	}
+
+	if !types.Match(value.Type(), to) {
+		if at != "" {
+			return Value{}, fmt.Errorf("can not convert '%s' to '%s' at '%s'", types.Name(value.Type()), types.Name(to), at)
+		}
+		return Value{}, fmt.Errorf("can not convert '%s' to '%s'", types.Name(value.Type()), types.Name(to))
+	}
+
+	return value, nil
+}
@@ -1,18 +1,58 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/bjatkin/nook/script/ast"
+	"github.com/bjatkin/nook/script/builtin"
+	"github.com/bjatkin/nook/script/types"
 )
 
 type scope struct {
 	parent *scope
 	idents map[string]Value
+
+	// hash is the content hash of the file the scope was imported from
+	hash string
+
+	// journal holds the values that have been replaced since begin was called
+	// so they can be restored by rollback. A nil value means the ident was not set.
+	journal map[string]*Value
+}
+
+func (s *scope) begin() {
+	s.journal = map[string]*Value{}
+}
+
+func (s *scope) commit() {
+	s.journal = nil
+}
+
+func (s *scope) rollback() {
+	for ident, value := range s.journal {
+		if value == nil {
+			delete(s.idents, ident)
+			continue
+		}
+
+		s.idents[ident] = *value
+	}
+
+	s.journal = nil
 }
 
 func (s *scope) lookupIdent(ident string, args []ast.Expr) (Value, bool) {
+	if namespace, member, ok := strings.Cut(ident, "."); ok {
+		return s.lookupQualified(namespace, member)
+	}
+
 	if value, ok := s.idents[ident]; ok {
 		return value, true
 	}
@@ -24,12 +64,66 @@ func (s *scope) lookupIdent(ident string, args []ast.Expr) (Value, bool) {
 	return s.parent.lookupIdent(ident, args)
 }
 
+// lookupQualified looks up a namespace and then looks up each of the remaining
+// segments of a qualified identifier as members of that namespace
+func (s *scope) lookupQualified(namespace, member string) (Value, bool) {
+	value, ok := s.lookupIdent(namespace, nil)
+	if !ok {
+		return Value{}, false
+	}
+
+	for {
+		ns, ok := value.value.(*scope)
+		if !ok {
+			return Value{}, false
+		}
+
+		name, rest, qualified := strings.Cut(member, ".")
+		value, ok = ns.idents[name]
+		if !ok {
+			return Value{}, false
+		}
+		if !qualified {
+			return value, true
+		}
+
+		member = rest
+	}
+}
+
 func (s *scope) setIdent(ident string, value Value) {
+	if s.journal != nil {
+		if _, ok := s.journal[ident]; !ok {
+			var prev *Value
+			if value, ok := s.idents[ident]; ok {
+				prev = &value
+			}
+			s.journal[ident] = prev
+		}
+	}
+
 	s.idents[ident] = value
 }
 
+// DefaultMaxDepth is the default number of nested calls a VM can make.
+// Tail calls do not count towards the depth.
+const DefaultMaxDepth = 10_000
+
 type VM struct {
 	scope *scope
+	impls map[*ast.Func]*Closure
+
+	// transaction holds every scope that has been changed since Begin was called
+	transaction []*scope
+
+	// path is the name of the source that is currently being evaluated
+	path string
+
+	// MaxDepth is the number of nested calls that can be made before a stack overflow
+	// is raised. There is always a limit since running out of go stack can not be recovered,
+	// a MaxDepth of 0 or less uses DefaultMaxDepth.
+	MaxDepth int
+	depth    int
 }
 
 func NewVM() *VM {
@@ -37,7 +131,57 @@ func NewVM() *VM {
 		scope: &scope{
 			idents: make(map[string]Value),
 		},
+		impls:    make(map[*ast.Func]*Closure),
+		MaxDepth: DefaultMaxDepth,
+	}
+}
+
+// Begin starts a new transaction. Values bound after Begin is called are only
+// kept if Commit is called, Rollback will restore the previous values.
+func (vm *VM) Begin() {
+	vm.scope.begin()
+	vm.transaction = []*scope{vm.scope}
+}
+
+// Commit keeps all the values bound since Begin was called
+func (vm *VM) Commit() {
+	for _, scope := range vm.transaction {
+		scope.commit()
+	}
+	vm.transaction = nil
+}
+
+// Rollback restores all the values bound since Begin was called
+func (vm *VM) Rollback() {
+	for _, scope := range vm.transaction {
+		scope.rollback()
 	}
+	vm.transaction = nil
+}
+
+// SetPath sets the name of the source that is about to be evaluated.
+// The name is used to report where calls were made in error traces.
+func (vm *VM) SetPath(path string) {
+	vm.path = path
+}
+
+func (vm *VM) openScope() {
+	vm.scope = &scope{
+		parent: vm.scope,
+		idents: make(map[string]Value),
+	}
+}
+
+func (vm *VM) closeScope() {
+	vm.scope = vm.scope.parent
+}
+
+// evalScoped evaluates an expression inside of a new scope
+func (vm *VM) evalScoped(expr ast.Expr) (Value, error) {
+	vm.openScope()
+	defer vm.closeScope()
+
+	return vm.Eval(expr)
 }
 
 // TODO: send back editor events?
@@ -46,18 +190,32 @@ func (vm *VM) Eval(expr ast.Expr) (Value, error) {
 	case *ast.Let:
 		value, err := vm.Eval(expr.Value)
 		if err != nil {
-			return Value{}, fmt.Errorf("failed to eval let expr")
+			return Value{}, fmt.Errorf("failed to eval let expr: %w", err)
 		}
 		vm.scope.setIdent(expr.Identifier.Name, value)
 
 		return NoneValue, nil
-	case *ast.Call:
-		value, err := vm.evalCall(expr.Func, expr.Args)
-		if err != nil {
-			return Value{}, fmt.Errorf("failed to call expr: '%w'", err)
+	case *ast.Impl:
+		vm.impls[expr.Func] = &Closure{Func: expr.Func, scope: vm.scope, path: vm.path}
+
+		return NoneValue, nil
+	case *ast.Func:
+		return Value{value: &Closure{Func: expr, scope: vm.scope, path: vm.path}, kind: Func}, nil
+	case *ast.Namespace:
+		return vm.evalNamespace(expr)
+	case *ast.Import:
+		return vm.evalImport(expr)
+	case *ast.Macro:
+		return NoneValue, nil
+	case *ast.If, *ast.Match, *ast.Do, *ast.Call:
+		value, next, err := vm.evalTail(expr)
+		if err != nil || next == nil {
+			return value, err
 		}
 
-		return value, nil
+		return vm.finishCall(next)
+	case *ast.Try:
+		return vm.evalTry(expr)
 	case *ast.Command:
 		name := expr.Name
 		cmdArgs := []string{}
@@ -80,9 +238,31 @@ func (vm *VM) Eval(expr ast.Expr) (Value, error) {
 		}
 
 		cmd := exec.Command(name, cmdArgs...)
-		result, _ := cmd.CombinedOutput()
+		if expr.Stdin != nil {
+			stdin, err := vm.evalStdin(expr.Stdin)
+			if err != nil {
+				return Value{}, err
+			}
+			defer stdin.Close()
+
+			cmd.Stdin = stdin
+		}
+
+		result, err := cmd.CombinedOutput()
+
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			// commands that fail can be caught with try like any other error
+			output := strings.TrimSpace(string(result))
+			return Value{}, &builtin.Error{
+				Message: fmt.Sprintf("'%s' exited with status %d", name, exitErr.ExitCode()),
+				Cause:   &builtin.Error{Message: output},
+			}
+		case err != nil:
+			return Value{}, &builtin.Error{Message: fmt.Sprintf("failed to run '%s'", name), Cause: toError(err)}
+		}
 
-		// TODO: handle status codes and return a more complex type than this
 		return Value{value: string(result), kind: String}, nil
 	case *ast.Int:
 		return Value{value: expr.Value, kind: Int}, nil
@@ -92,14 +272,79 @@ func (vm *VM) Eval(expr ast.Expr) (Value, error) {
 		return Value{value: expr.Value, kind: Bool}, nil
 	case *ast.String:
 		return Value{value: expr.Value, kind: String}, nil
+	case *ast.Interpolation:
+		builder := strings.Builder{}
+		for _, part := range expr.Parts {
+			value, err := vm.Eval(part)
+			if err != nil {
+				return Value{}, err
+			}
+
+			builder.WriteString(interpolate(value))
+		}
+
+		return Value{value: builder.String(), kind: String}, nil
 	case *ast.Atom:
 		return Value{value: expr.Value, kind: Atom}, nil
 	case *ast.Flag:
 		return Value{value: expr.Value, kind: Flag}, nil
+	case *ast.Duration:
+		return Value{value: expr.Value, kind: Duration}, nil
+	case *ast.Size:
+		return Value{value: builtin.Size(expr.Value), kind: Size}, nil
+	case *ast.Regex:
+		re, err := builtin.CompileRegex(expr.Value)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{value: re, kind: Regex}, nil
 	case *ast.Path:
 		return Value{value: expr.Value, kind: Path}, nil
 	case *ast.Nil:
 		return Value{value: nil, kind: None}, nil
+	case *ast.Tuple:
+		items, err := vm.evalArgs(expr.Items)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return Value{value: items, kind: Tuple}, nil
+	case *ast.Slice:
+		if expr.Value != nil {
+			return vm.Eval(expr.Value)
+		}
+
+		items, err := vm.evalArgs(expr.Items)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return Value{value: items, kind: Slice}, nil
+	case *ast.Index:
+		return vm.evalIndex(expr)
+	case *ast.Dict:
+		fields := map[string]Value{}
+		for _, field := range expr.Fields {
+			value, err := vm.Eval(field.Value)
+			if err != nil {
+				return Value{}, err
+			}
+
+			fields[field.Property.Name] = value
+		}
+
+		return Value{value: fields, kind: Dict}, nil
+	case *ast.Convert:
+		value, err := vm.Eval(expr.Value)
+		if err != nil {
+			return Value{}, err
+		}
+
+		return convert(value, expr.Type)
+	case *ast.And:
+		return vm.evalLogic(expr.Exprs, false)
+	case *ast.Or:
+		return vm.evalLogic(expr.Exprs, true)
 	case *ast.Identifier:
 		val, ok := vm.scope.lookupIdent(expr.Name, nil)
 		if !ok {
@@ -111,44 +356,424 @@ func (vm *VM) Eval(expr ast.Expr) (Value, error) {
 	}
 }
 
-func (vm *VM) evalCall(operator ast.Expr, args []ast.Expr) (Value, error) {
-	switch operator := operator.(type) {
-	case *ast.Func:
-		panic("ast.Func is not currently supported")
-	case *ast.Identifier:
-		panic("ast.Identifier is not currently supported")
-	case *ast.Builtin:
-		values, err := vm.evalArgs(args)
+// evalIndex gets a single item from a slice, tuple or dict. Dicts are indexed by property and
+// slices and tuples by an int, indexes that are out of range raise an error.
+func (vm *VM) evalIndex(expr *ast.Index) (Value, error) {
+	value, err := vm.Eval(expr.Value)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if property, ok := expr.Index.(*ast.Property); ok {
+		fields, ok := value.value.(map[string]Value)
+		if !ok {
+			return Value{}, fmt.Errorf("can not index '%s' with a property", value.kind)
+		}
+
+		field, ok := fields[property.Name]
+		if !ok {
+			return Value{}, &builtin.Error{Message: fmt.Sprintf("dict has no field '.%s'", property.Name)}
+		}
+
+		return field, nil
+	}
+
+	index, err := vm.Eval(expr.Index)
+	if err != nil {
+		return Value{}, err
+	}
+
+	items, ok := value.value.([]Value)
+	if !ok || index.kind != Int {
+		return Value{}, fmt.Errorf("can not index '%s' with '%s'", value.kind, index.kind)
+	}
+
+	i := index.Int()
+	if i < 0 || i >= int64(len(items)) {
+		return Value{}, &builtin.Error{Message: fmt.Sprintf("index %d out of range for %s of length %d", i, value.kind, len(items))}
+	}
+
+	return items[i], nil
+}
+
+// evalLogic evaluates the operands of an and/or expression in order. Evaluation stops at the first
+// operand that equals stop, which is false for and expressions and true for or expressions.
+func (vm *VM) evalLogic(exprs []ast.Expr, stop bool) (Value, error) {
+	for _, expr := range exprs {
+		value, err := vm.Eval(expr)
 		if err != nil {
-			return Value{}, fmt.Errorf("failed to evaluate argument '%w'", err)
+			return Value{}, err
 		}
 
-		args := []any{}
-		for i := range values {
-			args = append(args, values[i].value)
+		result, ok := value.value.(bool)
+		if !ok {
+			return Value{}, fmt.Errorf("expected a bool but got '%s'", value.kind)
+		}
+		if result == stop {
+			return value, nil
+		}
+	}
+
+	return Value{value: !stop, kind: Bool}, nil
+}
+
+// evalNamespace evaluates the expressions of a namespace inside of the namespaces scope.
+// Declaring a namespace that already exists adds to the existing namespace.
+func (vm *VM) evalNamespace(expr *ast.Namespace) (Value, error) {
+	name := expr.Identifier.Name
+	value, ok := vm.scope.idents[name]
+	namespace, isNamespace := value.value.(*scope)
+	switch {
+	case !ok || !isNamespace:
+		namespace = &scope{
+			parent: vm.scope,
+			idents: make(map[string]Value),
 		}
+		vm.scope.setIdent(name, Value{value: namespace, kind: Namespace})
+	case vm.transaction != nil && !slices.Contains(vm.transaction, namespace):
+		// changes to an existing namespace need to be undone if the transaction is rolled back
+		namespace.begin()
+		vm.transaction = append(vm.transaction, namespace)
+	}
+
+	prevScope := vm.scope
+	vm.scope = namespace
+	defer func() { vm.scope = prevScope }()
 
-		ret, err := operator.Fn(args...)
+	for _, expr := range expr.Exprs {
+		_, err := vm.Eval(expr)
 		if err != nil {
 			return Value{}, err
 		}
-		if ret == nil {
+	}
+
+	return NoneValue, nil
+}
+
+// evalImport evaluates the expressions of an imported file inside of a new namespace.
+// If the same file has already been imported the namespace is kept and the file is not evaluated again.
+func (vm *VM) evalImport(expr *ast.Import) (Value, error) {
+	name := expr.Identifier.Name
+	module := expr.Module
+	if value, ok := vm.scope.idents[name]; ok {
+		namespace, ok := value.value.(*scope)
+		if ok && namespace.hash == module.Hash {
 			return NoneValue, nil
 		}
+	}
+
+	namespace := &scope{
+		parent: vm.scope,
+		idents: make(map[string]Value),
+		hash:   module.Hash,
+	}
+	vm.scope.setIdent(name, Value{value: namespace, kind: Namespace})
+
+	prevScope, prevPath := vm.scope, vm.path
+	vm.scope, vm.path = namespace, module.Path
+	defer func() { vm.scope, vm.path = prevScope, prevPath }()
+
+	for i, expr := range module.Exprs {
+		_, err := vm.Eval(expr)
+		if err != nil {
+			return Value{}, fmt.Errorf("%s:%d: %w", module.Path, module.Lines[i], err)
+		}
+	}
+
+	return NoneValue, nil
+}
+
+// tailCall is a call to a closure that has been prepared but not made yet
+type tailCall struct {
+	call    *ast.Call
+	closure *Closure
+	values  []Value
+}
+
+// evalTail evaluates an expression in tail position. Calls to closures are not made, instead
+// they are returned so the caller can make the call without growing the stack.
+func (vm *VM) evalTail(expr ast.Expr) (Value, *tailCall, error) {
+	switch expr := expr.(type) {
+	case *ast.If:
+		cond, err := vm.Eval(expr.Cond)
+		if err != nil {
+			return Value{}, nil, err
+		}
+
+		if cond.kind != Bool {
+			return Value{}, nil, fmt.Errorf("if condition must be a bool but got '%s'", cond.kind)
+		}
+
+		if cond.value.(bool) {
+			return vm.evalTailScoped(expr.Then)
+		}
+		if expr.Else != nil {
+			return vm.evalTailScoped(expr.Else)
+		}
+
+		return NoneValue, nil, nil
+	case *ast.Match:
+		value, err := vm.Eval(expr.Value)
+		if err != nil {
+			return Value{}, nil, err
+		}
+
+		for _, matchCase := range expr.Cases {
+			pattern, err := vm.Eval(matchCase.Pattern)
+			if err != nil {
+				return Value{}, nil, err
+			}
+
+			if pattern.Equal(value) {
+				return vm.evalTailScoped(matchCase.Body)
+			}
+		}
+
+		if expr.Else != nil {
+			return vm.evalTailScoped(expr.Else)
+		}
+
+		return NoneValue, nil, nil
+	case *ast.Do:
+		vm.openScope()
+		defer vm.closeScope()
+
+		last := len(expr.Exprs) - 1
+		for _, expr := range expr.Exprs[:last] {
+			_, err := vm.Eval(expr)
+			if err != nil {
+				return Value{}, nil, err
+			}
+		}
+
+		return vm.evalTail(expr.Exprs[last])
+	case *ast.Call:
+		return vm.prepareCall(expr)
+	default:
+		value, err := vm.Eval(expr)
+		return value, nil, err
+	}
+}
+
+// evalTailScoped evaluates an expression in tail position inside of a new scope.
+// The arguments of a tail call are evaluated before the scope is closed.
+func (vm *VM) evalTailScoped(expr ast.Expr) (Value, *tailCall, error) {
+	vm.openScope()
+	defer vm.closeScope()
+
+	return vm.evalTail(expr)
+}
+
+// prepareCall evaluates the function and arguments of a call. Builtins are called right away
+// but calls to closures are returned so they can be made without growing the stack.
+func (vm *VM) prepareCall(call *ast.Call) (Value, *tailCall, error) {
+	var fn Value
+	switch operator := call.Func.(type) {
+	case *ast.Func, *ast.Builtin, *ast.Dispatch:
+		// these are resolved by the checker and are not values in the language
+	default:
+		value, err := vm.Eval(operator)
+		if err != nil {
+			return Value{}, nil, err
+		}
+		fn = value
+	}
+
+	values, err := vm.evalArgs(call.Args)
+	if err != nil {
+		return Value{}, nil, err
+	}
+
+	operator := call.Func
+	if dispatch, ok := operator.(*ast.Dispatch); ok {
+		operator, values, err = selectOverload(dispatch, values)
+		if err != nil {
+			return Value{}, nil, vm.trace(err, call)
+		}
+	}
 
-		switch ret.(type) {
-		case int64:
-			return Value{value: ret, kind: Int}, nil
-		case float64:
-			return Value{value: ret, kind: Float}, nil
-		case string:
-			return Value{value: ret, kind: String}, nil
-		default:
-			return Value{}, fmt.Errorf("failed to convert return to return type")
+	var closure *Closure
+	switch operator := operator.(type) {
+	case *ast.Builtin:
+		value, err := vm.callBuiltin(operator, values)
+		if err != nil {
+			return Value{}, nil, vm.trace(err, call)
 		}
 
+		return value, nil, nil
+	case *ast.Func:
+		var ok bool
+		closure, ok = vm.impls[operator]
+		if !ok {
+			closure = &Closure{Func: operator, scope: vm.scope, path: vm.path}
+		}
 	default:
-		panic("invalid call identifier")
+		var ok bool
+		closure, ok = fn.value.(*Closure)
+		if !ok {
+			return Value{}, nil, vm.trace(fmt.Errorf("can not call value with type '%s'", fn.kind), call)
+		}
+	}
+
+	return Value{}, &tailCall{call: call, closure: closure, values: values}, nil
+}
+
+// finishCall makes a call that was prepared by prepareCall.
+// Errors raised by the function add the call to the errors trace.
+func (vm *VM) finishCall(next *tailCall) (Value, error) {
+	value, err := vm.callClosure(next.closure, next.values)
+	if err != nil {
+		return Value{}, vm.trace(err, next.call)
+	}
+
+	return value, nil
+}
+
+// trace adds a call to the trace of an error that was raised while the call was running
+func (vm *VM) trace(err error, call *ast.Call) *builtin.Error {
+	nookErr := toError(err)
+	nookErr.Trace = append(nookErr.Trace, builtin.Frame{Name: call.Tok.Value, Path: vm.path, Pos: call.Tok.Pos})
+	return nookErr
+}
+
+// evalTry evaluates the body of a try expression, any error that is raised is passed to
+// the handler. If there is no handler the error is returned as the value of the try.
+func (vm *VM) evalTry(expr *ast.Try) (Value, error) {
+	value, err := vm.evalScoped(expr.Body)
+	if err == nil {
+		return value, nil
+	}
+
+	errValue := Value{value: toError(err), kind: Err}
+	if expr.Handler == nil {
+		return errValue, nil
+	}
+
+	handler, err := vm.Eval(expr.Handler)
+	if err != nil {
+		return Value{}, err
+	}
+
+	closure, ok := handler.value.(*Closure)
+	if !ok {
+		return Value{}, fmt.Errorf("can not call value with type '%s'", handler.kind)
+	}
+
+	return vm.callClosure(closure, []Value{errValue})
+}
+
+// selectOverload finds the most specific overload for the runtime types of the arguments.
+// If no overload matches, int arguments are promoted to floats the same way the checker does.
+func selectOverload(dispatch *ast.Dispatch, values []Value) (ast.Expr, []Value, error) {
+	argTypes := []ast.TypeExpr{}
+	argNames := []string{}
+	for _, value := range values {
+		argTypes = append(argTypes, value.Type())
+		argNames = append(argNames, value.kind.String())
+	}
+
+	funcTypes := []*ast.FuncType{}
+	for _, overload := range dispatch.Overloads {
+		funcTypes = append(funcTypes, overload.Type)
+	}
+
+	matches := types.Resolve(argTypes, funcTypes)
+	if promoted, ok := types.Promote(argTypes); ok && !types.Binds(argTypes, funcTypes, matches) {
+		promotedMatches := types.Resolve(promoted, funcTypes)
+		if len(matches) == 0 || types.Binds(promoted, funcTypes, promotedMatches) {
+			matches = promotedMatches
+		}
+		if len(matches) == 1 {
+			values = promoteValues(values, funcTypes[matches[0]])
+		}
+	}
+
+	if len(matches) != 1 {
+		return nil, nil, fmt.Errorf(
+			"could not select an overload for ('%s' %s)",
+			dispatch.Name,
+			strings.Join(argNames, " "),
+		)
+	}
+
+	return dispatch.Overloads[matches[0]].Func, values, nil
+}
+
+// promoteValues converts int values to floats where the function takes a float
+func promoteValues(values []Value, funcType *ast.FuncType) []Value {
+	promoted := []Value{}
+	for i, value := range values {
+		_, wantFloat := types.ParamType(funcType, i).(*ast.FloatType)
+		if value.kind == Int && wantFloat {
+			value = Value{value: float64(value.Int()), kind: Float}
+		}
+
+		promoted = append(promoted, value)
+	}
+
+	return promoted
+}
+
+func (vm *VM) callBuiltin(fn *ast.Builtin, values []Value) (Value, error) {
+	args := []any{}
+	for i := range values {
+		var paramType ast.TypeExpr = &ast.TraitType{}
+		if fn.Type != nil {
+			paramType = types.ParamType(fn.Type, i)
+		}
+		args = append(args, vm.toGo(values[i], paramType))
+	}
+
+	ret, err := fn.Fn(args...)
+	if err != nil {
+		return Value{}, err
+	}
+
+	var returnType ast.TypeExpr = &ast.TraitType{}
+	if fn.Type != nil {
+		returnType = fn.Type.Return
+	}
+
+	return fromGo(ret, returnType)
+}
+
+// callClosure evaluates the body of the closure with the paramaters bound in the closures scope.
+// Calls in tail position reuse the same go stack frame so recursive loops run in constant stack space.
+func (vm *VM) callClosure(closure *Closure, values []Value) (Value, error) {
+	maxDepth := vm.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if vm.depth >= maxDepth {
+		return Value{}, &builtin.Error{Message: fmt.Sprintf("stack overflow, the max call depth of %d was exceeded", maxDepth)}
+	}
+	vm.depth++
+	defer func() { vm.depth-- }()
+
+	prevScope, prevPath := vm.scope, vm.path
+	defer func() { vm.scope, vm.path = prevScope, prevPath }()
+
+	for {
+		params := closure.Func.Type.Params.Params
+		if len(values) != len(params) {
+			return Value{}, fmt.Errorf("expected %d arguments but got %d", len(params), len(values))
+		}
+
+		callScope := &scope{
+			parent: closure.scope,
+			idents: make(map[string]Value),
+		}
+		for i, param := range params {
+			callScope.setIdent(param.Identifier.Name, values[i])
+		}
+
+		vm.scope, vm.path = callScope, closure.path
+		value, next, err := vm.evalTail(closure.Func.Body)
+		if err != nil || next == nil {
+			return value, err
+		}
+
+		closure, values = next.closure, next.values
 	}
 }
 
@@ -165,3 +790,42 @@ func (vm *VM) evalArgs(args []ast.Expr) ([]Value, error) {
 
 	return evaled, nil
 }
+
+// evalStdin evaluates the input for a command, strings are written to stdin as is and paths
+// are opened so the contents of the file are written to stdin
+func (vm *VM) evalStdin(expr ast.Expr) (io.ReadCloser, error) {
+	value, err := vm.Eval(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value.kind {
+	case String:
+		return io.NopCloser(strings.NewReader(value.Str())), nil
+	case Path:
+		file, err := os.Open(value.Str())
+		if err != nil {
+			return nil, &builtin.Error{Message: fmt.Sprintf("failed to open '%s'", value.Str()), Cause: toError(err)}
+		}
+		return file, nil
+	default:
+		return nil, fmt.Errorf("stdin must be a 'str' or 'path' but got '%s'", value.kind)
+	}
+}
+
+// interpolate formats a value that is embedded in a string, strings and atoms are used without
+// quotes, regexes are used as the raw pattern and none values are left out
+func interpolate(value Value) string {
+	switch value.kind {
+	case String:
+		return value.Str()
+	case Atom:
+		return value.Str()[1:]
+	case Regex:
+		return value.value.(*regexp.Regexp).String()
+	case None:
+		return ""
+	default:
+		return value.String()
+	}
+}
@@ -74,3 +74,83 @@ func TestVM_Eval(t *testing.T) {
 		})
 	}
 }
+
+func TestValue_Table(t *testing.T) {
+	str := func(s string) Value { return Value{value: s, kind: String} }
+	dict := func(name, age string) Value {
+		return Value{value: map[string]Value{"name": str(name), "age": str(age)}, kind: Dict}
+	}
+	ordered := func(name, age string) Value {
+		value := dict(name, age)
+		value.names = []string{"name", "age"}
+		return value
+	}
+	tuple := func(items ...Value) Value { return Value{value: items, kind: Tuple} }
+	slice := func(items ...Value) Value { return Value{value: items, kind: Slice} }
+
+	tests := []struct {
+		name       string
+		value      Value
+		wantHeader []string
+		wantRows   [][]string
+		wantOk     bool
+	}{
+		{
+			name:       "dicts",
+			value:      slice(dict("ann", "30"), dict("bo", "4")),
+			wantHeader: []string{"age", "name"},
+			wantRows:   [][]string{{"30", "ann"}, {"4", "bo"}},
+			wantOk:     true,
+		},
+		{
+			name:       "dicts that keep their field order",
+			value:      slice(ordered("ann", "30"), dict("bo", "4")),
+			wantHeader: []string{"name", "age"},
+			wantRows:   [][]string{{"ann", "30"}, {"bo", "4"}},
+			wantOk:     true,
+		},
+		{
+			name:     "tuples",
+			value:    slice(tuple(str("a"), str("1")), tuple(str("b"), str("2"))),
+			wantRows: [][]string{{"a", "1"}, {"b", "2"}},
+			wantOk:   true,
+		},
+		{
+			name:   "tuples of different lengths",
+			value:  slice(tuple(str("a"), str("1")), tuple(str("b"))),
+			wantOk: false,
+		},
+		{
+			name:   "dicts and tuples",
+			value:  slice(dict("ann", "30"), tuple(str("b"))),
+			wantOk: false,
+		},
+		{
+			name:   "empty slice",
+			value:  slice(),
+			wantOk: false,
+		},
+		{
+			name:   "not a slice",
+			value:  str("a"),
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows, ok := tt.value.Table()
+			if ok != tt.wantOk {
+				t.Fatalf("Value.Table() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if !reflect.DeepEqual(header, tt.wantHeader) {
+				t.Errorf("Value.Table() header = %v, want %v", header, tt.wantHeader)
+			}
+			if !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("Value.Table() rows = %v, want %v", rows, tt.wantRows)
+			}
+		})
+	}
+}
@@ -4,25 +4,24 @@ import (
 	"strings"
 	"time"
 
-	"github.com/bjatkin/nook/script/checker"
-	"github.com/bjatkin/nook/script/normalizer"
 	"github.com/bjatkin/nook/script/parser"
-	"github.com/bjatkin/nook/script/vm"
+	"github.com/bjatkin/nook/script/session"
+	"github.com/bjatkin/nook/script/token"
 	"github.com/bjatkin/nook/ui/colors"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type runResult struct {
-	result string
-	errors []error
+	result   string
+	errors   []error
+	warnings []error
 }
 
 type activeCell struct {
 	editor      codeEditor
 	errorOutput []error
-	vm          *vm.VM
-	typeChecker *checker.Checker
+	session     *session.Session
 	running     bool
 	width       int
 	height      int
@@ -50,13 +49,15 @@ func (a activeCell) Update(msg tea.Msg) (activeCell, tea.Cmd) {
 			mode:    "INSERT",
 			cursor:  cursor{row: 0, col: 1},
 			content: []string{"("},
+			tokens:  &tokenCache{},
 			width:   a.editor.width,
 			height:  a.editor.height,
 		}
 		return a, func() tea.Msg {
 			return addHistoryEntry{
-				command: code,
-				output:  msg.result,
+				command:  code,
+				output:   msg.result,
+				warnings: msg.warnings,
 			}
 		}
 
@@ -80,7 +81,7 @@ func (a activeCell) Update(msg tea.Msg) (activeCell, tea.Cmd) {
 			a.running = true
 			return a, func() tea.Msg {
 				start := time.Now()
-				result, errors := a.runCode([]byte(code))
+				result, errors, warnings := a.runCode([]byte(code))
 
 				// minimum runtime is 1/16th second so the UI has time to update
 				minDuration := time.Second / 16
@@ -93,8 +94,9 @@ func (a activeCell) Update(msg tea.Msg) (activeCell, tea.Cmd) {
 				}
 
 				return runResult{
-					result: result,
-					errors: errors,
+					result:   result,
+					errors:   errors,
+					warnings: warnings,
 				}
 			}
 		default:
@@ -109,34 +111,23 @@ func (a activeCell) Update(msg tea.Msg) (activeCell, tea.Cmd) {
 	}
 }
 
-func (a activeCell) runCode(code []byte) (string, []error) {
-	p := parser.NewParser(code)
-	ast := p.Parse()
-	if len(p.Errors) != 0 {
-		return "", p.Errors
+func (a activeCell) runCode(code []byte) (string, []error, []error) {
+	result, errs, err := a.session.Run(code)
+	if len(errs) > 0 {
+		return "", errs, nil
 	}
-
-	n := normalizer.Normalizer{}
-	ast = n.Normalize(ast)
-	if len(n.Errors) > 0 {
-		return "", n.Errors
-	}
-
-	_ = a.typeChecker.Infer(ast)
-	if len(a.typeChecker.Errors) > 0 {
-		typeCheckErrs := a.typeChecker.Errors
-		a.typeChecker.Errors = []error{}
-		return "", typeCheckErrs
-	}
-
-	result, err := a.vm.Eval(ast)
 	if err != nil {
 		// This is a runtime error, so it should be returned as the
 		// result since the error is only for "compile time" errors
-		return err.Error(), nil
+		return err.Error(), nil, a.session.Warnings
+	}
+
+	// slices of dicts and tuples are easier to read as a table
+	if header, rows, ok := result.Table(); ok {
+		return renderTable(header, rows), nil, a.session.Warnings
 	}
 
-	return result.String(), nil
+	return result.String(), nil, a.session.Warnings
 }
 
 func (a activeCell) View() string {
@@ -166,30 +157,27 @@ func (a activeCell) View() string {
 	return editor + "\n" + strings.Join(errorLines, "\n")
 }
 
+// isCompleteExpression reports whether the code is ready to run. Parens inside of strings and
+// comments are ignored, and code with a heredoc that has not been closed yet is never complete.
 func isCompleteExpression(code string) bool {
 	depth := 0
-	inComment := false
 	exprCount := 0
-	for _, char := range code {
-		if char == '\n' {
-			inComment = false
-			continue
-		}
-
-		if char == '#' {
-			inComment = true
+	lexer := parser.NewVerboseLexer([]byte(code))
+	for _, tok := range lexer.Lex() {
+		switch tok.Kind {
+		case token.Whitespace, token.Comment:
 			continue
-		}
-
-		if !inComment && char == '(' {
+		case token.OpenParen:
 			depth++
-		}
-		if !inComment && char == ')' {
+		case token.CloseParen:
 			depth--
+		case token.Heredoc:
+			if parser.IsUnclosedHeredoc(tok) {
+				return false
+			}
 		}
-		if !inComment {
-			exprCount++
-		}
+
+		exprCount++
 	}
 
 	return depth <= 0 && exprCount > 0
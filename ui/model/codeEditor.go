@@ -4,11 +4,11 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
-	"github.com/bjatkin/nook/script/parser"
 	"github.com/bjatkin/nook/script/token"
 	"github.com/bjatkin/nook/ui/colors"
 )
@@ -28,12 +28,25 @@ type codeEditor struct {
 	cursor  cursor
 	content []string
 
+	// tokens is shared between copies of the editor so the content is only lexed again after it changes
+	tokens *tokenCache
+
 	// TODO: add width and height to the editor and support scrolling if the
 	// input get's too large
 	width  int
 	height int
 }
 
+// lexed returns the token cache after lexing any lines that changed since it was last used
+func (c *codeEditor) lexed() *tokenCache {
+	if c.tokens == nil {
+		c.tokens = &tokenCache{}
+	}
+
+	c.tokens.update(c.content)
+	return c.tokens
+}
+
 // Text returns the content of the editor as a string
 func (c *codeEditor) Text() string {
 	return strings.Join(c.content, "\n")
@@ -63,14 +76,14 @@ func (c codeEditor) insertUpdate(msg tea.Msg) (codeEditor, tea.Cmd) {
 		key := msg.String()
 
 		// TODO: this probably needs to be more robust
-		if len(key) == 1 || key == "space" || key == "tab" {
+		if utf8.RuneCountInString(key) == 1 || key == "space" || key == "tab" {
 			key = strings.ReplaceAll(key, "space", " ")
 			key = strings.ReplaceAll(key, "tab", "\t")
 
 			row := c.cursor.row
 			line := c.content[row]
 			c.content[row] = insertChar(c.cursor.col, line, key)
-			c.cursor.col++
+			c.cursor.col += len(key)
 		}
 
 		switch key {
@@ -98,9 +111,11 @@ func (c codeEditor) insertUpdate(msg tea.Msg) (codeEditor, tea.Cmd) {
 				c.content = removeLine(c.cursor.row, c.content)
 				c.cursor.row = max(0, c.cursor.row-1)
 				c.cursor.col = len(c.content[c.cursor.row])
-			} else {
-				c.content[row] = removeChar(c.cursor.col-1, line)
-				c.cursor.col = max(0, c.cursor.col-1)
+			} else if c.cursor.col > 0 {
+				// the column is a byte offset so step back over the whole character before the cursor
+				_, size := utf8.DecodeLastRuneInString(line[:c.cursor.col])
+				c.cursor.col -= size
+				c.content[row] = removeChar(c.cursor.col, line)
 			}
 
 			return c, nil
@@ -155,8 +170,7 @@ func (c codeEditor) normalUpdate(msg tea.Msg) (codeEditor, tea.Cmd) {
 			return c, func() tea.Msg { return changeMode("INSERT") }
 		case "a":
 			c.mode = "INSERT"
-			lineLen := len(c.content[c.cursor.row])
-			c.cursor.col = min(lineLen, c.cursor.col+1)
+			c.moveCursor(right)
 			return c, func() tea.Msg { return changeMode("INSERT") }
 		case "A":
 			c.mode = "INSERT"
@@ -185,9 +199,7 @@ func (c codeEditor) normalUpdate(msg tea.Msg) (codeEditor, tea.Cmd) {
 			return c, func() tea.Msg { return changeMode("INSERT") }
 		case "w":
 			// skip until the next NookScript token
-			line := c.content[c.cursor.row]
-			lexer := parser.NewVerboseLexer([]byte(line))
-			tokens := lexer.Lex()
+			tokens := c.lexed().line(c.cursor.row)
 
 			// find the current token
 			currentToken := -1
@@ -219,9 +231,7 @@ func (c codeEditor) normalUpdate(msg tea.Msg) (codeEditor, tea.Cmd) {
 			}
 		case "b":
 			// skip backwards to the previous NookScript token
-			line := c.content[c.cursor.row]
-			lexer := parser.NewVerboseLexer([]byte(line))
-			tokens := lexer.Lex()
+			tokens := c.lexed().line(c.cursor.row)
 
 			// find the current token
 			currentToken := -1
@@ -302,25 +312,34 @@ func (c *codeEditor) moveCursor(direction direction) {
 
 		c.cursor.col = contentCol
 	case left:
-		c.cursor.col = max(0, c.cursor.col-1)
+		// columns are byte offsets so the cursor moves over whole characters
+		_, size := utf8.DecodeLastRuneInString(c.content[c.cursor.row][:c.cursor.col])
+		c.cursor.col -= size
 	case right:
-		lineLen := len(c.content[c.cursor.row])
-		c.cursor.col = min(lineLen, c.cursor.col+1)
+		_, size := utf8.DecodeRuneInString(c.content[c.cursor.row][c.cursor.col:])
+		c.cursor.col += size
 	}
 }
 
+// toVisualColumn converts a byte offset in the line into the column it is drawn at
 func toVisualColumn(contentCol int, line string) int {
 	prefix := line[:contentCol]
 	tabs := strings.Count(prefix, "\t")
-	shortPrefix := len(prefix) - tabs
+	shortPrefix := utf8.RuneCountInString(prefix) - tabs
 	return shortPrefix + tabs*4
 }
 
+// lineWidth is the number of columns a line takes up once it is drawn
+func lineWidth(line string) int {
+	return toVisualColumn(len(line), line)
+}
+
+// toContentColumn converts the column a character is drawn at into the byte offset of the character
 func toContentColumn(visualCol int, line string) int {
 	contentCol := 0
 	trackCol := 0
-	for _, c := range line {
-		contentCol++
+	for i, c := range line {
+		contentCol = i
 		if c == '\t' {
 			trackCol += 4
 		} else {
@@ -332,7 +351,7 @@ func toContentColumn(visualCol int, line string) int {
 		}
 	}
 
-	return max(0, contentCol-1)
+	return contentCol
 }
 
 func removeLine(row int, lines []string) []string {
@@ -346,6 +365,7 @@ func removeLine(row int, lines []string) []string {
 	return append(lines[:row], lines[row+1:]...)
 }
 
+// removeChar removes the character that starts at the byte offset col
 func removeChar(col int, line string) string {
 	if col < 0 {
 		return line
@@ -353,22 +373,18 @@ func removeChar(col int, line string) string {
 	if len(line) < 1 {
 		return ""
 	}
-	if col == len(line)-1 {
-		return line[:len(line)-1]
-	}
 
+	_, size := utf8.DecodeRuneInString(line[col:])
 	start := line[:col]
-	end := line[col+1:]
+	end := line[col+size:]
 	return start + end
 }
 
+// insertChar inserts char at the byte offset col
 func insertChar(col int, line, char string) string {
 	if col == 0 {
 		return char + line
 	}
-	if col == len(line)-1 {
-		return line + char
-	}
 
 	start := line[:col]
 	end := line[col:]
@@ -383,44 +399,35 @@ func (c codeEditor) View(background lipgloss.Color) string {
 	styles := styles(background)
 	view := []string{}
 
+	tokens := c.lexed()
 	for row, line := range c.content {
-		pad := c.width - len(line)
+		pad := c.width - lineWidth(line)
 		padding := styles["default"].Render(strings.Repeat(" ", pad))
 		if row == c.cursor.row {
-			view = append(view, renderCursorLine(c.cursor.col, line, styles)+padding)
+			view = append(view, renderCursorLine(c.cursor.col, tokens.line(row), styles)+padding)
 		} else {
-			view = append(view, renderLine(line, styles)+padding)
+			view = append(view, renderLine(tokens.line(row), styles)+padding)
 		}
 	}
 
 	return strings.Join(view, "\n")
 }
 
-func renderLine(line string, styles map[string]lipgloss.Style) string {
-	lexer := parser.NewVerboseLexer([]byte(line))
+func renderLine(tokens []token.Token, styles map[string]lipgloss.Style) string {
 	view := ""
-	for _, tok := range lexer.Lex() {
-		if tok.Value == "\n" {
-			continue
-		}
-
+	for _, tok := range tokens {
 		view += styleToken(tok, styles)
 	}
 
 	return view
 }
 
-func renderCursorLine(cursorCol int, line string, styles map[string]lipgloss.Style) string {
-	lexer := parser.NewVerboseLexer([]byte(line))
+func renderCursorLine(cursorCol int, tokens []token.Token, styles map[string]lipgloss.Style) string {
 	cursorDrawn := false
 	col := 0
 	view := ""
 
-	for _, tok := range lexer.Lex() {
-		if tok.Value == "\n" {
-			continue
-		}
-
+	for _, tok := range tokens {
 		if cursorDrawn {
 			view += styleToken(tok, styles)
 			continue
@@ -467,13 +474,14 @@ func styleCursorToken(cursorLoc int, tok token.Token, styles map[string]lipgloss
 	startValue := tok.Value[:cursorLoc]
 	start := styleToken(token.Token{Value: startValue, Kind: tok.Kind}, styles)
 
-	cursorValue := string(tok.Value[cursorLoc])
+	_, size := utf8.DecodeRuneInString(tok.Value[cursorLoc:])
+	cursorValue := tok.Value[cursorLoc : cursorLoc+size]
 	cursor := styleCursor(token.Token{Value: cursorValue, Kind: tok.Kind}, styles)
-	if cursorLoc == len(tok.Value)-1 {
+	if cursorLoc+size == len(tok.Value) {
 		return start + cursor
 	}
 
-	endValue := tok.Value[cursorLoc+1:]
+	endValue := tok.Value[cursorLoc+size:]
 	end := styleToken(token.Token{Value: endValue, Kind: tok.Kind}, styles)
 	return start + cursor + end
 }
@@ -490,14 +498,16 @@ func styleToken(tok token.Token, styles map[string]lipgloss.Style) string {
 		value := strings.ReplaceAll(tok.Value, " ", "·")
 		value = strings.ReplaceAll(value, "\t", "├───")
 		return styles["muted"].Render(value)
-	case token.Let, token.Bool, token.GreaterThan, token.GreaterEqual,
-		token.LessThan, token.LessEqual, token.Equal, token.Command:
+	case token.Let, token.Fn, token.Impl, token.If, token.Else, token.Match, token.Do, token.Ns, token.Import,
+		token.Macro, token.Quote, token.Quasiquote, token.Unquote, token.Splice, token.Try,
+		token.Bool, token.GreaterThan, token.GreaterEqual,
+		token.LessThan, token.LessEqual, token.Equal, token.NotEqual, token.And, token.Or, token.Command:
 		return styles["keyword"].Render(tok.Value)
 	case token.Plus, token.Minus, token.Divide, token.Multiply:
 		return styles["symbol"].Render(tok.Value)
 	case token.Int, token.Float:
 		return styles["number"].Render(tok.Value)
-	case token.String:
+	case token.String, token.Regex, token.Heredoc:
 		return styles["string"].Render(tok.Value)
 	case token.Comment:
 		return styles["comment"].Render(tok.Value)
@@ -518,14 +528,16 @@ func styleCursor(tok token.Token, styles map[string]lipgloss.Style) string {
 		value := strings.ReplaceAll(tok.Value, " ", "·")
 		value = strings.ReplaceAll(value, "\t", "├───")
 		return styles["cursorMuted"].Render(value)
-	case token.Let, token.Bool, token.GreaterThan, token.GreaterEqual,
-		token.LessThan, token.LessEqual, token.Equal, token.Command:
+	case token.Let, token.Fn, token.Impl, token.If, token.Else, token.Match, token.Do, token.Ns, token.Import,
+		token.Macro, token.Quote, token.Quasiquote, token.Unquote, token.Splice, token.Try,
+		token.Bool, token.GreaterThan, token.GreaterEqual,
+		token.LessThan, token.LessEqual, token.Equal, token.NotEqual, token.And, token.Or, token.Command:
 		return styles["cursorKeyword"].Render(tok.Value)
 	case token.Plus, token.Minus, token.Divide, token.Multiply:
 		return styles["cursorSymbol"].Render(tok.Value)
 	case token.Int, token.Float:
 		return styles["cursorNumber"].Render(tok.Value)
-	case token.String:
+	case token.String, token.Regex, token.Heredoc:
 		return styles["cursorString"].Render(tok.Value)
 	case token.Comment:
 		return styles["cursorComment"].Render(tok.Value)
@@ -3,6 +3,8 @@ package model
 import (
 	"reflect"
 	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 func Test_removeChar(t *testing.T) {
@@ -47,6 +49,22 @@ func Test_removeChar(t *testing.T) {
 			},
 			want: "01245",
 		},
+		{
+			name: "multi byte character",
+			args: args{
+				col:  1,
+				line: "café!",
+			},
+			want: "cfé!",
+		},
+		{
+			name: "remove a multi byte character",
+			args: args{
+				col:  3,
+				line: "café!",
+			},
+			want: "caf!",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -80,7 +98,7 @@ func Test_insertChar(t *testing.T) {
 		{
 			name: "end of line",
 			args: args{
-				col:  3,
+				col:  4,
 				line: "0123",
 				char: "4",
 			},
@@ -192,6 +210,14 @@ func Test_toVisualColumn(t *testing.T) {
 			},
 			want: 6,
 		},
+		{
+			name: "multi byte character",
+			args: args{
+				contentCol: 5,
+				line:       "café!",
+			},
+			want: 4,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -201,3 +227,120 @@ func Test_toVisualColumn(t *testing.T) {
 		})
 	}
 }
+
+func Test_codeEditor_moveCursor(t *testing.T) {
+	type args struct {
+		col       int
+		direction direction
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "right onto a multi byte character",
+			args: args{col: 2, direction: right},
+			want: 3,
+		},
+		{
+			name: "right over a multi byte character",
+			args: args{col: 3, direction: right},
+			want: 5,
+		},
+		{
+			name: "left over a multi byte character",
+			args: args{col: 5, direction: left},
+			want: 3,
+		},
+		{
+			name: "left at the start of the line",
+			args: args{col: 0, direction: left},
+			want: 0,
+		},
+		{
+			name: "right at the end of the line",
+			args: args{col: 6, direction: right},
+			want: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := codeEditor{content: []string{"café!"}, cursor: cursor{col: tt.args.col}}
+			c.moveCursor(tt.args.direction)
+			if c.cursor.col != tt.want {
+				t.Errorf("codeEditor.moveCursor() col = %v, want %v", c.cursor.col, tt.want)
+			}
+		})
+	}
+}
+
+func Test_codeEditor_insertUpdate(t *testing.T) {
+	type args struct {
+		line string
+		col  int
+		msg  tea.KeyMsg
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantLine string
+		wantCol  int
+	}{
+		{
+			name:     "type a multi byte character",
+			args:     args{line: "caf!", col: 3, msg: tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("é")}},
+			wantLine: "café!",
+			wantCol:  5,
+		},
+		{
+			name:     "backspace over a multi byte character",
+			args:     args{line: "café!", col: 5, msg: tea.KeyMsg{Type: tea.KeyBackspace}},
+			wantLine: "caf!",
+			wantCol:  3,
+		},
+		{
+			name:     "backspace after a multi byte character",
+			args:     args{line: "café!", col: 6, msg: tea.KeyMsg{Type: tea.KeyBackspace}},
+			wantLine: "café",
+			wantCol:  5,
+		},
+		{
+			name:     "backspace at the start of the line",
+			args:     args{line: "é", col: 0, msg: tea.KeyMsg{Type: tea.KeyBackspace}},
+			wantLine: "é",
+			wantCol:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := codeEditor{mode: "INSERT", content: []string{tt.args.line}, cursor: cursor{col: tt.args.col}}
+			c, _ = c.insertUpdate(tt.args.msg)
+			if c.content[0] != tt.wantLine {
+				t.Errorf("codeEditor.insertUpdate() line = %q, want %q", c.content[0], tt.wantLine)
+			}
+			if c.cursor.col != tt.wantCol {
+				t.Errorf("codeEditor.insertUpdate() col = %v, want %v", c.cursor.col, tt.wantCol)
+			}
+		})
+	}
+}
+
+func Test_lineWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want int
+	}{
+		{name: "ascii", line: "(ls)", want: 4},
+		{name: "multi byte characters", line: "(let café \"☕\")", want: 14},
+		{name: "tab", line: "\t(ls)", want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineWidth(tt.line); got != tt.want {
+				t.Errorf("lineWidth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
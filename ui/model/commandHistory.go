@@ -10,13 +10,15 @@ import (
 )
 
 type addHistoryEntry struct {
-	command string
-	output  string
+	command  string
+	output   string
+	warnings []error
 }
 
 type historyEntry struct {
-	command string
-	output  string
+	command  string
+	output   string
+	warnings []error
 }
 
 type history struct {
@@ -36,8 +38,9 @@ func (h history) Update(msg tea.Msg) (history, tea.Cmd) {
 		return h, nil
 	case addHistoryEntry:
 		h.entries = append(h.entries, historyEntry{
-			command: msg.command,
-			output:  msg.output,
+			command:  msg.command,
+			output:   msg.output,
+			warnings: msg.warnings,
 		})
 
 		return h, nil
@@ -58,6 +61,9 @@ func (h history) View() string {
 	for _, entry := range slices.Backward(h.entries) {
 		command := renderCommand(h.width, entry.command)
 		output := renderOutput(h.width, entry.output)
+		if len(entry.warnings) > 0 {
+			output = renderWarnings(h.width, entry.warnings) + "\n" + output
+		}
 		divider := dividerStyle.Render(strings.Repeat(" ", h.width))
 		view = append(view, command+"\n"+output+"\n"+divider)
 	}
@@ -69,10 +75,13 @@ func renderCommand(width int, command string) string {
 	// on the history struct when it's created
 	styles := styles(colors.Blue2)
 	view := []string{}
-	for _, line := range strings.Split(command, "\n") {
-		pad := width - len(line)
+	lines := strings.Split(command, "\n")
+	tokens := &tokenCache{}
+	tokens.update(lines)
+	for row, line := range lines {
+		pad := width - lineWidth(line)
 		padding := styles["default"].Render(strings.Repeat(" ", pad))
-		view = append(view, renderLine(line, styles)+padding)
+		view = append(view, renderLine(tokens.line(row), styles)+padding)
 	}
 
 	return strings.Join(view, "\n")
@@ -96,3 +105,23 @@ func renderOutput(width int, output string) string {
 	}
 	return strings.Join(view, "\n")
 }
+
+func renderWarnings(width int, warnings []error) string {
+	view := []string{}
+
+	// TODO: cache these styles
+	gutterStyle := lipgloss.NewStyle().Background(colors.Blue1).Foreground(colors.Blue3)
+	lineStyle := lipgloss.NewStyle().Background(colors.Blue1).Foreground(colors.Yellow3)
+
+	for _, warning := range warnings {
+		line := "warning: " + warning.Error()
+		pad := width - len(line) + 4
+		padding := ""
+		if pad > 0 {
+			padding = lineStyle.Render(strings.Repeat(" ", pad))
+		}
+
+		view = append(view, gutterStyle.Render("  │ ")+lineStyle.Render(line)+padding)
+	}
+	return strings.Join(view, "\n")
+}
@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/bjatkin/nook/script/checker"
-	"github.com/bjatkin/nook/script/vm"
+	"github.com/bjatkin/nook/script/session"
 	"github.com/bjatkin/nook/ui/colors"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -43,9 +42,9 @@ func NewModel() (Model, error) {
 				cursor:  cursor{row: 0, col: 1},
 				mode:    "INSERT",
 				content: []string{"("},
+				tokens:  &tokenCache{},
 			},
-			vm:          vm.NewVM(),
-			typeChecker: checker.NewChecker(),
+			session: session.NewSession(),
 		},
 	}, nil
 }
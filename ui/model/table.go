@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderTable lines up the columns of a table so it can be shown in the history.
+// The header is underlined, if there is no header only the rows are shown.
+func renderTable(header []string, rows [][]string) string {
+	widths := []int{}
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	lines := []string{}
+	if header != nil {
+		lines = append(lines, renderRow(header, widths))
+
+		underline := []string{}
+		for _, width := range widths {
+			underline = append(underline, strings.Repeat("-", width))
+		}
+		lines = append(lines, renderRow(underline, widths))
+	}
+
+	for _, row := range rows {
+		lines = append(lines, renderRow(row, widths))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func renderRow(row []string, widths []int) string {
+	cells := []string{}
+	for i, cell := range row {
+		cells = append(cells, cell+strings.Repeat(" ", widths[i]-lipgloss.Width(cell)))
+	}
+
+	return strings.TrimRight(strings.Join(cells, "  "), " ")
+}
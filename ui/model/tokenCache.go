@@ -0,0 +1,133 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/bjatkin/nook/script/parser"
+	"github.com/bjatkin/nook/script/token"
+)
+
+// tokenCache holds the tokens for each line in the editor so only the lines that
+// changed since the last update need to be lexed again
+type tokenCache struct {
+	lines []cachedLine
+}
+
+// cachedLine holds the tokens on a single line. Tokens that span lines (e.g. a heredoc)
+// are split at each new line, and the token positions are relative to the start of the line.
+type cachedLine struct {
+	text   string
+	tokens []token.Token
+
+	// open is true when a token continues past the end of the line onto the next line, or when
+	// a string that has not been closed yet could be closed by a later line
+	open bool
+}
+
+// update lexes the lines that changed since the last update. Lexing starts at the first changed
+// line, or at the start of the token that runs into it, and stops as soon as it reaches an unchanged
+// line that starts at the same place it did before the change.
+func (t *tokenCache) update(content []string) {
+	old := t.lines
+
+	prefix := 0
+	for prefix < len(old) && prefix < len(content) && old[prefix].text == content[prefix] {
+		prefix++
+	}
+	if prefix == len(old) && prefix == len(content) {
+		return
+	}
+
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(content)-prefix &&
+		old[len(old)-1-suffix].text == content[len(content)-1-suffix] {
+		suffix++
+	}
+
+	start := prefix
+	// the last line is never marked open since nothing followed it, so lex it again when lines are added after it
+	if start == len(old) && start > 0 {
+		start--
+	}
+	for start > 0 && old[start-1].open {
+		start--
+	}
+
+	// only lines were removed from the end, so the remaining lines lex the same way
+	if start >= len(content) {
+		t.lines = old[:len(content)]
+		return
+	}
+
+	lines := make([]cachedLine, start, len(content))
+	copy(lines, old[:start])
+
+	row := start
+	line := cachedLine{text: content[row]}
+	col := 0
+	unclosed := false
+
+	lexer := parser.NewVerboseLexer([]byte(strings.Join(content[start:], "\n")))
+	for {
+		// once an unchanged line starts outside of a token, both before and after the change,
+		// the rest of the lines will lex the same way they did before
+		if col == 0 && row >= len(content)-suffix && (row == start || !lines[row-1].open) {
+			oldRow := row - len(content) + len(old)
+			if oldRow == 0 || !old[oldRow-1].open {
+				t.lines = append(lines, old[oldRow:]...)
+				return
+			}
+		}
+
+		tok := lexer.Next()
+		if tok.Kind == token.EOF {
+			break
+		}
+
+		// strings without a closing quote are invalid, but adding a quote on any later line would close them
+		if tok.Kind == token.Invalid && (tok.Value[0] == '"' || tok.Value[0] == '`') {
+			unclosed = true
+		}
+
+		value := tok.Value
+		for {
+			end := strings.IndexByte(value, '\n')
+			if end < 0 {
+				end = len(value)
+			}
+
+			if end > 0 {
+				piece := value[:end]
+				line.tokens = append(line.tokens, token.Token{
+					Pos:   uint(col),
+					Value: piece,
+					Kind:  tok.Kind,
+				})
+				col += len(piece)
+			}
+			if end == len(value) {
+				break
+			}
+
+			// a new line on it's own is whitespace, any other token continues on the next line
+			line.open = tok.Kind != token.Whitespace || unclosed
+			lines = append(lines, line)
+
+			row++
+			line = cachedLine{text: content[row]}
+			col = 0
+			value = value[end+1:]
+		}
+	}
+
+	t.lines = append(lines, line)
+}
+
+// line returns the tokens on a line, update must be called first so the tokens match the content
+func (t *tokenCache) line(row int) []token.Token {
+	if row < 0 || row >= len(t.lines) {
+		return nil
+	}
+
+	return t.lines[row].tokens
+}
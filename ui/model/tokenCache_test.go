@@ -0,0 +1,221 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/bjatkin/nook/script/token"
+	"github.com/bjatkin/nook/ui/colors"
+)
+
+func Test_tokenCache_update(t *testing.T) {
+	type edit struct {
+		row  int
+		text string
+		// insert adds the text as a new line rather than replacing the row, delete removes the row
+		insert bool
+		delete bool
+	}
+	tests := []struct {
+		name    string
+		content []string
+		edits   []edit
+	}{
+		{
+			name:    "edit a single line",
+			content: []string{"(let a 1)", "(let b 2)", "(+ a b)"},
+			edits:   []edit{{row: 1, text: "(let b 3)"}},
+		},
+		{
+			name:    "open a string that runs onto later lines",
+			content: []string{"(let a 1)", "(let b 2)", "(+ a b)"},
+			edits:   []edit{{row: 0, text: `(let a "1)`}, {row: 2, text: `(+ a b")`}},
+		},
+		{
+			name:    "edit the inside of a heredoc",
+			content: []string{"($cat < <<EOF", "  one", "  two", "  EOF)", "(+ 1 2)"},
+			edits:   []edit{{row: 2, text: "  (two)"}, {row: 3, text: "  EO)"}, {row: 3, text: "  EOF)"}},
+		},
+		{
+			name:    "insert and delete lines",
+			content: []string{"(do", "  1", "  2)", "# done"},
+			edits:   []edit{{row: 1, text: "  `raw", insert: true}, {row: 3, text: "  3`", insert: true}, {row: 1, delete: true}},
+		},
+		{
+			name:    "unicode",
+			content: []string{`(let café "☕`, `")`},
+			edits:   []edit{{row: 1, text: `☕")`}},
+		},
+		{
+			name:    "delete the last line",
+			content: []string{"(+ 1", "2)"},
+			edits:   []edit{{row: 1, delete: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := slices.Clone(tt.content)
+			cache := &tokenCache{}
+			cache.update(content)
+
+			for i, e := range tt.edits {
+				switch {
+				case e.insert:
+					content = slices.Insert(content, e.row, e.text)
+				case e.delete:
+					content = slices.Delete(content, e.row, e.row+1)
+				default:
+					content[e.row] = e.text
+				}
+				cache.update(content)
+
+				// the cache should always match lexing all the content again
+				full := &tokenCache{}
+				full.update(content)
+				if !reflect.DeepEqual(cache.lines, full.lines) {
+					t.Errorf("edit %d tokenCache.update() = %v, want %v", i, cache.lines, full.lines)
+				}
+			}
+		})
+	}
+}
+
+// Test_tokenCache_update_random makes random edits and checks the cache always matches a full lex
+func Test_tokenCache_update_random(t *testing.T) {
+	pieces := []string{"(", ")", " ", "1", "a", `"`, "`", "<<EOF", "EOF", "${", "}", "# c", "é", "./p", "'x", "\\"}
+	randomLine := func(r *rand.Rand) string {
+		line := ""
+		for range r.Intn(6) {
+			line += pieces[r.Intn(len(pieces))]
+		}
+		return line
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for run := range 200 {
+		content := []string{randomLine(r)}
+		cache := &tokenCache{}
+		cache.update(content)
+
+		for i := range 30 {
+			row := r.Intn(len(content))
+			switch r.Intn(3) {
+			case 0:
+				content = slices.Insert(content, r.Intn(len(content)+1), randomLine(r))
+			case 1:
+				if len(content) > 1 {
+					content = slices.Delete(content, row, row+1)
+				}
+			default:
+				content[row] = randomLine(r)
+			}
+			cache.update(content)
+
+			full := &tokenCache{}
+			full.update(content)
+			if !reflect.DeepEqual(cache.lines, full.lines) {
+				t.Fatalf("run %d edit %d tokenCache.update(%q) = %v, want %v", run, i, content, cache.lines, full.lines)
+			}
+		}
+	}
+}
+
+func Test_tokenCache_update_reuse(t *testing.T) {
+	content := []string{}
+	for i := range 20 {
+		content = append(content, fmt.Sprintf("(let v%d %d)", i, i))
+	}
+
+	cache := &tokenCache{}
+	cache.update(content)
+	before := slices.Clone(cache.lines)
+
+	content[10] = "(let v10 \"ten\")"
+	cache.update(content)
+
+	for row, line := range cache.lines {
+		reused := &line.tokens[0] == &before[row].tokens[0]
+		if reused == (row == 10) {
+			t.Errorf("tokenCache.update() row %d reused %v, want %v", row, reused, row != 10)
+		}
+	}
+
+	want := []token.Kind{token.OpenParen, token.Let, token.Whitespace, token.Identifier, token.Whitespace, token.String, token.CloseParen}
+	got := []token.Kind{}
+	for _, tok := range cache.line(10) {
+		got = append(got, tok.Kind)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenCache.line(10) = %v, want %v", got, want)
+	}
+}
+
+func Test_tokenCache_line_multiLine(t *testing.T) {
+	cache := &tokenCache{}
+	cache.update([]string{"(let q <<SQL", "  select 1", "  SQL)"})
+
+	want := [][]token.Token{
+		{
+			{Pos: 0, Value: "(", Kind: token.OpenParen},
+			{Pos: 1, Value: "let", Kind: token.Let},
+			{Pos: 4, Value: " ", Kind: token.Whitespace},
+			{Pos: 5, Value: "q", Kind: token.Identifier},
+			{Pos: 6, Value: " ", Kind: token.Whitespace},
+			{Pos: 7, Value: "<<SQL", Kind: token.Heredoc},
+		},
+		{
+			{Pos: 0, Value: "  select 1", Kind: token.Heredoc},
+		},
+		{
+			{Pos: 0, Value: "  SQL", Kind: token.Heredoc},
+			{Pos: 5, Value: ")", Kind: token.CloseParen},
+		},
+	}
+	for row := range want {
+		if got := cache.line(row); !reflect.DeepEqual(got, want[row]) {
+			t.Errorf("tokenCache.line(%d) = %v, want %v", row, got, want[row])
+		}
+	}
+}
+
+// BenchmarkCodeEditor_View renders a 500 line buffer after editing a line in the middle of it.
+// The full benchmark lexes the whole buffer on every render for comparison.
+func BenchmarkCodeEditor_View(b *testing.B) {
+	content := []string{}
+	for i := range 500 {
+		content = append(content, fmt.Sprintf(`  (let value_%d (str.concat "line %d" ./files/%d.txt)) # comment`, i, i, i))
+	}
+
+	for _, bench := range []struct {
+		name string
+		full bool
+	}{
+		{name: "incremental"},
+		{name: "full", full: true},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			editor := codeEditor{
+				mode:    "INSERT",
+				cursor:  cursor{row: 250, col: 4},
+				content: slices.Clone(content),
+				tokens:  &tokenCache{},
+				width:   120,
+				height:  500,
+			}
+			editor.View(colors.Black)
+
+			b.ResetTimer()
+			for i := range b.N {
+				editor.content[250] = insertChar(4, content[250], fmt.Sprint(i%10))
+				if bench.full {
+					editor.tokens = &tokenCache{}
+				}
+
+				editor.View(colors.Black)
+			}
+		})
+	}
+}